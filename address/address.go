@@ -4,21 +4,21 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 
+	"github.com/xelis-project/xelis-go-sdk/config"
 	d "github.com/xelis-project/xelis-go-sdk/data"
 )
 
-var PrefixAddress string = "xel"
-var TestnetPrefixAddress string = "xet"
-
 var ExtraDataLimit = 1024
 
 var ErrIntegratedDataLimit = errors.New("invalid data in integrated address, maximum size reached")
-var ErrInvalidNetworkPrefix = fmt.Errorf("invalid network prefix (%s or %s)", PrefixAddress, TestnetPrefixAddress)
+var ErrInvalidNetworkPrefix = errors.New("invalid network prefix")
+var ErrInvalidPublicKey = errors.New("public key must be 32 bytes")
 
 type Address struct {
 	publicKey    []byte
-	isMainnet    bool
+	prefix       string
 	isIntegrated bool
 	extraData    *d.Element
 }
@@ -27,13 +27,15 @@ func NewAddressFromData(data []byte, hrp string) (addr *Address, err error) {
 	reader := bytes.NewReader(data)
 
 	publicKey := make([]byte, 32)
-	_, err = reader.Read(publicKey)
+	_, err = io.ReadFull(reader, publicKey)
 	if err != nil {
+		err = &AddressError{Kind: KindInvalidLength, Position: -1, Err: ErrInvalidLength, offset: int64(len(data))}
 		return
 	}
 
 	addrType, err := reader.ReadByte()
 	if err != nil {
+		err = &AddressError{Kind: KindInvalidLength, Position: -1, Err: ErrInvalidLength, offset: int64(len(data))}
 		return
 	}
 
@@ -46,23 +48,41 @@ func NewAddressFromData(data []byte, hrp string) (addr *Address, err error) {
 	case 1:
 		integrated = true
 
-		dataValueReader := &d.ValueReader{Reader: reader}
+		dataValueReader := &d.ValueReader{Reader: reader, MaxSize: ExtraDataLimit}
 		extraData, err = dataValueReader.Read()
 		if err != nil {
+			kind := KindInvalidIntegratedData
+			if errors.Is(err, d.ErrMaxSize) {
+				kind = KindIntegratedDataTooLarge
+				err = ErrIntegratedDataLimit
+			}
+
+			offset := int64(-1)
+			var readErr *d.ReadError
+			if errors.As(err, &readErr) {
+				offset = readErr.Offset
+			}
+
+			err = &AddressError{Kind: kind, Position: -1, Err: err, offset: offset}
 			return
 		}
 
 		if reader.Size() > int64(ExtraDataLimit) {
-			err = ErrIntegratedDataLimit
+			err = &AddressError{Kind: KindIntegratedDataTooLarge, Position: -1, Err: ErrIntegratedDataLimit, offset: int64(ExtraDataLimit)}
 			return
 		}
 	default:
-		err = fmt.Errorf("invalid address type")
+		err = &AddressError{Kind: KindInvalidAddressType, Position: -1, Err: ErrInvalidAddressType, offset: 32}
+		return
+	}
+
+	if reader.Len() > 0 {
+		err = &AddressError{Kind: KindInvalidLength, Position: -1, Err: ErrInvalidLength, offset: reader.Size() - int64(reader.Len())}
 		return
 	}
 
 	addr = &Address{
-		isMainnet:    hrp == PrefixAddress,
+		prefix:       hrp,
 		publicKey:    publicKey,
 		isIntegrated: integrated,
 		extraData:    &extraData,
@@ -77,21 +97,44 @@ func NewAddressFromString(address string) (addr *Address, err error) {
 		return
 	}
 
-	if hrp != PrefixAddress && hrp != TestnetPrefixAddress {
-		err = ErrInvalidNetworkPrefix
+	_, err = config.NetworkFromPrefix(hrp)
+	if err != nil {
+		err = &AddressError{Kind: KindInvalidHrp, Position: 0, Err: fmt.Errorf("%w: %s", ErrInvalidNetworkPrefix, hrp)}
 		return
 	}
 
 	bits, err := convertBits(decoded, 5, 8, false)
 	if err != nil {
+		err = &AddressError{Kind: KindInvalidLength, Position: -1, Err: err}
 		return
 	}
 
 	addr, err = NewAddressFromData(bits, hrp)
+	if err != nil {
+		err = dataError(err, len(hrp))
+		return
+	}
+
+	return
+}
+
+// NewAddress returns a normal address of the public key on the network
+func NewAddress(publicKey []byte, network config.Network) (addr *Address, err error) {
+	if len(publicKey) != 32 {
+		err = ErrInvalidPublicKey
+		return
+	}
+
+	profile, err := network.Profile()
 	if err != nil {
 		return
 	}
 
+	addr = &Address{
+		publicKey: append([]byte(nil), publicKey...),
+		prefix:    profile.AddressPrefix,
+	}
+
 	return
 }
 
@@ -105,7 +148,24 @@ func IsValidAddress(address string) (valid bool, err error) {
 }
 
 func (a *Address) IsMainnet() bool {
-	return a.isMainnet
+	return a.prefix == config.Mainnet.AddressPrefix()
+}
+
+// Prefix returns the human readable part of the address
+func (a *Address) Prefix() string {
+	return a.prefix
+}
+
+// Network returns the first network registered with the address prefix,
+// use IsNetwork to check networks sharing the same prefix
+func (a *Address) Network() (network config.Network, err error) {
+	return config.NetworkFromPrefix(a.prefix)
+}
+
+// IsNetwork returns true if the address can be used on the network
+func (a *Address) IsNetwork(network config.Network) bool {
+	prefix := network.AddressPrefix()
+	return prefix != "" && prefix == a.prefix
 }
 
 func (a *Address) IsIntegrated() bool {
@@ -171,11 +231,6 @@ func (a *Address) Format() (addr string, err error) {
 		return
 	}
 
-	hrp := PrefixAddress
-	if !a.isMainnet {
-		hrp = TestnetPrefixAddress
-	}
-
-	addr, err = encode(hrp, bits)
+	addr, err = encode(a.prefix, bits)
 	return
 }
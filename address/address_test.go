@@ -1,9 +1,12 @@
 package address
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/xelis-project/xelis-go-sdk/config"
 	d "github.com/xelis-project/xelis-go-sdk/data"
 )
 
@@ -194,3 +197,91 @@ func TestAddressClearExtraData(t *testing.T) {
 		t.Logf("Expected %s, got %s", MAINNET_ADDR, addr)
 	}
 }
+
+func FuzzNewAddressFromData(f *testing.F) {
+	addr, err := NewAddressFromString(MAINNET_ADDR)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(append(addr.GetPublicKey(), 0))
+	f.Add(append(addr.GetPublicKey(), 1, 0, 1, 5, 'h', 'e', 'l', 'l', 'o'))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		addr, err := NewAddressFromData(data, config.MAINNET_ADDRESS_PREFIX)
+		if err != nil {
+			return
+		}
+
+		formatted, err := addr.Format()
+		if err != nil {
+			t.Fatalf("can't format decoded address: %s", err)
+		}
+
+		decoded, err := NewAddressFromString(formatted)
+		if err != nil {
+			t.Fatalf("can't decode formatted address %s: %s", formatted, err)
+		}
+
+		if string(decoded.GetPublicKey()) != string(addr.GetPublicKey()) || decoded.IsIntegrated() != addr.IsIntegrated() {
+			t.Fatalf("expected %+v, got %+v", addr, decoded)
+		}
+	})
+}
+
+func TestAddressNetwork(t *testing.T) {
+	addr, err := NewAddressFromString(MAINNET_ADDR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	network, err := addr.Network()
+	if err != nil || network != config.Mainnet || !addr.IsNetwork(config.Mainnet) || addr.IsNetwork(config.Testnet) {
+		t.Fatalf("unexpected network %s: %v", network, err)
+	}
+
+	testnet, err := NewAddress(addr.GetPublicKey(), config.Devnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := testnet.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(formatted, config.TESTNET_ADDRESS_PREFIX+":") || !testnet.IsNetwork(config.Testnet) || !testnet.IsNetwork(config.Stagenet) {
+		t.Fatalf("unexpected devnet address %s", formatted)
+	}
+
+	// custom networks are accepted once registered
+	err = config.RegisterNetwork(config.NetworkProfile{Network: "Private", AddressPrefix: "xpv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	private, err := NewAddress(addr.GetPublicKey(), "Private")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err = private.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := NewAddressFromString(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	network, err = parsed.Network()
+	if err != nil || network != "Private" || parsed.IsMainnet() {
+		t.Fatalf("unexpected network %s: %v", network, err)
+	}
+
+	err = config.RegisterNetwork(config.NetworkProfile{Network: "Private", AddressPrefix: "xpv"})
+	if !errors.Is(err, config.ErrNetworkExists) {
+		t.Fatalf("expected %s, got %v", config.ErrNetworkExists, err)
+	}
+}
@@ -3,6 +3,7 @@ package address
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -17,6 +18,7 @@ var ErrInvalidChecksum Bech32Error = errors.New("invalid checksum")
 var ErrNonZeroPadding Bech32Error = errors.New("non zero padding")
 var ErrIllegalZeroPadding Bech32Error = errors.New("illegal zero padding")
 var ErrHrpEmpty Bech32Error = errors.New("human readable part is empty")
+var ErrSeparatorMissing Bech32Error = fmt.Errorf("separator %s is missing", SEPARATOR)
 
 func ErrSeparatorInvalidPosition(pos int) Bech32Error {
 	return fmt.Errorf("invalid separator position: %d", pos)
@@ -26,6 +28,10 @@ func ErrHrpInvalidCharacter(c byte) Bech32Error {
 	return fmt.Errorf("invalid character value in human readable part: %d", c)
 }
 
+func ErrInvalidCharacter(c byte) Bech32Error {
+	return fmt.Errorf("invalid character %q in data part", c)
+}
+
 func ErrInvalidIndex(index int) Bech32Error {
 	return fmt.Errorf("invalid index %d", index)
 }
@@ -121,56 +127,179 @@ func convertBits(data []byte, from uint16, to uint16, pad bool) (result []byte,
 }
 
 func decode(bech string) (hrp string, decoded []byte, err Bech32Error) {
-	if strings.ToUpper(bech) != bech && strings.ToLower(bech) != bech {
-		err = ErrHrpMixCase
+	if pos := mixedCasePosition(bech); pos >= 0 {
+		err = &AddressError{Kind: KindMixedCase, Position: pos, Err: ErrHrpMixCase}
 		return
 	}
 
-	pos := strings.Index(bech, ":")
-	if pos < 1 || pos+7 > len(bech) {
-		err = ErrSeparatorInvalidPosition(pos)
+	// all uppercase is allowed, the checksum is computed on the lowercase form
+	bech = strings.ToLower(bech)
+
+	pos := strings.Index(bech, SEPARATOR)
+	if pos < 0 {
+		err = &AddressError{Kind: KindInvalidSeparator, Position: -1, Err: ErrSeparatorMissing}
+		return
+	}
+
+	if pos == 0 {
+		err = &AddressError{Kind: KindInvalidHrp, Position: 0, Err: ErrHrpEmpty}
+		return
+	}
+
+	// long strings are refused before locating the typos of their checksum
+	if maxLength := pos + 1 + maxDataLength(); len(bech) > maxLength {
+		err = &AddressError{Kind: KindInvalidLength, Position: maxLength, Err: ErrInvalidLength}
+		return
+	}
+
+	if pos+7 > len(bech) {
+		err = &AddressError{Kind: KindInvalidLength, Position: len(bech), Err: ErrSeparatorInvalidPosition(pos)}
 		return
 	}
 
 	hrp = bech[0:pos]
-	for _, value := range []byte(hrp) {
+	for i, value := range []byte(hrp) {
 		if value < 33 || value > 126 {
-			err = ErrHrpInvalidCharacter(value)
+			err = &AddressError{Kind: KindInvalidCharacter, Position: i, Err: ErrHrpInvalidCharacter(value)}
 			return
 		}
 	}
 
 	for i := pos + 1; i < len(bech); i++ {
-		if i >= len(bech) {
-			err = ErrInvalidIndex(i)
+		c := bech[i]
+		index := strings.IndexByte(CHARSET, c)
+		if index < 0 {
+			err = &AddressError{Kind: KindInvalidCharacter, Position: i, Err: ErrInvalidCharacter(c)}
 			return
 		}
 
+		decoded = append(decoded, byte(index))
+	}
+
+	if !verifyChecksum(hrp, decoded) {
+		positions := locateErrors(hrp, decoded)
+		for i := range positions {
+			positions[i] += pos + 1
+		}
+
+		position := -1
+		if len(positions) > 0 {
+			position = positions[0]
+		}
+
+		err = &AddressError{Kind: KindInvalidChecksum, Position: position, TypoPositions: positions, Err: ErrInvalidChecksum}
+		return
+	}
+
+	decoded = decoded[:len(decoded)-6]
+
+	return
+}
+
+// mixedCasePosition returns the index of the first letter not in the case of the first letter, -1 if none
+func mixedCasePosition(bech string) int {
+	upper := -1
+	for i := 0; i < len(bech); i++ {
 		c := bech[i]
-		found := false
-		var index byte
-		for i, char := range CHARSET {
-			if byte(char) == c {
-				index = byte(i)
-				found = true
-				break
+		isUpper := c >= 'A' && c <= 'Z'
+		isLower := c >= 'a' && c <= 'z'
+		if !isUpper && !isLower {
+			continue
+		}
+
+		if upper == -1 {
+			if isUpper {
+				upper = 1
+			} else {
+				upper = 0
 			}
+		} else if isUpper != (upper == 1) {
+			return i
 		}
+	}
 
-		if !found {
-			err = ErrHrpInvalidCharacter(c)
-			return
+	return -1
+}
+
+// maxDataLength is the number of characters after the separator of the largest integrated address:
+// the public key, the address type, the extra data and the checksum
+func maxDataLength() int {
+	return (8*(32+1+ExtraDataLimit)+4)/5 + 6
+}
+
+// shiftResidue is the residue of the values followed by a zero, polymod computed from 0 instead of 1
+func shiftResidue(chk uint32) uint32 {
+	top := chk >> 25
+	chk = (chk & 0x1ffffff) << 5
+	for i, item := range GENERATOR {
+		if (top>>i)&1 == 1 {
+			chk ^= item
 		}
+	}
+
+	return chk
+}
+
+// MaxTypoPositions is the max number of likely typo positions reported for an invalid checksum
+var MaxTypoPositions = 6
+
+// locateErrors returns the data indexes where changing one or two characters gives a valid checksum,
+// single substitutions are preferred as they are the most likely typos
+func locateErrors(hrp string, data []byte) (positions []int) {
+	values := append(hrpExpand(hrp), data...)
+	target := polymod(values) ^ 1
 
-		decoded = append(decoded, index)
+	type substitution struct {
+		position int
+		value    byte
 	}
 
-	if !verifyChecksum(hrp, decoded) {
-		err = ErrInvalidChecksum
-		return
+	// polymod is affine so the residue of a corrupted string is the residue of the
+	// original string xor the residue of the error alone. The residue of a value at the
+	// last position is the value itself, each position before shifts it once more.
+	var shifted [32]uint32
+	for value := range shifted {
+		shifted[value] = uint32(value)
 	}
 
-	decoded = decoded[:len(decoded)-6]
+	residues := make(map[uint32][]substitution)
+	for position := len(data) - 1; position >= 0; position-- {
+		for value := byte(1); value < 32; value++ {
+			residues[shifted[value]] = append(residues[shifted[value]], substitution{position, value})
+			shifted[value] = shiftResidue(shifted[value])
+		}
+	}
+
+	found := make(map[int]bool)
+	add := func(position int) {
+		if !found[position] {
+			found[position] = true
+			positions = append(positions, position)
+		}
+	}
+
+	for _, single := range residues[target] {
+		add(single.position)
+	}
+
+	if len(positions) == 0 {
+		for residue, firsts := range residues {
+			for _, second := range residues[residue^target] {
+				for _, first := range firsts {
+					if first.position < second.position {
+						add(first.position)
+						add(second.position)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Ints(positions)
+	if len(positions) > MaxTypoPositions {
+		// too many candidates to guide the user
+		positions = nil
+	}
 
 	return
 }
@@ -0,0 +1,266 @@
+package address
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+)
+
+var ErrEmptyLabel = errors.New("label can't be empty")
+var ErrLabelExists = errors.New("label already used by another address")
+var ErrAddressNotFound = errors.New("address not found in book")
+
+type BookEntry struct {
+	Address   string    `json:"address"`
+	Label     string    `json:"label"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Book labels addresses of a single network, labels are unique and case insensitive
+type Book struct {
+	mutex   sync.RWMutex
+	network config.Network
+	path    string
+	entries map[string]BookEntry
+}
+
+func NewBook(network config.Network) *Book {
+	return &Book{network: network, entries: make(map[string]BookEntry)}
+}
+
+// OpenBook loads the book saved at path, a missing file is an empty book.
+// Every change is saved back to the file.
+func OpenBook(path string, network config.Network) (book *Book, err error) {
+	book = NewBook(network)
+	book.path = path
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		book = nil
+		return
+	}
+
+	var entries []BookEntry
+	err = json.Unmarshal(b, &entries)
+	if err != nil {
+		book = nil
+		return
+	}
+
+	for _, entry := range entries {
+		err = book.add(entry)
+		if err != nil {
+			book = nil
+			return
+		}
+	}
+
+	return
+}
+
+func (b *Book) validate(entry BookEntry) (err error) {
+	if strings.TrimSpace(entry.Label) == "" {
+		return ErrEmptyLabel
+	}
+
+	addr, err := NewAddressFromString(entry.Address)
+	if err != nil {
+		return
+	}
+
+	if !addr.IsNetwork(b.network) {
+		return ErrNetworkMismatch
+	}
+
+	for address, other := range b.entries {
+		if address != entry.Address && strings.EqualFold(other.Label, entry.Label) {
+			return ErrLabelExists
+		}
+	}
+
+	return
+}
+
+func (b *Book) add(entry BookEntry) (err error) {
+	err = b.validate(entry)
+	if err != nil {
+		return
+	}
+
+	b.entries[entry.Address] = entry
+	return
+}
+
+// Set adds the address or updates its label and note
+func (b *Book) Set(address string, label string, note string) (err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	previous, existed := b.entries[address]
+	entry := previous
+	if !existed {
+		entry = BookEntry{Address: address, CreatedAt: time.Now().UTC()}
+	}
+
+	entry.Label = label
+	entry.Note = note
+
+	err = b.add(entry)
+	if err != nil {
+		return
+	}
+
+	err = b.save()
+	if err != nil {
+		// keep memory in sync with the file
+		if existed {
+			b.entries[address] = previous
+		} else {
+			delete(b.entries, address)
+		}
+	}
+
+	return
+}
+
+func (b *Book) Remove(address string) (err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entry, ok := b.entries[address]
+	if !ok {
+		return ErrAddressNotFound
+	}
+
+	delete(b.entries, address)
+	err = b.save()
+	if err != nil {
+		b.entries[address] = entry
+	}
+
+	return
+}
+
+func (b *Book) Get(address string) (entry BookEntry, ok bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	entry, ok = b.entries[address]
+	return
+}
+
+func (b *Book) FindByLabel(label string) (entry BookEntry, ok bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, entry = range b.entries {
+		if strings.EqualFold(entry.Label, label) {
+			return entry, true
+		}
+	}
+
+	return BookEntry{}, false
+}
+
+// Entries returns all the entries sorted by label
+func (b *Book) Entries() (entries []BookEntry) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	entries = make([]BookEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Label) < strings.ToLower(entries[j].Label)
+	})
+
+	return
+}
+
+// PaymentURI returns a payment request to the labeled address
+func (b *Book) PaymentURI(label string, amount uint64, asset string) (uri string, err error) {
+	entry, ok := b.FindByLabel(label)
+	if !ok {
+		err = ErrAddressNotFound
+		return
+	}
+
+	payment := PaymentURI{Address: entry.Address, Amount: amount, Asset: asset}
+	return payment.Format(b.network)
+}
+
+// WriteFile saves the book as indented JSON
+func (b *Book) WriteFile(path string) (err error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.writeFile(path)
+}
+
+func (b *Book) save() (err error) {
+	if b.path == "" {
+		return
+	}
+
+	return b.writeFile(b.path)
+}
+
+// the file is replaced by a rename so a crash never leaves a partial book
+func (b *Book) writeFile(path string) (err error) {
+	entries := make([]BookEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Address < entries[j].Address
+	})
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return
+	}
+
+	err = os.Chmod(tmp.Name(), 0600)
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
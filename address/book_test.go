@@ -0,0 +1,77 @@
+package address
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+)
+
+func TestBook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.json")
+	book, err := OpenBook(path, config.Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = book.Set(MAINNET_ADDR, "Exchange", "hot wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = book.Set("xel:invalid", "Other", "")
+	if err == nil {
+		t.Fatal("expected invalid address error")
+	}
+
+	err = book.Set(MAINNET_ADDR, "", "")
+	if !errors.Is(err, ErrEmptyLabel) {
+		t.Fatalf("expected %s, got %v", ErrEmptyLabel, err)
+	}
+
+	entry, ok := book.FindByLabel("exchange")
+	if !ok || entry.Address != MAINNET_ADDR || entry.Note != "hot wallet" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	uri, err := book.PaymentURI("Exchange", 100, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment, err := ParsePaymentURI(uri, config.Mainnet)
+	if err != nil || payment.Address != MAINNET_ADDR || payment.Amount != 100 {
+		t.Fatalf("unexpected payment %+v: %v", payment, err)
+	}
+
+	reopened, err := OpenBook(path, config.Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(book.Entries(), reopened.Entries()) {
+		t.Fatalf("expected %+v, got %+v", book.Entries(), reopened.Entries())
+	}
+
+	// a book of one network can't load the other
+	_, err = OpenBook(path, config.Testnet)
+	if !errors.Is(err, ErrNetworkMismatch) {
+		t.Fatalf("expected %s, got %v", ErrNetworkMismatch, err)
+	}
+
+	err = reopened.Remove(MAINNET_ADDR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err = OpenBook(path, config.Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reopened.Entries()) != 0 {
+		t.Fatal("expected empty book")
+	}
+}
@@ -0,0 +1,226 @@
+package address
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	d "github.com/xelis-project/xelis-go-sdk/data"
+)
+
+// payment requests are formatted as xelis:<address>?amount=<atomic units>&asset=<hash>&message=<text>&data=<hex>&expiry=<unix seconds>
+// unknown parameters are ignored unless they start with req- like in bip21
+
+var URIScheme = "xelis"
+
+// MaxMessageSize is the max size in bytes of the message parameter
+var MaxMessageSize = 255
+
+var ErrInvalidScheme = fmt.Errorf("payment uri must start with %s:", URIScheme)
+var ErrNetworkMismatch = errors.New("address is not on the expected network")
+var ErrInvalidAmount = errors.New("invalid amount")
+var ErrInvalidAsset = errors.New("asset must be a 64 chars hex hash")
+var ErrMessageTooLong = fmt.Errorf("message max size is %d bytes", MaxMessageSize)
+var ErrInvalidExpiry = errors.New("invalid expiry")
+var ErrDuplicateParam = errors.New("duplicate parameter")
+var ErrDataWithIntegratedAddress = errors.New("data can't be set with an integrated address")
+
+func ErrUnknownRequiredParam(name string) error {
+	return fmt.Errorf("unknown required parameter %s", name)
+}
+
+type PaymentURI struct {
+	Address string
+	// Amount in atomic units, 0 lets the payer choose
+	Amount uint64
+	// Asset hash, empty is XELIS
+	Asset   string
+	Message string
+	// Data is attached to the transfer, only for non integrated addresses
+	Data   *d.Element
+	Expiry *time.Time
+}
+
+func validateAsset(asset string) (err error) {
+	if len(asset) != 64 {
+		return ErrInvalidAsset
+	}
+
+	_, err = hex.DecodeString(asset)
+	if err != nil {
+		return ErrInvalidAsset
+	}
+
+	return
+}
+
+// Validate checks all the fields and that the address is on the expected network
+func (p PaymentURI) Validate(network config.Network) (err error) {
+	addr, err := NewAddressFromString(p.Address)
+	if err != nil {
+		return
+	}
+
+	if !addr.IsNetwork(network) {
+		return ErrNetworkMismatch
+	}
+
+	if p.Asset != "" {
+		err = validateAsset(p.Asset)
+		if err != nil {
+			return
+		}
+	}
+
+	if len(p.Message) > MaxMessageSize {
+		return ErrMessageTooLong
+	}
+
+	if p.Data != nil {
+		if addr.IsIntegrated() {
+			return ErrDataWithIntegratedAddress
+		}
+
+		var size int
+		size, err = d.EncodedSize(*p.Data)
+		if err != nil {
+			return
+		}
+
+		if size > ExtraDataLimit {
+			return ErrIntegratedDataLimit
+		}
+	}
+
+	return
+}
+
+// IsExpired returns true if the request has an expiry before now
+func (p PaymentURI) IsExpired(now time.Time) bool {
+	return p.Expiry != nil && !now.Before(*p.Expiry)
+}
+
+// String formats the uri without validating it, use Format to validate first
+func (p PaymentURI) String() string {
+	var params []string
+	if p.Amount > 0 {
+		params = append(params, "amount="+strconv.FormatUint(p.Amount, 10))
+	}
+
+	if p.Asset != "" {
+		params = append(params, "asset="+p.Asset)
+	}
+
+	if p.Message != "" {
+		params = append(params, "message="+url.QueryEscape(p.Message))
+	}
+
+	if p.Data != nil {
+		b, err := p.Data.ToBytes()
+		if err == nil {
+			params = append(params, "data="+hex.EncodeToString(b))
+		}
+	}
+
+	if p.Expiry != nil {
+		params = append(params, "expiry="+strconv.FormatInt(p.Expiry.Unix(), 10))
+	}
+
+	uri := URIScheme + ":" + p.Address
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+
+	return uri
+}
+
+// Format validates the request for the network and returns the uri
+func (p PaymentURI) Format(network config.Network) (uri string, err error) {
+	err = p.Validate(network)
+	if err != nil {
+		return
+	}
+
+	uri = p.String()
+	return
+}
+
+// ParsePaymentURI parses and validates a payment request for the network, expiry is not checked
+func ParsePaymentURI(uri string, network config.Network) (payment PaymentURI, err error) {
+	scheme, rest, ok := strings.Cut(uri, ":")
+	if !ok || !strings.EqualFold(scheme, URIScheme) {
+		err = ErrInvalidScheme
+		return
+	}
+
+	address, rawQuery, _ := strings.Cut(rest, "?")
+	payment.Address = address
+
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return
+	}
+
+	for name, values := range params {
+		if len(values) > 1 {
+			err = fmt.Errorf("%w: %s", ErrDuplicateParam, name)
+			return
+		}
+
+		value := values[0]
+		switch name {
+		case "amount":
+			payment.Amount, err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				err = ErrInvalidAmount
+				return
+			}
+		case "asset":
+			payment.Asset = value
+		case "message":
+			payment.Message = value
+		case "data":
+			var b []byte
+			b, err = hex.DecodeString(value)
+			if err != nil {
+				return
+			}
+
+			if len(b) > ExtraDataLimit {
+				err = ErrIntegratedDataLimit
+				return
+			}
+
+			var element d.Element
+			element, err = d.ElementFromBytes(b)
+			if err != nil {
+				return
+			}
+
+			payment.Data = &element
+		case "expiry":
+			var unix int64
+			unix, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || unix <= 0 {
+				err = ErrInvalidExpiry
+				return
+			}
+
+			expiry := time.Unix(unix, 0)
+			payment.Expiry = &expiry
+		default:
+			if strings.HasPrefix(name, "req-") {
+				err = ErrUnknownRequiredParam(name)
+				return
+			}
+		}
+	}
+
+	err = payment.Validate(network)
+	return
+}
@@ -0,0 +1,95 @@
+package address
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	d "github.com/xelis-project/xelis-go-sdk/data"
+)
+
+func TestPaymentURI(t *testing.T) {
+	expiry := time.Unix(1700000000, 0)
+	data := d.Element{Fields: map[d.Value]d.Element{"order": {Value: uint64(42)}}}
+	payment := PaymentURI{
+		Address: MAINNET_ADDR,
+		Amount:  150000000,
+		Asset:   "0000000000000000000000000000000000000000000000000000000000000000",
+		Message: "order #42 & co",
+		Data:    &data,
+		Expiry:  &expiry,
+	}
+
+	uri, err := payment.Format(config.Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParsePaymentURI(uri, config.Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(payment, parsed) {
+		t.Fatalf("expected %+v, got %+v", payment, parsed)
+	}
+
+	if !parsed.IsExpired(expiry) || parsed.IsExpired(expiry.Add(-time.Second)) {
+		t.Fatal("invalid expiry check")
+	}
+
+	minimal, err := ParsePaymentURI("XELIS:"+MAINNET_ADDR+"?label=ignored", config.Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if minimal.Address != MAINNET_ADDR || minimal.Amount != 0 || minimal.Expiry != nil {
+		t.Fatalf("unexpected %+v", minimal)
+	}
+}
+
+func TestPaymentURIErrors(t *testing.T) {
+	testnetAddr, err := NewAddressFromString(MAINNET_ADDR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testnetAddr.prefix = config.TESTNET_ADDRESS_PREFIX
+	testnet, err := testnetAddr.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := "xelis:" + MAINNET_ADDR
+	tests := []struct {
+		uri string
+		err error
+	}{
+		{"xel:" + MAINNET_ADDR, ErrInvalidScheme},
+		{"xelis:" + testnet, ErrNetworkMismatch},
+		{base + "?amount=-1", ErrInvalidAmount},
+		{base + "?amount=1&amount=2", ErrDuplicateParam},
+		{base + "?asset=00", ErrInvalidAsset},
+		{base + "?expiry=0", ErrInvalidExpiry},
+		{base + "?data=0002", d.ErrShortRead},
+	}
+
+	for _, test := range tests {
+		_, err := ParsePaymentURI(test.uri, config.Mainnet)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%s: expected %s, got %v", test.uri, test.err, err)
+		}
+	}
+
+	_, err = ParsePaymentURI(base+"?req-unknown=1", config.Mainnet)
+	if err == nil {
+		t.Fatal("expected unknown required parameter error")
+	}
+
+	_, err = ParsePaymentURI("xelis:"+MAINNET_ADDR[:len(MAINNET_ADDR)-1]+"1", config.Mainnet)
+	if err == nil {
+		t.Fatal("expected invalid checksum")
+	}
+}
@@ -0,0 +1,149 @@
+package address
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	d "github.com/xelis-project/xelis-go-sdk/data"
+)
+
+type ErrorKind int
+
+const (
+	KindMixedCase ErrorKind = iota + 1
+	KindInvalidCharacter
+	KindInvalidSeparator
+	KindInvalidHrp
+	KindInvalidChecksum
+	KindInvalidLength
+	KindInvalidAddressType
+	KindIntegratedDataTooLarge
+	KindInvalidIntegratedData
+)
+
+var errorKinds = map[ErrorKind]string{
+	KindMixedCase:              "mixed_case",
+	KindInvalidCharacter:       "invalid_character",
+	KindInvalidSeparator:       "invalid_separator",
+	KindInvalidHrp:             "invalid_hrp",
+	KindInvalidChecksum:        "invalid_checksum",
+	KindInvalidLength:          "invalid_length",
+	KindInvalidAddressType:     "invalid_address_type",
+	KindIntegratedDataTooLarge: "integrated_data_too_large",
+	KindInvalidIntegratedData:  "invalid_integrated_data",
+}
+
+func (k ErrorKind) String() string {
+	if name, ok := errorKinds[k]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("unknown(%d)", int(k))
+}
+
+func (k ErrorKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+var ErrInvalidLength = errors.New("invalid address length")
+var ErrInvalidAddressType = errors.New("invalid address type")
+
+// AddressError is returned when decoding an address fails.
+// Position is the index of the faulty character in the address, -1 if unknown.
+type AddressError struct {
+	Kind     ErrorKind
+	Position int
+	// likely typo positions of an invalid checksum, empty if they can't be guessed
+	TypoPositions []int
+	Err           error
+
+	// offset in the address bytes, -1 if unknown
+	offset int64
+}
+
+func (e *AddressError) Error() string {
+	if len(e.TypoPositions) > 0 {
+		positions := make([]string, len(e.TypoPositions))
+		for i, position := range e.TypoPositions {
+			positions[i] = fmt.Sprint(position)
+		}
+
+		return fmt.Sprintf("%s: check characters at positions %s", e.Err, strings.Join(positions, ", "))
+	}
+
+	if e.Position >= 0 {
+		return fmt.Sprintf("%s at position %d", e.Err, e.Position)
+	}
+
+	return e.Err.Error()
+}
+
+func (e *AddressError) Unwrap() error {
+	return e.Err
+}
+
+// dataError returns the error of the address bytes with the position of the character holding the failing byte
+func dataError(err error, separator int) error {
+	var addrErr *AddressError
+	if !errors.As(err, &addrErr) {
+		return err
+	}
+
+	if addrErr.offset >= 0 && addrErr.Position < 0 {
+		addrErr.Position = separator + 1 + int(addrErr.offset*8/5)
+	}
+
+	return addrErr
+}
+
+// Validation is the result of Validate ready to be rendered by a frontend
+type Validation struct {
+	Address        string         `json:"address"`
+	Valid          bool           `json:"valid"`
+	Network        config.Network `json:"network,omitempty"`
+	Prefix         string         `json:"prefix,omitempty"`
+	PublicKey      string         `json:"public_key,omitempty"`
+	Integrated     bool           `json:"integrated"`
+	IntegratedData *d.Element     `json:"integrated_data,omitempty"`
+	ErrorKind      ErrorKind      `json:"error_kind,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	// nil if the error has no position
+	ErrorPosition *int  `json:"error_position,omitempty"`
+	TypoPositions []int `json:"typo_positions,omitempty"`
+}
+
+// Validate decodes the address offline and reports why it is invalid
+func Validate(address string) (validation Validation) {
+	validation.Address = address
+
+	addr, err := NewAddressFromString(address)
+	if err != nil {
+		validation.Error = err.Error()
+
+		var addrErr *AddressError
+		if errors.As(err, &addrErr) {
+			validation.ErrorKind = addrErr.Kind
+			validation.TypoPositions = addrErr.TypoPositions
+			if addrErr.Position >= 0 {
+				position := addrErr.Position
+				validation.ErrorPosition = &position
+			}
+		}
+
+		return
+	}
+
+	validation.Valid = true
+	validation.Network, _ = addr.Network()
+	validation.Prefix = addr.Prefix()
+	validation.PublicKey = hex.EncodeToString(addr.GetPublicKey())
+	validation.Integrated = addr.IsIntegrated()
+	if addr.IsIntegrated() {
+		validation.IntegratedData = addr.GetExtraData()
+	}
+
+	return
+}
@@ -0,0 +1,161 @@
+package address
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	d "github.com/xelis-project/xelis-go-sdk/data"
+)
+
+func encodeTestAddress(t *testing.T, hrp string, data []byte) string {
+	bits, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := encode(hrp, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return addr
+}
+
+func replaceAt(s string, i int, c byte) string {
+	b := []byte(s)
+	b[i] = c
+	return string(b)
+}
+
+func TestAddressErrors(t *testing.T) {
+	addr, err := NewAddressFromString(MAINNET_ADDR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey := addr.GetPublicKey()
+	element, err := d.Element{Value: d.Blob(make([]byte, 2000))}.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typo := replaceAt(MAINNET_ADDR, 20, 'q')
+	if typo == MAINNET_ADDR {
+		typo = replaceAt(MAINNET_ADDR, 20, 'p')
+	}
+
+	tests := []struct {
+		name     string
+		address  string
+		kind     ErrorKind
+		err      error
+		position int
+	}{
+		{"mixed case", replaceAt(MAINNET_ADDR, 10, 'Z'), KindMixedCase, ErrHrpMixCase, 10},
+		{"invalid character", replaceAt(MAINNET_ADDR, 12, 'b'), KindInvalidCharacter, nil, 12},
+		{"missing separator", strings.Replace(MAINNET_ADDR, ":", "", 1), KindInvalidSeparator, ErrSeparatorMissing, -1},
+		{"empty hrp", MAINNET_ADDR[3:], KindInvalidHrp, ErrHrpEmpty, 0},
+		{"too short", "xel:qqqq", KindInvalidLength, nil, 8},
+		{"typo", typo, KindInvalidChecksum, ErrInvalidChecksum, 20},
+		{"unknown hrp", encodeTestAddress(t, "abc", append(publicKey, 0)), KindInvalidHrp, ErrInvalidNetworkPrefix, 0},
+		{"short public key", encodeTestAddress(t, "xel", publicKey[:20]), KindInvalidLength, ErrInvalidLength, -1},
+		{"trailing bytes", encodeTestAddress(t, "xel", append(publicKey, 0, 0)), KindInvalidLength, ErrInvalidLength, 4 + 33*8/5},
+		{"address type", encodeTestAddress(t, "xel", append(publicKey, 2)), KindInvalidAddressType, ErrInvalidAddressType, 4 + 32*8/5},
+		{"integrated data", encodeTestAddress(t, "xel", append(append(publicKey, 1), 0, 9)), KindInvalidIntegratedData, d.ErrInvalidValueType, -1},
+		{"longer than an address", encodeTestAddress(t, "xel", append(append(publicKey, 1), element...)), KindInvalidLength, ErrInvalidLength, 4 + maxDataLength()},
+		{"long garbage", "xel:" + strings.Repeat("q", 6000), KindInvalidLength, ErrInvalidLength, 4 + maxDataLength()},
+	}
+
+	for _, test := range tests {
+		_, err := NewAddressFromString(test.address)
+
+		var addrErr *AddressError
+		if !errors.As(err, &addrErr) {
+			t.Fatalf("%s: expected address error, got %v", test.name, err)
+		}
+
+		if addrErr.Kind != test.kind || (test.err != nil && !errors.Is(err, test.err)) {
+			t.Fatalf("%s: expected %s %v, got %s %v", test.name, test.kind, test.err, addrErr.Kind, err)
+		}
+
+		if test.position >= 0 && addrErr.Position != test.position {
+			t.Fatalf("%s: expected position %d, got %d", test.name, test.position, addrErr.Position)
+		}
+	}
+	// too large data can only be given as bytes, its string is longer than any address
+	_, err = NewAddressFromData(append(append(publicKey, 1), element...), "xel")
+	var addrErr *AddressError
+	if !errors.As(err, &addrErr) || addrErr.Kind != KindIntegratedDataTooLarge || !errors.Is(err, ErrIntegratedDataLimit) {
+		t.Fatalf("expected %s, got %v", ErrIntegratedDataLimit, err)
+	}
+}
+
+func TestTypoPositions(t *testing.T) {
+	typo := replaceAt(MAINNET_ADDR, 30, 'q')
+	if typo == MAINNET_ADDR {
+		typo = replaceAt(MAINNET_ADDR, 30, 'p')
+	}
+
+	validation := Validate(typo)
+	if validation.Valid || validation.ErrorKind != KindInvalidChecksum {
+		t.Fatalf("unexpected validation %+v", validation)
+	}
+
+	if len(validation.TypoPositions) != 1 || validation.TypoPositions[0] != 30 {
+		t.Fatalf("expected typo at 30, got %v", validation.TypoPositions)
+	}
+
+	// the largest integrated address is still checked for typos
+	element, err := d.Element{Value: d.Blob(make([]byte, ExtraDataLimit-6))}.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := NewAddressFromString(MAINNET_ADDR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	largest := encodeTestAddress(t, "xel", append(append(addr.GetPublicKey(), 1), element...))
+	if len(largest) != 4+maxDataLength() {
+		t.Fatalf("expected the largest address, got %d characters", len(largest))
+	}
+
+	validation = Validate(replaceAt(largest, 1000, 'p'))
+	if len(validation.TypoPositions) != 1 || validation.TypoPositions[0] != 1000 {
+		t.Fatalf("expected typo at 1000, got %v", validation.TypoPositions)
+	}
+
+	// two typos are located too, possibly with other candidates
+	typo = replaceAt(replaceAt(MAINNET_ADDR, 40, 'q'), 50, 'q')
+	_, err = NewAddressFromString(typo)
+	if !errors.Is(err, ErrInvalidChecksum) {
+		t.Fatalf("expected %s, got %v", ErrInvalidChecksum, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validation := Validate(strings.ToUpper(MAINNET_ADDR))
+	if !validation.Valid || validation.Network != config.Mainnet || validation.Integrated || len(validation.PublicKey) != 64 {
+		t.Fatalf("unexpected validation %+v", validation)
+	}
+
+	validation = Validate("xel:")
+	b, err := json.Marshal(validation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result map[string]interface{}
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result["valid"] != false || result["error_kind"] != "invalid_length" || result["error"] == "" {
+		t.Fatalf("unexpected json %s", b)
+	}
+}
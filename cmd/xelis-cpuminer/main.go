@@ -0,0 +1,175 @@
+// Command xelis-cpuminer is a reference CPU miner for testnet and devnet automation.
+// It mines the jobs of a getwork endpoint, a node or a pool, or the block templates of a node in solo mode.
+//
+//	xelis-cpuminer -address xet:... -network Dev -threads 2
+//	xelis-cpuminer -address xet:... -solo -rpc http://127.0.0.1:8080/json_rpc -blocks 10
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+)
+
+func main() {
+	minerAddress := flag.String("address", "", "address receiving the rewards (required)")
+	worker := flag.String("worker", "cpuminer", "worker name sent to the getwork endpoint")
+	threads := flag.Int("threads", runtime.NumCPU(), "number of mining threads")
+	network := flag.String("network", "", "network of the default endpoints, from the address prefix if empty")
+	solo := flag.Bool("solo", false, "poll block templates over json rpc instead of getwork")
+	getworkEndpoint := flag.String("getwork", "", "getwork endpoint, from the network if empty")
+	rpcEndpoint := flag.String("rpc", "", "json rpc endpoint for solo mode, from the network if empty")
+	poll := flag.Duration("poll", 2*time.Second, "block template polling interval in solo mode")
+	statsInterval := flag.Duration("stats", 10*time.Second, "stats printing interval")
+	blocks := flag.Uint64("blocks", 0, "exit after this number of accepted solutions, 0 to mine forever")
+	flag.Parse()
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	err := run(logger, options{
+		minerAddress:    *minerAddress,
+		worker:          *worker,
+		threads:         *threads,
+		network:         config.Network(*network),
+		solo:            *solo,
+		getworkEndpoint: *getworkEndpoint,
+		rpcEndpoint:     *rpcEndpoint,
+		poll:            *poll,
+		statsInterval:   *statsInterval,
+		blocks:          *blocks,
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+type options struct {
+	minerAddress    string
+	worker          string
+	threads         int
+	network         config.Network
+	solo            bool
+	getworkEndpoint string
+	rpcEndpoint     string
+	poll            time.Duration
+	statsInterval   time.Duration
+	blocks          uint64
+}
+
+func run(logger *log.Logger, opts options) (err error) {
+	if opts.threads <= 0 {
+		return fmt.Errorf("invalid number of threads %d", opts.threads)
+	}
+
+	if opts.statsInterval <= 0 {
+		return fmt.Errorf("invalid stats interval %s", opts.statsInterval)
+	}
+
+	if opts.poll <= 0 {
+		return fmt.Errorf("invalid polling interval %s", opts.poll)
+	}
+
+	addr, err := address.NewAddressFromString(opts.minerAddress)
+	if err != nil {
+		return fmt.Errorf("invalid miner address: %w", err)
+	}
+
+	if opts.network == "" {
+		opts.network, err = addr.Network()
+		if err != nil {
+			return
+		}
+	}
+
+	if !addr.IsNetwork(opts.network) {
+		return fmt.Errorf("miner address is not a %s address", opts.network)
+	}
+
+	profile, err := opts.network.Profile()
+	if err != nil {
+		return
+	}
+
+	var src source
+	if opts.solo {
+		endpoint := opts.rpcEndpoint
+		if endpoint == "" {
+			endpoint = profile.NodeRPC
+		}
+
+		var rpc *daemon.RPC
+		rpc, err = daemon.NewRPC(endpoint)
+		if err != nil {
+			return
+		}
+
+		src = newSoloSource(rpc, opts.minerAddress, opts.poll)
+		logger.Printf("solo mining on %s with %d threads", endpoint, opts.threads)
+	} else {
+		endpoint := opts.getworkEndpoint
+		if endpoint == "" {
+			endpoint = profile.NodeGetwork
+		}
+
+		src, err = newGetworkSource(endpoint, opts.minerAddress, opts.worker)
+		if err != nil {
+			return
+		}
+
+		logger.Printf("mining on %s with %d threads", endpoint, opts.threads)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	m := newMiner(opts.threads, opts.blocks, logger)
+	go printStats(ctx, logger, m, opts.statsInterval)
+
+	err = m.Run(ctx, src)
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+
+	s := m.stats()
+	logger.Printf("stopped: %d hashes, %d found, %d accepted, %d rejected", s.Hashes, s.Found, s.Accepted, s.Rejected)
+	return
+}
+
+func printStats(ctx context.Context, logger *log.Logger, m *miner, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := m.stats()
+	lastAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s := m.stats()
+			hashrate := float64(s.Hashes-last.Hashes) / now.Sub(lastAt).Seconds()
+			logger.Printf("height %d, %s, found %d, accepted %d, rejected %d", s.Height, formatHashrate(hashrate), s.Found, s.Accepted, s.Rejected)
+			last, lastAt = s, now
+		}
+	}
+}
+
+func formatHashrate(hashrate float64) string {
+	units := []string{"H/s", "KH/s", "MH/s", "GH/s", "TH/s"}
+	unit := 0
+	for hashrate >= 1000 && unit < len(units)-1 {
+		hashrate /= 1000
+		unit++
+	}
+
+	return fmt.Sprintf("%.2f %s", hashrate, units[unit])
+}
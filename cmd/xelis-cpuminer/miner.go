@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/getwork"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+	"github.com/xelis-project/xelis-go-sdk/pow"
+)
+
+type job struct {
+	id         uint64
+	algorithm  daemon.AlgorithmVersion
+	height     uint64
+	difficulty *big.Int
+	target     *big.Int
+	work       work.MinerWork
+
+	// block template submitted with the miner work in solo mode
+	template string
+	// job answered in getwork mode
+	getworkJob getwork.Job
+}
+
+type solution struct {
+	job  *job
+	work work.MinerWork
+}
+
+// source delivers jobs to the miner and reports the results of its solutions
+type source interface {
+	Run(ctx context.Context, m *miner) error
+	Submit(m *miner, s solution) error
+}
+
+type stats struct {
+	Height   uint64
+	Hashes   uint64
+	Found    uint64
+	Accepted uint64
+	Rejected uint64
+}
+
+type miner struct {
+	threads int
+	// stop after this number of accepted solutions, 0 to never stop
+	maxAccepted uint64
+	logger      *log.Logger
+
+	jobId     atomic.Uint64
+	job       atomic.Pointer[job]
+	solutions chan solution
+	stop      context.CancelFunc
+
+	hashes   atomic.Uint64
+	found    atomic.Uint64
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+}
+
+func newMiner(threads int, maxAccepted uint64, logger *log.Logger) *miner {
+	return &miner{
+		threads:     threads,
+		maxAccepted: maxAccepted,
+		logger:      logger,
+		solutions:   make(chan solution, 16),
+	}
+}
+
+// setJob switches all the workers to the job, the solutions of the previous job are still submitted
+func (m *miner) setJob(j *job) {
+	j.id = m.jobId.Add(1)
+	m.job.Store(j)
+}
+
+// done reports if enough solutions were accepted, the results of the solutions
+// still in flight are not counted once it is done
+func (m *miner) done() bool {
+	return m.maxAccepted > 0 && m.accepted.Load() >= m.maxAccepted
+}
+
+func (m *miner) result(accepted bool, reason string) {
+	if m.done() {
+		return
+	}
+
+	if !accepted {
+		m.rejected.Add(1)
+		m.logger.Printf("solution rejected: %s", reason)
+		return
+	}
+
+	count := m.accepted.Add(1)
+	m.logger.Printf("solution accepted (%d)", count)
+	if m.maxAccepted > 0 && count >= m.maxAccepted {
+		m.stop()
+	}
+}
+
+func (m *miner) stats() (s stats) {
+	if j := m.job.Load(); j != nil {
+		s.Height = j.height
+	}
+
+	s.Hashes = m.hashes.Load()
+	s.Found = m.found.Load()
+	s.Accepted = m.accepted.Load()
+	s.Rejected = m.rejected.Load()
+	return
+}
+
+// Run mines the jobs of the source until ctx is done or enough solutions are accepted
+func (m *miner) Run(ctx context.Context, src source) (err error) {
+	ctx, m.stop = context.WithCancel(ctx)
+	defer m.stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < m.threads; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			m.mine(ctx, index)
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.submit(ctx, src)
+	}()
+
+	err = src.Run(ctx, m)
+	m.stop()
+	wg.Wait()
+
+	// stopped by maxAccepted
+	if m.done() {
+		err = nil
+	}
+
+	return
+}
+
+func (m *miner) submit(ctx context.Context, src source) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-m.solutions:
+			err := src.Submit(m, s)
+			if err != nil && !m.done() {
+				m.rejected.Add(1)
+				m.logger.Printf("can't submit solution: %s", err)
+			}
+		}
+	}
+}
+
+// mine hashes the nonces index, index + threads, ... of the current job until it changes
+func (m *miner) mine(ctx context.Context, index int) {
+	hasher := pow.NewHasher()
+	buffer := make([]byte, work.Size)
+
+	var current *job
+	var minerWork work.MinerWork
+	for ctx.Err() == nil {
+		j := m.job.Load()
+		if j == nil || (j == current && !pow.Supported(j.algorithm)) {
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+
+		if j != current {
+			current = j
+			minerWork = j.work
+			minerWork.SetNonce(uint64(index))
+
+			if !pow.Supported(j.algorithm) {
+				if index == 0 {
+					m.logger.Print(pow.ErrAlgorithm(j.algorithm))
+				}
+
+				continue
+			}
+		}
+
+		minerWork.Write(buffer)
+		hash, err := hasher.Hash(j.algorithm, buffer)
+		if err != nil {
+			m.logger.Print(err)
+			return
+		}
+		m.hashes.Add(1)
+
+		if work.MeetsTarget(hash, j.target) {
+			m.found.Add(1)
+			select {
+			case m.solutions <- solution{job: j, work: minerWork}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		minerWork.Nonce += uint64(m.threads)
+	}
+}
+
+// newJob parses the miner work and difficulty sent by the node or the pool
+func newJob(algorithm daemon.AlgorithmVersion, height uint64, difficulty string, minerWork string) (j *job, err error) {
+	w, err := work.DecodeHex(minerWork)
+	if err != nil {
+		return
+	}
+
+	d, err := work.ParseDifficulty(difficulty)
+	if err != nil {
+		return
+	}
+
+	target, err := work.Target(d)
+	if err != nil {
+		return
+	}
+
+	j = &job{algorithm: algorithm, height: height, difficulty: d, target: target, work: w}
+	return
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/getwork"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+)
+
+const MINER_ADDR = "xel:ys4peuzztwl67rzhsdu0yxfzwcfmgt85uu53hycpeeary7n8qvysqmxznt0"
+
+var discard = log.New(io.Discard, "", 0)
+
+func testMinerWork() string {
+	var w work.MinerWork
+	copy(w.WorkHash[:], "work hash")
+	return w.String()
+}
+
+type fakeDaemon struct {
+	mutex     sync.Mutex
+	submitted []daemon.SubmitBlockParams
+}
+
+func (f *fakeDaemon) GetBlockTemplate(params daemon.GetBlockTemplateParams) (daemon.GetBlockTemplateResult, error) {
+	return daemon.GetBlockTemplateResult{Template: "template", Algorithm: daemon.AlgorithmV2, Height: 3, Difficulty: "1"}, nil
+}
+
+func (f *fakeDaemon) GetMinerWork(params daemon.GetMinerWorkParams) (daemon.GetMinerWorkResult, error) {
+	return daemon.GetMinerWorkResult{MinerWork: testMinerWork()}, nil
+}
+
+func (f *fakeDaemon) SubmitBlock(params daemon.SubmitBlockParams) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.submitted = append(f.submitted, params)
+	return true, nil
+}
+
+func runMiner(t *testing.T, m *miner, src source) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := m.Run(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// with the difficulty 1 each hash is a solution
+func TestSolo(t *testing.T) {
+	d := &fakeDaemon{}
+	m := newMiner(2, 3, discard)
+	runMiner(t, m, newSoloSource(d, MINER_ADDR, 10*time.Millisecond))
+
+	s := m.stats()
+	if s.Accepted != 3 || s.Rejected != 0 || s.Height != 3 || s.Hashes < 3 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	nonces := make(map[uint64]bool)
+	for _, params := range d.submitted {
+		w, err := work.DecodeHex(*params.MinerWork)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if params.BlockTemplate != "template" || string(w.WorkHash[:9]) != "work hash" || nonces[w.Nonce] {
+			t.Fatalf("unexpected submission %+v", params)
+		}
+
+		nonces[w.Nonce] = true
+	}
+}
+
+func TestGetwork(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteJSON(map[string]getwork.MinerWork{getwork.NewJob: {
+			MinerWork:  testMinerWork(),
+			Height:     7,
+			Difficulty: "1",
+			Algorithm:  daemon.AlgorithmV2,
+		}})
+
+		// the first solution is rejected
+		for i := 0; ; i++ {
+			var submit struct {
+				BlockTemplate string `json:"block_template"`
+			}
+
+			err := conn.ReadJSON(&submit)
+			if err != nil {
+				return
+			}
+
+			_, err = hex.DecodeString(submit.BlockTemplate)
+			if err != nil || i == 0 {
+				conn.WriteJSON(map[string]string{getwork.BlockRejected: "rejected"})
+			} else {
+				conn.WriteJSON(getwork.BlockAccepted)
+			}
+		}
+	}))
+	defer server.Close()
+
+	src, err := newGetworkSource("ws"+strings.TrimPrefix(server.URL, "http")+"/getwork", MINER_ADDR, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newMiner(1, 2, discard)
+	runMiner(t, m, src)
+
+	s := m.stats()
+	if s.Accepted != 2 || s.Rejected != 1 || s.Height != 7 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestUnsupportedAlgorithm(t *testing.T) {
+	j, err := newJob(daemon.AlgorithmV3, 1, "1", testMinerWork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newMiner(1, 0, discard)
+	m.setJob(j)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	m.mine(ctx, 0)
+
+	if m.hashes.Load() != 0 || len(m.solutions) != 0 {
+		t.Fatal("jobs of unsupported algorithms must not be mined")
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	invalid := []options{
+		{threads: 1, poll: time.Second},
+		{threads: 1, statsInterval: time.Second},
+	}
+
+	for _, opts := range invalid {
+		err := run(discard, opts)
+		if err == nil || !strings.Contains(err.Error(), "interval") {
+			t.Fatalf("expected an invalid interval error, got %v", err)
+		}
+	}
+}
+
+func TestFormatHashrate(t *testing.T) {
+	if formatHashrate(12) != "12.00 H/s" || formatHashrate(1500000) != "1.50 MH/s" {
+		t.Fatal(formatHashrate(1500000))
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/getwork"
+)
+
+// getworkSource mines the jobs pushed by the getwork endpoint of a node or a pool
+type getworkSource struct {
+	client *getwork.Client
+}
+
+func newGetworkSource(endpoint, minerAddress, worker string) (*getworkSource, error) {
+	client, err := getwork.NewClient(endpoint, minerAddress, worker, getwork.DefaultClientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getworkSource{client: client}, nil
+}
+
+func (s *getworkSource) Run(ctx context.Context, m *miner) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.client.Run(ctx)
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case getworkJob := <-s.client.Jobs():
+			j, err := newJob(getworkJob.Algorithm, getworkJob.Height, getworkJob.Difficulty, getworkJob.Template)
+			if err != nil {
+				m.logger.Printf("invalid job: %s", err)
+				continue
+			}
+
+			j.getworkJob = getworkJob
+			m.setJob(j)
+			m.logger.Printf("new job at height %d, difficulty %s", j.height, j.difficulty)
+		case result := <-s.client.Results():
+			switch {
+			case result.Err != nil:
+				m.result(false, result.Err.Error())
+			default:
+				m.result(result.Accepted, result.Reason)
+			}
+		case err := <-s.client.Errors():
+			m.logger.Printf("getwork: %s", err)
+		}
+	}
+}
+
+// Submit sends the solution, the result is delivered by the connection
+func (s *getworkSource) Submit(m *miner, sol solution) error {
+	return s.client.Submit(sol.job.getworkJob, sol.work.String())
+}
+
+// soloDaemon is implemented by daemon.RPC and daemon.WebSocket
+type soloDaemon interface {
+	GetBlockTemplate(params daemon.GetBlockTemplateParams) (daemon.GetBlockTemplateResult, error)
+	GetMinerWork(params daemon.GetMinerWorkParams) (daemon.GetMinerWorkResult, error)
+	SubmitBlock(params daemon.SubmitBlockParams) (bool, error)
+}
+
+// soloSource polls the block template of the node and submits the blocks found
+type soloSource struct {
+	daemon   soloDaemon
+	address  string
+	interval time.Duration
+	refresh  chan struct{}
+
+	template string
+}
+
+func newSoloSource(d soloDaemon, minerAddress string, interval time.Duration) *soloSource {
+	return &soloSource{
+		daemon:   d,
+		address:  minerAddress,
+		interval: interval,
+		refresh:  make(chan struct{}, 1),
+	}
+}
+
+func (s *soloSource) Run(ctx context.Context, m *miner) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.poll(m)
+		if err != nil {
+			m.logger.Printf("can't get block template: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.refresh:
+		}
+	}
+}
+
+func (s *soloSource) poll(m *miner) (err error) {
+	template, err := s.daemon.GetBlockTemplate(daemon.GetBlockTemplateParams{Address: s.address})
+	if err != nil || template.Template == s.template {
+		return
+	}
+
+	minerWork, err := s.daemon.GetMinerWork(daemon.GetMinerWorkParams{Template: template.Template})
+	if err != nil {
+		return
+	}
+
+	j, err := newJob(template.Algorithm, template.Height, template.Difficulty, minerWork.MinerWork)
+	if err != nil {
+		return
+	}
+
+	s.template = template.Template
+	j.template = template.Template
+	m.setJob(j)
+	m.logger.Printf("new block template at height %d, difficulty %s", j.height, j.difficulty)
+	return
+}
+
+// Submit sends the block and asks for a new template, the node answers with an error if it is rejected
+func (s *soloSource) Submit(m *miner, sol solution) error {
+	_, err := s.daemon.SubmitBlock(sol.work.SubmitParams(sol.job.template))
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+
+	if err != nil {
+		m.result(false, err.Error())
+	} else {
+		m.result(true, "")
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Network is the network of a node, values are the ones returned by get_info
+type Network string
+
+const (
+	Mainnet  Network = "Mainnet"
+	Testnet  Network = "Testnet"
+	Stagenet Network = "Stagenet"
+	Devnet   Network = "Dev"
+)
+
+const MAINNET_ADDRESS_PREFIX = "xel"
+const TESTNET_ADDRESS_PREFIX = "xet"
+
+var ErrUnknownNetwork = errors.New("unknown network")
+var ErrNetworkExists = errors.New("network already registered")
+var ErrInvalidAddressPrefix = errors.New("address prefix must be lowercase ascii letters and digits")
+
+// NetworkProfile is the address prefix and the public endpoints of a network, endpoints are empty when there is none
+type NetworkProfile struct {
+	Network       Network
+	AddressPrefix string
+	NodeRPC       string
+	NodeWS        string
+	NodeGetwork   string
+}
+
+var networksLock sync.RWMutex
+
+// registered in order, the first network of a prefix is the one returned by NetworkFromPrefix
+var networks = []NetworkProfile{
+	{Mainnet, MAINNET_ADDRESS_PREFIX, MAINNET_NODE_RPC, MAINNET_NODE_WS, MAINNET_NODE_GETWORK},
+	{Testnet, TESTNET_ADDRESS_PREFIX, TESTNET_NODE_RPC, TESTNET_NODE_WS, TESTNET_NODE_GETWORK},
+	{Stagenet, TESTNET_ADDRESS_PREFIX, "", "", ""},
+	{Devnet, TESTNET_ADDRESS_PREFIX, LOCAL_NODE_RPC, LOCAL_NODE_WS, LOCAL_NODE_GETWORK},
+}
+
+func validPrefix(prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+
+	for _, c := range []byte(prefix) {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RegisterNetwork adds a custom network, its prefix can be shared with other networks
+func RegisterNetwork(profile NetworkProfile) (err error) {
+	if !validPrefix(profile.AddressPrefix) {
+		return ErrInvalidAddressPrefix
+	}
+
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	for _, existing := range networks {
+		if existing.Network == profile.Network {
+			return fmt.Errorf("%w: %s", ErrNetworkExists, profile.Network)
+		}
+	}
+
+	networks = append(networks, profile)
+	return
+}
+
+// Profile returns the registered profile of the network
+func (n Network) Profile() (profile NetworkProfile, err error) {
+	networksLock.RLock()
+	defer networksLock.RUnlock()
+
+	for _, profile = range networks {
+		if profile.Network == n {
+			return
+		}
+	}
+
+	err = fmt.Errorf("%w: %s", ErrUnknownNetwork, n)
+	return
+}
+
+// AddressPrefix returns the human readable part of the network addresses, empty if unknown
+func (n Network) AddressPrefix() string {
+	profile, err := n.Profile()
+	if err != nil {
+		return ""
+	}
+
+	return profile.AddressPrefix
+}
+
+func (n Network) IsMainnet() bool {
+	return n == Mainnet
+}
+
+// NetworkFromPrefix returns the first network registered with the address prefix,
+// testnet, stagenet and devnet share the same prefix so testnet is returned
+func NetworkFromPrefix(prefix string) (network Network, err error) {
+	networksLock.RLock()
+	defer networksLock.RUnlock()
+
+	for _, profile := range networks {
+		if profile.AddressPrefix == prefix {
+			network = profile.Network
+			return
+		}
+	}
+
+	err = fmt.Errorf("%w: no network with address prefix %s", ErrUnknownNetwork, prefix)
+	return
+}
+
+// Networks returns all the registered profiles
+func Networks() []NetworkProfile {
+	networksLock.RLock()
+	defer networksLock.RUnlock()
+
+	return append([]NetworkProfile(nil), networks...)
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"encoding/json"
 
+	"github.com/xelis-project/xelis-go-sdk/config"
 	"github.com/xelis-project/xelis-go-sdk/transaction"
 	"github.com/xelis-project/xelis-go-sdk/xvm"
 )
@@ -346,12 +347,12 @@ const BlockV4 BlockVersion = "V4"
 const BlockV5 BlockVersion = "V5"
 const BlockV6 BlockVersion = "V6"
 
-type Network string
+type Network = config.Network
 
-const NetworkDev Network = "Dev"
-const NetworkTestnet Network = "Testnet"
-const NetworkStagenet Network = "Stagenet"
-const NetworkMainnet Network = "Mainnet"
+const NetworkDev = config.Devnet
+const NetworkTestnet = config.Testnet
+const NetworkStagenet = config.Stagenet
+const NetworkMainnet = config.Mainnet
 
 type GetInfoResult struct {
 	Height            uint64       `json:"height"`
@@ -0,0 +1,346 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+)
+
+type TxState string
+
+const (
+	// Transaction is not known by the node yet
+	TxPending   TxState = "pending"
+	TxInMempool TxState = "in_mempool"
+	TxExecuted  TxState = "executed"
+	// Block executing the transaction is at or below the stable topoheight
+	TxStable TxState = "stable"
+	// Block executing the transaction was orphaned, the tx usually goes back to mempool
+	TxOrphaned TxState = "orphaned"
+	// Transaction was in mempool and disappeared without being executed
+	TxDropped TxState = "dropped"
+)
+
+var ErrTxDropped = errors.New("transaction dropped from mempool")
+
+type TxStatus struct {
+	Hash       string  `json:"hash"`
+	State      TxState `json:"state"`
+	BlockHash  string  `json:"block_hash,omitempty"`
+	Topoheight uint64  `json:"topoheight,omitempty"`
+	Err        error   `json:"-"`
+}
+
+type WaitOptions struct {
+	// State to reach before WaitForTransaction returns, TxStable by default
+	Until TxState
+	// Interval between two polls, also used as a fallback when events are available
+	PollInterval time.Duration
+}
+
+// TxTrackerClient is implemented by RPC and WebSocket
+type TxTrackerClient interface {
+	GetTransaction(params GetTransactionParams) (TransactionResponse, error)
+	GetTransactionExecutor(params GetTransactionExecutorParams) (GetTransactionExecutorResult, error)
+	GetStableTopoheight() (uint64, error)
+}
+
+// TxTracker follows transactions until they are stable.
+// Create one tracker per client and share it, events are subscribed only once.
+type TxTracker struct {
+	client TxTrackerClient
+
+	mutex    sync.Mutex
+	watchers map[string]map[*txWatcher]struct{}
+}
+
+type txWatcher struct {
+	hash string
+	wake chan struct{}
+}
+
+// NewTxTracker creates a tracker polling the node, use it with RPC (HTTP)
+func NewTxTracker(client TxTrackerClient) *TxTracker {
+	return &TxTracker{
+		client:   client,
+		watchers: make(map[string]map[*txWatcher]struct{}),
+	}
+}
+
+// NewTxTrackerWS creates a tracker refreshing transactions on TransactionAddedInMempool,
+// TransactionExecuted, TransactionOrphaned, BlockOrphaned and StableTopoheightChanged events
+func NewTxTrackerWS(ws *WebSocket) (tracker *TxTracker, err error) {
+	tracker = NewTxTracker(ws)
+
+	err = ws.TransactionAddedInMempoolFunc(func(tx Transaction, err error) {
+		if err == nil {
+			tracker.wake(tx.Hash)
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	err = ws.TransactionExecutedFunc(func(event TransactionExecutedEvent, err error) {
+		if err == nil {
+			tracker.wake(event.TxHash)
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	err = ws.TransactionOrphanedFunc(func(tx Transaction, err error) {
+		if err == nil {
+			tracker.wake(tx.Hash)
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	// we don't know which transactions were in the orphaned block so refresh everything
+	err = ws.BlockOrphanedFunc(func(event BlockOrphanedEvent, err error) {
+		if err == nil {
+			tracker.wakeAll()
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	err = ws.StableTopoheightChangedFunc(func(event StableTopoheightChangedEvent, err error) {
+		if err == nil {
+			tracker.wakeAll()
+		}
+	})
+	return
+}
+
+// events are received in the websocket listener so we never block here
+func (t *TxTracker) wake(hash string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for watcher := range t.watchers[hash] {
+		select {
+		case watcher.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *TxTracker) wakeAll() {
+	t.mutex.Lock()
+	hashes := make([]string, 0, len(t.watchers))
+	for hash := range t.watchers {
+		hashes = append(hashes, hash)
+	}
+	t.mutex.Unlock()
+
+	for _, hash := range hashes {
+		t.wake(hash)
+	}
+}
+
+func (t *TxTracker) addWatcher(hash string) *txWatcher {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	watcher := &txWatcher{hash: hash, wake: make(chan struct{}, 1)}
+	watchers, ok := t.watchers[hash]
+	if !ok {
+		watchers = make(map[*txWatcher]struct{})
+		t.watchers[hash] = watchers
+	}
+
+	watchers[watcher] = struct{}{}
+	return watcher
+}
+
+func (t *TxTracker) removeWatcher(watcher *txWatcher) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	watchers := t.watchers[watcher.hash]
+	delete(watchers, watcher)
+	if len(watchers) == 0 {
+		delete(t.watchers, watcher.hash)
+	}
+}
+
+// code of the daemon errors, it is shared by other errors so the message is checked too
+const errCodeAny = -32004
+
+// isTxNotFound reports if the node answered that it doesn't know the transaction,
+// the other errors don't tell anything about the transaction
+func isTxNotFound(err error) bool {
+	var rpcErr *rpc.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == errCodeAny && strings.Contains(strings.ToLower(rpcErr.Message), "not found")
+}
+
+// fetch the current state of the transaction, previous state is used to detect orphaned and dropped transactions
+func (t *TxTracker) fetchStatus(hash string, previous TxStatus) (status TxStatus, err error) {
+	status = TxStatus{Hash: hash, State: previous.State}
+
+	tx, err := t.client.GetTransaction(GetTransactionParams{Hash: hash})
+	if err != nil {
+		if !isTxNotFound(err) {
+			return
+		}
+
+		err = nil
+		switch previous.State {
+		case TxInMempool, TxOrphaned:
+			status.State = TxDropped
+		case TxExecuted, TxStable:
+			status.State = TxOrphaned
+		default:
+			status.State = TxPending
+		}
+		return
+	}
+
+	if tx.ExecutedInBlock == nil {
+		switch previous.State {
+		case TxExecuted, TxStable:
+			status.State = TxOrphaned
+		default:
+			if tx.InMempool {
+				status.State = TxInMempool
+			} else {
+				status.State = TxPending
+			}
+		}
+		return
+	}
+
+	executor, err := t.client.GetTransactionExecutor(GetTransactionExecutorParams{Hash: hash})
+	if err != nil {
+		return
+	}
+
+	stableTopoheight, err := t.client.GetStableTopoheight()
+	if err != nil {
+		return
+	}
+
+	status.BlockHash = executor.BlockHash
+	status.Topoheight = executor.BlockTopoheight
+	if executor.BlockTopoheight <= stableTopoheight {
+		status.State = TxStable
+	} else {
+		status.State = TxExecuted
+	}
+
+	return
+}
+
+func (o WaitOptions) withDefaults() WaitOptions {
+	if o.Until == "" {
+		o.Until = TxStable
+	}
+
+	if o.PollInterval == 0 {
+		o.PollInterval = 5 * time.Second
+	}
+
+	return o
+}
+
+func isFinalState(state TxState, until TxState) bool {
+	switch state {
+	case TxDropped, TxStable:
+		return true
+	case TxExecuted:
+		return until == TxExecuted || until == TxInMempool
+	case TxInMempool:
+		return until == TxInMempool
+	}
+
+	return false
+}
+
+// Track sends every state change of the transaction in the returned channel.
+// The channel is closed once the Until state is reached, the tx is dropped or the context is done.
+// A status with Err set is sent if the node can't be reached.
+func (t *TxTracker) Track(ctx context.Context, hash string, opts WaitOptions) <-chan TxStatus {
+	opts = opts.withDefaults()
+	updates := make(chan TxStatus, 8)
+	watcher := t.addWatcher(hash)
+
+	go func() {
+		defer close(updates)
+		defer t.removeWatcher(watcher)
+
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+
+		current := TxStatus{Hash: hash, State: TxPending}
+		send := func(status TxStatus) bool {
+			select {
+			case updates <- status:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if !send(current) {
+			return
+		}
+
+		for {
+			status, err := t.fetchStatus(hash, current)
+			if err != nil {
+				status.Err = err
+				if !send(status) {
+					return
+				}
+			} else if status.State != current.State || status.BlockHash != current.BlockHash {
+				current = status
+				if !send(current) {
+					return
+				}
+
+				if isFinalState(current.State, opts.Until) {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-watcher.wake:
+			}
+		}
+	}()
+
+	return updates
+}
+
+// WaitForTransaction blocks until the transaction reaches the Until state (TxStable by default).
+func (t *TxTracker) WaitForTransaction(ctx context.Context, hash string, opts WaitOptions) (status TxStatus, err error) {
+	opts = opts.withDefaults()
+	for update := range t.Track(ctx, hash, opts) {
+		if update.Err == nil {
+			status = update
+		}
+	}
+
+	if status.State == TxDropped {
+		err = ErrTxDropped
+		return
+	}
+
+	if !isFinalState(status.State, opts.Until) && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	return
+}
@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+)
+
+type fakeTrackerClient struct {
+	mutex            sync.Mutex
+	tx               *TransactionResponse
+	err              error
+	executor         GetTransactionExecutorResult
+	stableTopoheight uint64
+}
+
+func (f *fakeTrackerClient) GetTransaction(params GetTransactionParams) (tx TransactionResponse, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.err != nil {
+		err = f.err
+		return
+	}
+
+	if f.tx == nil {
+		err = &rpc.RPCError{Code: -32004, Message: "transaction not found"}
+		return
+	}
+
+	tx = *f.tx
+	return
+}
+
+func (f *fakeTrackerClient) GetTransactionExecutor(params GetTransactionExecutorParams) (GetTransactionExecutorResult, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.executor, nil
+}
+
+func (f *fakeTrackerClient) GetStableTopoheight() (uint64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.stableTopoheight, nil
+}
+
+func (f *fakeTrackerClient) update(fn func()) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	fn()
+}
+
+func TestTxTrackerStable(t *testing.T) {
+	client := &fakeTrackerClient{}
+	tracker := NewTxTracker(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updates := tracker.Track(ctx, "tx", WaitOptions{PollInterval: 10 * time.Millisecond})
+
+	var states []TxState
+	for status := range updates {
+		if status.Err != nil {
+			t.Fatal(status.Err)
+		}
+
+		states = append(states, status.State)
+		switch status.State {
+		case TxPending:
+			client.update(func() { client.tx = &TransactionResponse{InMempool: true} })
+		case TxInMempool:
+			blockHash := "block"
+			client.update(func() {
+				client.tx = &TransactionResponse{ExecutedInBlock: &blockHash}
+				client.executor = GetTransactionExecutorResult{BlockHash: blockHash, BlockTopoheight: 10}
+			})
+		case TxExecuted:
+			client.update(func() { client.stableTopoheight = 10 })
+		}
+	}
+
+	expected := []TxState{TxPending, TxInMempool, TxExecuted, TxStable}
+	if len(states) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, states)
+	}
+
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, states)
+		}
+	}
+}
+
+func TestTxTrackerOrphanedAndDropped(t *testing.T) {
+	blockHash := "block"
+	client := &fakeTrackerClient{
+		tx:       &TransactionResponse{ExecutedInBlock: &blockHash},
+		executor: GetTransactionExecutorResult{BlockHash: blockHash, BlockTopoheight: 10},
+	}
+	tracker := NewTxTracker(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var last TxStatus
+	var orphaned bool
+	for status := range tracker.Track(ctx, "tx", WaitOptions{PollInterval: 10 * time.Millisecond}) {
+		last = status
+		switch status.State {
+		case TxExecuted:
+			client.update(func() { client.tx = &TransactionResponse{InMempool: true} })
+		case TxOrphaned:
+			orphaned = true
+			client.update(func() { client.tx = nil })
+		}
+	}
+
+	if !orphaned {
+		t.Fatal("expected transaction to be orphaned")
+	}
+
+	if last.State != TxDropped {
+		t.Fatalf("expected dropped state, got %s", last.State)
+	}
+}
+
+func TestTxTrackerContextDone(t *testing.T) {
+	tracker := NewTxTracker(&fakeTrackerClient{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	status, err := tracker.WaitForTransaction(ctx, "tx", WaitOptions{PollInterval: 10 * time.Millisecond})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	if status.State != TxPending {
+		t.Fatalf("expected pending state, got %s", status.State)
+	}
+}
+
+func TestTxTrackerNodeError(t *testing.T) {
+	client := &fakeTrackerClient{err: &rpc.RPCError{Code: -32603, Message: "internal error"}}
+	tracker := NewTxTracker(client)
+
+	for _, previous := range []TxState{TxInMempool, TxExecuted} {
+		status, err := tracker.fetchStatus("tx", TxStatus{Hash: "tx", State: previous})
+		if err == nil || status.State != previous {
+			t.Fatalf("expected the error and the %s state to be kept, got %s %v", previous, status.State, err)
+		}
+	}
+}
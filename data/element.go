@@ -23,6 +23,14 @@ type Element struct {
 func ElementFromBytes(data []byte) (dataElement Element, err error) {
 	reader := ValueReader{Reader: bytes.NewReader(data)}
 	dataElement, err = reader.Read()
+	if err != nil {
+		return
+	}
+
+	if reader.Reader.Len() > 0 {
+		err = &ReadError{Offset: reader.offset(), Err: ErrTrailingBytes}
+	}
+
 	return
 }
 
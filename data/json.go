@@ -0,0 +1,372 @@
+package data
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// UnmarshalJSON decodes the plain JSON sent by the wallet and produced by MarshalJSON.
+// Numbers become u64 or u128 and object keys strings, use UnmarshalTypedJSON to keep the exact types.
+func (d *Element) UnmarshalJSON(b []byte) (err error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var v interface{}
+	err = decoder.Decode(&v)
+	if err != nil {
+		return
+	}
+
+	*d, err = plainToElement(v)
+	return
+}
+
+func plainToElement(v interface{}) (element Element, err error) {
+	switch v := v.(type) {
+	case nil:
+		// empty element
+	case bool, string:
+		element.Value = v
+	case json.Number:
+		element.Value, err = parseNumber(v.String())
+	case []interface{}:
+		element.Array = make([]Element, 0, len(v))
+		for _, item := range v {
+			var e Element
+			e, err = plainToElement(item)
+			if err != nil {
+				return
+			}
+
+			element.Array = append(element.Array, e)
+		}
+	case map[string]interface{}:
+		element.Fields = make(map[Value]Element, len(v))
+		for key, item := range v {
+			var e Element
+			e, err = plainToElement(item)
+			if err != nil {
+				return
+			}
+
+			element.Fields[key] = e
+		}
+	default:
+		err = fmt.Errorf("unsupported json value %T", v)
+	}
+
+	return
+}
+
+func parseNumber(s string) (value Value, err error) {
+	number, ok := new(big.Int).SetString(s, 10)
+	if !ok || number.Sign() < 0 {
+		err = fmt.Errorf("invalid number %s, only unsigned integers are supported", s)
+		return
+	}
+
+	if number.IsUint64() {
+		value = number.Uint64()
+		return
+	}
+
+	if number.BitLen() > 128 {
+		err = ErrOverflow
+		return
+	}
+
+	value = *number
+	return
+}
+
+var valueTypeJSONNames = map[ValueType]string{
+	BoolType:   "bool",
+	StringType: "string",
+	U8Type:     "u8",
+	U16Type:    "u16",
+	U32Type:    "u32",
+	U64Type:    "u64",
+	U128Type:   "u128",
+	HashType:   "hash",
+	BlobType:   "blob",
+}
+
+type typedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type typedField struct {
+	Key   typedValue   `json:"key"`
+	Value TypedElement `json:"value"`
+}
+
+type typedElement struct {
+	Value  *typedValue    `json:"value,omitempty"`
+	Array  []TypedElement `json:"array,omitempty"`
+	Fields []typedField   `json:"fields,omitempty"`
+}
+
+// TypedElement uses the typed JSON representation, it round-trips without losing value types.
+//
+//	{"value":{"type":"u8","value":3}}
+//	{"array":[...]}
+//	{"fields":[{"key":{"type":"string","value":"a"},"value":{...}}]}
+//
+// u64 and u128 are decimal strings, hash and blob are hex. Fields are in canonical order.
+type TypedElement struct {
+	Element
+}
+
+func (d Element) MarshalTypedJSON() ([]byte, error) {
+	return json.Marshal(TypedElement{Element: d})
+}
+
+func UnmarshalTypedJSON(b []byte) (element Element, err error) {
+	var typed TypedElement
+	err = json.Unmarshal(b, &typed)
+	element = typed.Element
+	return
+}
+
+func toTypedValue(value Value) (typed typedValue, err error) {
+	valueType, ok := ValueTypeOf(value)
+	if !ok {
+		err = ErrUnsupportedValue(value)
+		return
+	}
+
+	typed.Type = valueTypeJSONNames[valueType]
+
+	var raw interface{}
+	switch v := value.(type) {
+	case uint64:
+		raw = strconv.FormatUint(v, 10)
+	case big.Int:
+		raw = v.String()
+	case Hash:
+		raw = hex.EncodeToString(v[:])
+	case Blob:
+		raw = hex.EncodeToString(v)
+	default:
+		raw = v
+	}
+
+	typed.Value, err = json.Marshal(raw)
+	return
+}
+
+func fromTypedValue(typed typedValue) (value Value, err error) {
+	switch typed.Type {
+	case "bool":
+		var v bool
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case "string":
+		var v string
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case "u8":
+		var v uint8
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case "u16":
+		var v uint16
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case "u32":
+		var v uint32
+		err = json.Unmarshal(typed.Value, &v)
+		value = v
+	case "u64", "u128":
+		var s string
+		err = json.Unmarshal(typed.Value, &s)
+		if err != nil {
+			return
+		}
+
+		value, err = parseNumber(s)
+		if err != nil {
+			return
+		}
+
+		if _, isU64 := value.(uint64); typed.Type == "u128" && isU64 {
+			value = *new(big.Int).SetUint64(value.(uint64))
+		} else if typed.Type == "u64" && !isU64 {
+			err = ErrOverflow
+		}
+	case "hash", "blob":
+		var s string
+		err = json.Unmarshal(typed.Value, &s)
+		if err != nil {
+			return
+		}
+
+		var b []byte
+		b, err = hex.DecodeString(s)
+		if err != nil {
+			return
+		}
+
+		if typed.Type == "blob" {
+			value = Blob(b)
+			return
+		}
+
+		if len(b) != 32 {
+			err = fmt.Errorf("invalid hash length %d", len(b))
+			return
+		}
+
+		var hash Hash
+		copy(hash[:], b)
+		value = hash
+	default:
+		err = fmt.Errorf("unknown value type %s", typed.Type)
+	}
+
+	return
+}
+
+func (t TypedElement) MarshalJSON() ([]byte, error) {
+	eType, err := t.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var typed typedElement
+	switch eType {
+	case ElementValueType:
+		if t.Value == nil {
+			return []byte("{}"), nil
+		}
+
+		var value typedValue
+		value, err = toTypedValue(t.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		typed.Value = &value
+	case ElementArrayType:
+		typed.Array = make([]TypedElement, 0, len(t.Array))
+		for _, item := range t.Array {
+			typed.Array = append(typed.Array, TypedElement{Element: item})
+		}
+
+		if len(typed.Array) == 0 {
+			return []byte(`{"array":[]}`), nil
+		}
+	case ElementFieldsType:
+		var keys []Value
+		keys, err = SortedKeys(t.Fields)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			var typedKey typedValue
+			typedKey, err = toTypedValue(key)
+			if err != nil {
+				return nil, err
+			}
+
+			typed.Fields = append(typed.Fields, typedField{Key: typedKey, Value: TypedElement{Element: t.Fields[key]}})
+		}
+
+		if len(typed.Fields) == 0 {
+			return []byte(`{"fields":[]}`), nil
+		}
+	}
+
+	return json.Marshal(typed)
+}
+
+func (t *TypedElement) UnmarshalJSON(b []byte) (err error) {
+	var typed typedElement
+	err = json.Unmarshal(b, &typed)
+	if err != nil {
+		return
+	}
+
+	// an empty array or fields is omitted by the struct, check the raw keys
+	var keys map[string]json.RawMessage
+	err = json.Unmarshal(b, &keys)
+	if err != nil {
+		return
+	}
+
+	if len(keys) > 1 {
+		return fmt.Errorf("only one of value, array or fields must be set")
+	}
+
+	t.Element = Element{}
+	switch {
+	case typed.Value != nil:
+		t.Value, err = fromTypedValue(*typed.Value)
+	case keys["array"] != nil:
+		t.Array = make([]Element, 0, len(typed.Array))
+		for _, item := range typed.Array {
+			t.Array = append(t.Array, item.Element)
+		}
+	case keys["fields"] != nil:
+		t.Fields = make(map[Value]Element, len(typed.Fields))
+		for _, field := range typed.Fields {
+			var key Value
+			key, err = fromTypedValue(field.Key)
+			if err != nil {
+				return
+			}
+
+			// not comparable so they can't be map keys
+			switch key.(type) {
+			case big.Int, Blob:
+				return ErrInvalidKey
+			}
+
+			if _, ok := t.Fields[key]; ok {
+				return ErrDuplicateKey
+			}
+
+			t.Fields[key] = field.Value.Element
+		}
+	}
+
+	return
+}
+
+// SortedKeys returns the keys of fields in canonical order, sorted by their encoded bytes
+func SortedKeys(fields map[Value]Element) (keys []Value, err error) {
+	type encodedKey struct {
+		key     Value
+		encoded []byte
+	}
+
+	encodedKeys := make([]encodedKey, 0, len(fields))
+	for key := range fields {
+		var buf bytes.Buffer
+		writer := ValueWriter{Writer: &buf}
+		err = writer.writeValue(key)
+		if err != nil {
+			return
+		}
+
+		encodedKeys = append(encodedKeys, encodedKey{key: key, encoded: buf.Bytes()})
+	}
+
+	sort.Slice(encodedKeys, func(i, j int) bool {
+		return bytes.Compare(encodedKeys[i].encoded, encodedKeys[j].encoded) < 0
+	})
+
+	keys = make([]Value, 0, len(encodedKeys))
+	for _, key := range encodedKeys {
+		keys = append(keys, key.key)
+	}
+
+	return
+}
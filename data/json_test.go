@@ -0,0 +1,125 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestElementUnmarshalJSON(t *testing.T) {
+	var element Element
+	err := json.Unmarshal([]byte(`{"amount":18446744073709551616,"memo":"hi","ids":[1,2],"paid":true}`), &element)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amount := element.Fields["amount"].Value.(big.Int)
+	if amount.String() != "18446744073709551616" {
+		t.Fatalf("unexpected amount %s", amount.String())
+	}
+
+	if element.Fields["ids"].Array[1].Value != uint64(2) || element.Fields["paid"].Value != true {
+		t.Fatalf("unexpected element %+v", element)
+	}
+
+	// plain JSON round-trips through MarshalJSON except u128 written as strings
+	delete(element.Fields, "amount")
+	b, err := json.Marshal(element)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var other Element
+	err = json.Unmarshal(b, &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(element, other) {
+		t.Fatalf("expected %+v, got %+v", element, other)
+	}
+
+	err = json.Unmarshal([]byte(`-1`), &other)
+	if err == nil {
+		t.Fatal("expected negative number error")
+	}
+}
+
+func TestElementTypedJSON(t *testing.T) {
+	var bigNumber big.Int
+	bigNumber.SetString("2093458230498572039452039485702938475", 10)
+
+	element := Element{Fields: map[Value]Element{
+		uint8(1):          {Value: uint16(2)},
+		"hash":            {Value: Hash{1}},
+		Hash{2}:           {Value: Blob{1, 2, 3}},
+		"big":             {Value: bigNumber},
+		"u64":             {Value: uint64(18446744073709551615)},
+		"empty":           {Array: []Element{}},
+		"nested":          {Array: []Element{{Value: true}, {Fields: map[Value]Element{}}}},
+		uint32(123456789): {Value: "u32"},
+	}}
+
+	b, err := element.MarshalTypedJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := UnmarshalTypedJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(element, decoded) {
+		t.Fatalf("expected %+v, got %+v", element, decoded)
+	}
+
+	t.Log(string(b))
+}
+
+func TestElementCanonicalBytes(t *testing.T) {
+	fields := make(map[Value]Element)
+	for i := 0; i < 50; i++ {
+		fields[uint8(i)] = Element{Value: uint8(i)}
+		fields[string(rune('a'+i%26))+string(rune('a'+i/26))] = Element{Value: true}
+	}
+
+	element := Element{Fields: fields}
+	expected, err := element.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		b, err := element.ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(expected, b) {
+			t.Fatal("element bytes are not deterministic")
+		}
+	}
+}
+
+func TestElementTypedJSONInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		err  error
+	}{
+		{"blob key", `{"fields":[{"key":{"type":"blob","value":"01"},"value":{"value":{"type":"u8","value":1}}}]}`, ErrInvalidKey},
+		{"u128 key", `{"fields":[{"key":{"type":"u128","value":"5"},"value":{"value":{"type":"u8","value":1}}}]}`, ErrInvalidKey},
+		{"duplicate key", `{"fields":[{"key":{"type":"string","value":"a"},"value":{"value":{"type":"u8","value":1}}},{"key":{"type":"string","value":"a"},"value":{"value":{"type":"u8","value":2}}}]}`, ErrDuplicateKey},
+	}
+
+	for _, test := range tests {
+		_, err := UnmarshalTypedJSON([]byte(test.json))
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%s: expected %s, got %v", test.name, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,699 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// MaxElements is the max number of items in an array or fields element, the size is written as a byte
+var MaxElements = 255
+
+var ErrMaxElements = errors.New("array and fields max size is 255 elements")
+var ErrNilValue = errors.New("nil values can't be encoded, use omitempty")
+var ErrNegativeNumber = errors.New("negative numbers can't be encoded")
+var ErrOverflow = errors.New("number overflows the value type")
+var ErrInvalidTarget = errors.New("unmarshal target must be a non-nil pointer")
+
+// Marshaler is implemented by types encoding themselves
+type Marshaler interface {
+	MarshalData() (Element, error)
+}
+
+// Unmarshaler is implemented by types decoding themselves
+type Unmarshaler interface {
+	UnmarshalData(element Element) error
+}
+
+// CodingError reports which field could not be encoded or decoded
+type CodingError struct {
+	Path string
+	Err  error
+}
+
+func (e *CodingError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("data: %s", e.Err)
+	}
+
+	return fmt.Sprintf("data: %s: %s", e.Path, e.Err)
+}
+
+func (e *CodingError) Unwrap() error {
+	return e.Err
+}
+
+func codingError(path string, err error) error {
+	var codingErr *CodingError
+	if errors.As(err, &codingErr) {
+		return err
+	}
+
+	return &CodingError{Path: path, Err: err}
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+var elementReflectType = reflect.TypeOf(Element{})
+var hashReflectType = reflect.TypeOf(Hash{})
+var bigIntReflectType = reflect.TypeOf(big.Int{})
+var marshalerReflectType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+var unmarshalerReflectType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+var valueTypeNames = map[string]ValueType{
+	"bool":   BoolType,
+	"string": StringType,
+	"u8":     U8Type,
+	"u16":    U16Type,
+	"u32":    U32Type,
+	"u64":    U64Type,
+	"u128":   U128Type,
+	"hash":   HashType,
+	"blob":   BlobType,
+}
+
+type fieldTag struct {
+	name      string
+	valueType *ValueType
+	omitEmpty bool
+	skip      bool
+}
+
+// parse `data:"name,u16,omitempty"`
+func parseTag(field reflect.StructField) (tag fieldTag, err error) {
+	tag.name = field.Name
+	value, ok := field.Tag.Lookup("data")
+	if !ok {
+		return
+	}
+
+	if value == "-" {
+		tag.skip = true
+		return
+	}
+
+	parts := strings.Split(value, ",")
+	if parts[0] != "" {
+		tag.name = parts[0]
+	}
+
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			tag.omitEmpty = true
+			continue
+		}
+
+		valueType, ok := valueTypeNames[option]
+		if !ok {
+			err = fmt.Errorf("unknown tag option %s", option)
+			return
+		}
+
+		tag.valueType = &valueType
+	}
+
+	return
+}
+
+type structField struct {
+	index []int
+	tag   fieldTag
+}
+
+// exported fields with embedded structs flattened like encoding/json
+func structFields(t reflect.Type) (fields []structField, err error) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		_, named := field.Tag.Lookup("data")
+		embedded := field.Anonymous && !named && field.Type.Kind() == reflect.Struct
+		if !field.IsExported() && !embedded {
+			continue
+		}
+
+		var tag fieldTag
+		tag, err = parseTag(field)
+		if err != nil {
+			return
+		}
+
+		if tag.skip {
+			continue
+		}
+
+		if embedded {
+			var embeddedFields []structField
+			embeddedFields, err = structFields(field.Type)
+			if err != nil {
+				return
+			}
+
+			for _, e := range embeddedFields {
+				e.index = append([]int{i}, e.index...)
+				fields = append(fields, e)
+			}
+
+			continue
+		}
+
+		fields = append(fields, structField{index: []int{i}, tag: tag})
+	}
+
+	return
+}
+
+// Marshal encodes bools, strings, unsigned integers, big.Int (u128), Hash, Blob or []byte,
+// slices, arrays, maps and structs into an Element.
+// Struct fields are named by the `data` tag which can also force the value type: `data:"amount,u16"`.
+func Marshal(v interface{}) (element Element, err error) {
+	return marshal(reflect.ValueOf(v), nil, "")
+}
+
+func marshal(v reflect.Value, valueType *ValueType, path string) (element Element, err error) {
+	if !v.IsValid() {
+		err = codingError(path, ErrNilValue)
+		return
+	}
+
+	if v.Type() == elementReflectType {
+		element = v.Interface().(Element)
+		return
+	}
+
+	if v.Type().Implements(marshalerReflectType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			err = codingError(path, ErrNilValue)
+			return
+		}
+
+		element, err = v.Interface().(Marshaler).MarshalData()
+		if err != nil {
+			err = codingError(path, err)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			err = codingError(path, ErrNilValue)
+			return
+		}
+
+		return marshal(v.Elem(), valueType, path)
+	}
+
+	value, isValue, err := marshalValue(v, valueType)
+	if err != nil {
+		err = codingError(path, err)
+		return
+	}
+
+	if isValue {
+		element.Value = value
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Len() > MaxElements {
+			err = codingError(path, ErrMaxElements)
+			return
+		}
+
+		element.Array = make([]Element, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			var item Element
+			item, err = marshal(v.Index(i), valueType, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return
+			}
+
+			element.Array = append(element.Array, item)
+		}
+	case reflect.Map:
+		if v.Len() > MaxElements {
+			err = codingError(path, ErrMaxElements)
+			return
+		}
+
+		element.Fields = make(map[Value]Element, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			keyPath := joinPath(path, fmt.Sprintf("%v", iter.Key()))
+
+			var key Element
+			key, err = marshal(iter.Key(), nil, keyPath)
+			if err != nil {
+				return
+			}
+
+			if key.Value == nil {
+				err = codingError(keyPath, fmt.Errorf("map key must be a value"))
+				return
+			}
+
+			// not comparable so they can't be fields keys
+			switch key.Value.(type) {
+			case big.Int, Blob:
+				err = codingError(keyPath, ErrInvalidKey)
+				return
+			}
+
+			var item Element
+			item, err = marshal(iter.Value(), valueType, keyPath)
+			if err != nil {
+				return
+			}
+
+			element.Fields[key.Value] = item
+		}
+	case reflect.Struct:
+		var fields []structField
+		fields, err = structFields(v.Type())
+		if err != nil {
+			err = codingError(path, err)
+			return
+		}
+
+		element.Fields = make(map[Value]Element, len(fields))
+		for _, field := range fields {
+			fieldValue := v.FieldByIndex(field.index)
+			if field.tag.omitEmpty && fieldValue.IsZero() {
+				continue
+			}
+
+			var item Element
+			item, err = marshal(fieldValue, field.tag.valueType, joinPath(path, field.tag.name))
+			if err != nil {
+				return
+			}
+
+			element.Fields[field.tag.name] = item
+		}
+
+		if len(element.Fields) > MaxElements {
+			err = codingError(path, ErrMaxElements)
+			return
+		}
+	default:
+		err = codingError(path, fmt.Errorf("unsupported type %s", v.Type()))
+	}
+
+	return
+}
+
+func marshalValue(v reflect.Value, valueType *ValueType) (value Value, isValue bool, err error) {
+	isValue = true
+
+	switch {
+	case v.Type() == hashReflectType || (v.Kind() == reflect.Array && v.Len() == 32 && v.Type().Elem().Kind() == reflect.Uint8):
+		var hash Hash
+		reflect.Copy(reflect.ValueOf(&hash).Elem(), v)
+		value = hash
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.Len() > MaxBlobSize {
+			err = ErrMaxBlobSize
+			return
+		}
+
+		value = Blob(v.Bytes())
+	case v.Type() == bigIntReflectType:
+		number := v.Interface().(big.Int)
+		value, err = toNumberValue(&number, valueType)
+		return
+	default:
+		switch v.Kind() {
+		case reflect.Bool:
+			value = v.Bool()
+		case reflect.String:
+			if len(v.String()) > MaxStringSize {
+				err = ErrMaxStringSize
+				return
+			}
+
+			value = v.String()
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+			var defaultType ValueType
+			switch v.Kind() {
+			case reflect.Uint8:
+				defaultType = U8Type
+			case reflect.Uint16:
+				defaultType = U16Type
+			case reflect.Uint32:
+				defaultType = U32Type
+			default:
+				defaultType = U64Type
+			}
+
+			if valueType == nil {
+				valueType = &defaultType
+			}
+
+			value, err = toNumberValue(new(big.Int).SetUint64(v.Uint()), valueType)
+			return
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			value, err = toNumberValue(big.NewInt(v.Int()), valueType)
+			return
+		default:
+			isValue = false
+			return
+		}
+	}
+
+	if valueType != nil {
+		actual, _ := ValueTypeOf(value)
+		if actual != *valueType {
+			err = fmt.Errorf("can't encode %s as value type %d", v.Type(), *valueType)
+		}
+	}
+
+	return
+}
+
+// numbers without an explicit value type are encoded as u64, or u128 for big.Int
+func toNumberValue(number *big.Int, valueType *ValueType) (value Value, err error) {
+	if number.Sign() < 0 {
+		err = ErrNegativeNumber
+		return
+	}
+
+	target := U64Type
+	if valueType != nil {
+		target = *valueType
+	} else if !number.IsUint64() {
+		target = U128Type
+	}
+
+	var max uint64
+	switch target {
+	case U8Type:
+		max = math.MaxUint8
+	case U16Type:
+		max = math.MaxUint16
+	case U32Type:
+		max = math.MaxUint32
+	case U64Type:
+		max = math.MaxUint64
+	case U128Type:
+		if number.BitLen() > 128 {
+			err = ErrOverflow
+			return
+		}
+
+		value = *new(big.Int).Set(number)
+		return
+	default:
+		err = fmt.Errorf("can't encode a number as value type %d", target)
+		return
+	}
+
+	if !number.IsUint64() || number.Uint64() > max {
+		err = ErrOverflow
+		return
+	}
+
+	n := number.Uint64()
+	switch target {
+	case U8Type:
+		value = uint8(n)
+	case U16Type:
+		value = uint16(n)
+	case U32Type:
+		value = uint32(n)
+	default:
+		value = n
+	}
+
+	return
+}
+
+// ValueTypeOf returns the type of a value supported by the writer
+func ValueTypeOf(value Value) (valueType ValueType, ok bool) {
+	ok = true
+	switch value.(type) {
+	case bool:
+		valueType = BoolType
+	case string:
+		valueType = StringType
+	case uint8:
+		valueType = U8Type
+	case uint16:
+		valueType = U16Type
+	case uint32:
+		valueType = U32Type
+	case uint64:
+		valueType = U64Type
+	case big.Int:
+		valueType = U128Type
+	case Hash:
+		valueType = HashType
+	case Blob:
+		valueType = BlobType
+	default:
+		ok = false
+	}
+
+	return
+}
+
+// Unmarshal decodes an element into the value pointed by v, see Marshal for the supported types.
+// Unknown struct fields are ignored and missing ones are left untouched.
+func Unmarshal(element Element, v interface{}) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &CodingError{Err: ErrInvalidTarget}
+	}
+
+	return unmarshal(element, rv.Elem(), nil, "")
+}
+
+func unmarshal(element Element, v reflect.Value, valueType *ValueType, path string) (err error) {
+	if v.Type() == elementReflectType {
+		v.Set(reflect.ValueOf(element))
+		return
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerReflectType) {
+		err = v.Addr().Interface().(Unmarshaler).UnmarshalData(element)
+		if err != nil {
+			err = codingError(path, err)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return unmarshal(element, v.Elem(), valueType, path)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return codingError(path, fmt.Errorf("unsupported type %s", v.Type()))
+		}
+
+		// values are set as is, arrays and fields as an Element
+		if element.Value != nil {
+			v.Set(reflect.ValueOf(element.Value))
+		} else {
+			v.Set(reflect.ValueOf(element))
+		}
+		return
+	}
+
+	if element.Value != nil {
+		err = unmarshalValue(element.Value, v, valueType)
+		if err != nil {
+			err = codingError(path, err)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if element.Array == nil {
+			return codingError(path, fmt.Errorf("expected an array for %s", v.Type()))
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(element.Array), len(element.Array))
+		for i, item := range element.Array {
+			err = unmarshal(item, slice.Index(i), valueType, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return
+			}
+		}
+
+		v.Set(slice)
+	case reflect.Array:
+		if element.Array == nil || len(element.Array) != v.Len() {
+			return codingError(path, fmt.Errorf("expected an array of %d elements for %s", v.Len(), v.Type()))
+		}
+
+		for i, item := range element.Array {
+			err = unmarshal(item, v.Index(i), valueType, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return
+			}
+		}
+	case reflect.Map:
+		if element.Fields == nil {
+			return codingError(path, fmt.Errorf("expected fields for %s", v.Type()))
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(element.Fields)))
+		}
+
+		for key, item := range element.Fields {
+			keyPath := joinPath(path, fmt.Sprintf("%v", key))
+
+			k := reflect.New(v.Type().Key()).Elem()
+			err = unmarshal(Element{Value: key}, k, nil, keyPath)
+			if err != nil {
+				return
+			}
+
+			value := reflect.New(v.Type().Elem()).Elem()
+			err = unmarshal(item, value, valueType, keyPath)
+			if err != nil {
+				return
+			}
+
+			v.SetMapIndex(k, value)
+		}
+	case reflect.Struct:
+		if element.Fields == nil {
+			return codingError(path, fmt.Errorf("expected fields for %s", v.Type()))
+		}
+
+		var fields []structField
+		fields, err = structFields(v.Type())
+		if err != nil {
+			return codingError(path, err)
+		}
+
+		for _, field := range fields {
+			item, ok := element.Fields[field.tag.name]
+			if !ok {
+				continue
+			}
+
+			err = unmarshal(item, v.FieldByIndex(field.index), field.tag.valueType, joinPath(path, field.tag.name))
+			if err != nil {
+				return
+			}
+		}
+	default:
+		err = codingError(path, fmt.Errorf("can't decode %s from an array or fields", v.Type()))
+	}
+
+	return
+}
+
+func unmarshalValue(value Value, v reflect.Value, valueType *ValueType) (err error) {
+	actual, ok := ValueTypeOf(value)
+	if !ok {
+		return ErrUnsupportedValue(value)
+	}
+
+	if valueType != nil && actual != *valueType {
+		return fmt.Errorf("expected value type %d, got %d", *valueType, actual)
+	}
+
+	mismatch := fmt.Errorf("can't decode value type %d into %s", actual, v.Type())
+
+	switch {
+	case v.Type() == hashReflectType || (v.Kind() == reflect.Array && v.Len() == 32 && v.Type().Elem().Kind() == reflect.Uint8):
+		hash, ok := value.(Hash)
+		if !ok {
+			return mismatch
+		}
+
+		reflect.Copy(v, reflect.ValueOf(hash[:]))
+		return
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		blob, ok := value.(Blob)
+		if !ok {
+			return mismatch
+		}
+
+		b := reflect.MakeSlice(v.Type(), len(blob), len(blob))
+		reflect.Copy(b, reflect.ValueOf([]byte(blob)))
+		v.Set(b)
+		return
+	}
+
+	var number *big.Int
+	switch n := value.(type) {
+	case uint8:
+		number = new(big.Int).SetUint64(uint64(n))
+	case uint16:
+		number = new(big.Int).SetUint64(uint64(n))
+	case uint32:
+		number = new(big.Int).SetUint64(uint64(n))
+	case uint64:
+		number = new(big.Int).SetUint64(n)
+	case big.Int:
+		number = new(big.Int).Set(&n)
+	}
+
+	if v.Type() == bigIntReflectType {
+		if number == nil {
+			return mismatch
+		}
+
+		v.Set(reflect.ValueOf(*number))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch
+		}
+
+		v.SetBool(b)
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch
+		}
+
+		v.SetString(s)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		if number == nil {
+			return mismatch
+		}
+
+		if !number.IsUint64() || v.OverflowUint(number.Uint64()) {
+			return ErrOverflow
+		}
+
+		v.SetUint(number.Uint64())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		if number == nil {
+			return mismatch
+		}
+
+		if !number.IsInt64() || v.OverflowInt(number.Int64()) {
+			return ErrOverflow
+		}
+
+		v.SetInt(number.Int64())
+	default:
+		return mismatch
+	}
+
+	return
+}
@@ -0,0 +1,162 @@
+package data
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type marshalBase struct {
+	ID uint64 `data:"id"`
+}
+
+type marshalItem struct {
+	Name  string `data:"name"`
+	Count uint64 `data:"count,u16"`
+}
+
+type marshalStruct struct {
+	marshalBase
+	Title   string            `data:"title"`
+	Paid    bool              `data:"paid"`
+	Small   uint8             `data:"small"`
+	Total   *big.Int          `data:"total,u128"`
+	Tx      Hash              `data:"tx"`
+	Payload []byte            `data:"payload"`
+	Items   []marshalItem     `data:"items"`
+	Labels  map[string]uint32 `data:"labels"`
+	Note    *string           `data:"note,omitempty"`
+	Ignored string            `data:"-"`
+	Raw     Element           `data:"raw"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	total, _ := new(big.Int).SetString("340282366920938463463374607431768211455", 10)
+	v := marshalStruct{
+		marshalBase: marshalBase{ID: 7},
+		Title:       "invoice",
+		Paid:        true,
+		Small:       3,
+		Total:       total,
+		Tx:          Hash{1, 2, 3},
+		Payload:     []byte{9, 8, 7},
+		Items:       []marshalItem{{Name: "a", Count: 2}, {Name: "b", Count: 65535}},
+		Labels:      map[string]uint32{"x": 1},
+		Ignored:     "ignored",
+		Raw:         Element{Array: []Element{{Value: "raw"}}},
+	}
+
+	element, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := element.Fields["note"]; ok {
+		t.Fatal("expected omitted note")
+	}
+
+	if _, ok := element.Fields["Ignored"]; ok {
+		t.Fatal("expected ignored field")
+	}
+
+	if element.Fields["id"].Value != uint64(7) || element.Fields["items"].Array[1].Fields["count"].Value != uint16(65535) {
+		t.Fatalf("unexpected element %+v", element)
+	}
+
+	var decoded marshalStruct
+	err = Unmarshal(element, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v.Ignored = ""
+	if !reflect.DeepEqual(v, decoded) {
+		t.Fatalf("expected %+v, got %+v", v, decoded)
+	}
+}
+
+func TestMarshalBytes(t *testing.T) {
+	v := marshalItem{Name: "coffee", Count: 3}
+	element, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := element.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	element, err = ElementFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded marshalItem
+	err = Unmarshal(element, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != v {
+		t.Fatalf("expected %+v, got %+v", v, decoded)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := Marshal(marshalItem{Name: strings.Repeat("a", MaxStringSize+1)})
+	if !errors.Is(err, ErrMaxStringSize) || !strings.Contains(err.Error(), "name") {
+		t.Fatalf("expected %s on field name, got %v", ErrMaxStringSize, err)
+	}
+
+	_, err = Marshal(marshalItem{Count: 65536})
+	if !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected %s, got %v", ErrOverflow, err)
+	}
+
+	_, err = Marshal(make([]byte, MaxBlobSize+1))
+	if !errors.Is(err, ErrMaxBlobSize) {
+		t.Fatalf("expected %s, got %v", ErrMaxBlobSize, err)
+	}
+
+	_, err = Marshal(make([]string, MaxElements+1))
+	if !errors.Is(err, ErrMaxElements) {
+		t.Fatalf("expected %s, got %v", ErrMaxElements, err)
+	}
+
+	// pointers are valid go map keys but u128 and blob values are not fields keys
+	u128 := new(big.Int).Lsh(big.NewInt(1), 64)
+	_, err = Marshal(map[*big.Int]string{u128: "a"})
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected %s, got %v", ErrInvalidKey, err)
+	}
+
+	_, err = Marshal(map[*[]byte]string{{1}: "a"})
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected %s, got %v", ErrInvalidKey, err)
+	}
+
+	_, err = Marshal(-1)
+	if !errors.Is(err, ErrNegativeNumber) {
+		t.Fatalf("expected %s, got %v", ErrNegativeNumber, err)
+	}
+
+	var small uint8
+	err = Unmarshal(Element{Value: uint64(256)}, &small)
+	if !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected %s, got %v", ErrOverflow, err)
+	}
+
+	var item marshalItem
+	err = Unmarshal(Element{Fields: map[Value]Element{"count": {Value: uint64(1)}}}, &item)
+	if err == nil || !strings.Contains(err.Error(), "count") {
+		t.Fatalf("expected value type error on count, got %v", err)
+	}
+
+	err = Unmarshal(Element{Value: "a"}, item)
+	if !errors.Is(err, ErrInvalidTarget) {
+		t.Fatalf("expected %s, got %v", ErrInvalidTarget, err)
+	}
+}
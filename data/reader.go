@@ -1,18 +1,173 @@
 package data
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"unicode/utf8"
 )
 
+// DefaultMaxDepth is the max nesting of arrays and fields when ValueReader.MaxDepth is not set
+var DefaultMaxDepth = 32
+
+var ErrMaxDepth = errors.New("max depth reached")
+var ErrMaxSize = errors.New("max size reached")
+var ErrShortRead = errors.New("unexpected end of data")
+var ErrInvalidBool = errors.New("invalid bool value")
+var ErrInvalidString = errors.New("string is not valid utf-8")
+var ErrInvalidElementType = errors.New("invalid element type")
+var ErrInvalidValueType = errors.New("invalid value type")
+var ErrInvalidKey = errors.New("u128 and blob values can't be used as keys")
+var ErrDuplicateKey = errors.New("duplicate key in fields")
+var ErrUnsortedKeys = errors.New("fields keys are not in canonical order")
+var ErrTrailingBytes = errors.New("trailing bytes after element")
+
+// ReadError is returned by ValueReader with the offset of the failing byte in the reader
+type ReadError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("data: offset %d: %s", e.Offset, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// ValueReader decodes elements from untrusted bytes.
+// With Reader set, lengths are checked against the remaining data before allocating,
+// streams use NewValueReader and allocations are bounded by the max string and blob sizes.
 type ValueReader struct {
 	Reader *bytes.Reader
+	// Max nesting of arrays and fields, DefaultMaxDepth if 0
+	MaxDepth int
+	// Max bytes read by a single Read call, no limit if 0
+	MaxSize int
+	// Reject fields whose keys are not strictly increasing in canonical order, as written by ToBytes.
+	// Off by default as wallets write fields in insertion order.
+	Canonical bool
+
+	stream       *bufio.Reader
+	streamOffset int64
+	depth        int
+	start        int64
+}
+
+// NewValueReader reads elements from any reader, other readers than *bytes.Reader are buffered
+// so the same ValueReader must be used to read the following elements
+func NewValueReader(r io.Reader) *ValueReader {
+	if reader, ok := r.(*bytes.Reader); ok {
+		return &ValueReader{Reader: reader}
+	}
+
+	return &ValueReader{stream: bufio.NewReader(r)}
+}
+
+func (d *ValueReader) source() byteReader {
+	if d.stream != nil {
+		return d.stream
+	}
+
+	return d.Reader
+}
+
+func (d *ValueReader) offset() int64 {
+	if d.stream != nil {
+		return d.streamOffset
+	}
+
+	return d.Reader.Size() - int64(d.Reader.Len())
+}
+
+// More reports if there is data left to read another element
+func (d *ValueReader) More() (bool, error) {
+	if d.stream == nil {
+		return d.Reader.Len() > 0, nil
+	}
+
+	_, err := d.stream.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ReadAll reads concatenated elements until the end of the data
+func (d *ValueReader) ReadAll() (elements []Element, err error) {
+	for {
+		var more bool
+		more, err = d.More()
+		if err != nil || !more {
+			return
+		}
+
+		var element Element
+		element, err = d.Read()
+		if err != nil {
+			return
+		}
+
+		elements = append(elements, element)
+	}
+}
+
+func (d *ValueReader) fail(err error) error {
+	var readErr *ReadError
+	if errors.As(err, &readErr) {
+		return err
+	}
+
+	if errors.Is(err, io.EOF) {
+		err = ErrShortRead
+	}
+
+	return &ReadError{Offset: d.offset(), Err: err}
+}
+
+// reserve checks size bytes can be read before allocating them
+func (d *ValueReader) reserve(size int) (err error) {
+	if d.stream == nil && size > d.Reader.Len() {
+		return ErrShortRead
+	}
+
+	if d.MaxSize > 0 && d.offset()-d.start+int64(size) > int64(d.MaxSize) {
+		return ErrMaxSize
+	}
+
+	return
 }
 
 func (d *ValueReader) Read() (dataElement Element, err error) {
-	dataElementType, err := d.Reader.ReadByte()
+	if d.depth == 0 {
+		d.start = d.offset()
+	}
+
+	maxDepth := d.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	if d.depth >= maxDepth {
+		err = d.fail(ErrMaxDepth)
+		return
+	}
+
+	d.depth++
+	defer func() { d.depth-- }()
+
+	dataElementType, err := d.readU8()
 	if err != nil {
 		return
 	}
@@ -28,12 +183,12 @@ func (d *ValueReader) Read() (dataElement Element, err error) {
 		dataElement = Element{Value: value}
 	case byte(ElementArrayType): // Array
 		var size byte
-		size, err = d.Reader.ReadByte()
+		size, err = d.readU8()
 		if err != nil {
 			return
 		}
 
-		var values []Element
+		values := make([]Element, 0, size)
 		for i := 0; i < int(size); i++ {
 			var value Element
 			value, err = d.Read()
@@ -47,19 +202,51 @@ func (d *ValueReader) Read() (dataElement Element, err error) {
 		dataElement = Element{Array: values}
 	case byte(ElementFieldsType): // Fields / Map
 		var size byte
-		size, err = d.Reader.ReadByte()
+		size, err = d.readU8()
 		if err != nil {
 			return
 		}
 
-		fields := make(map[Value]Element, 0)
+		fields := make(map[Value]Element, size)
+		var previousKey []byte
 		for i := 0; i < int(size); i++ {
+			offset := d.offset()
+
 			var key Value
 			key, err = d.readValue()
 			if err != nil {
 				return
 			}
 
+			// not comparable so they can't be map keys
+			switch key.(type) {
+			case big.Int, Blob:
+				err = &ReadError{Offset: offset, Err: ErrInvalidKey}
+				return
+			}
+
+			if _, ok := fields[key]; ok {
+				err = &ReadError{Offset: offset, Err: ErrDuplicateKey}
+				return
+			}
+
+			if d.Canonical {
+				// keys sorted by their encoded bytes, see SortedKeys
+				var encodedKey bytes.Buffer
+				writer := ValueWriter{Writer: &encodedKey}
+				err = writer.writeValue(key)
+				if err != nil {
+					return
+				}
+
+				if previousKey != nil && bytes.Compare(encodedKey.Bytes(), previousKey) <= 0 {
+					err = &ReadError{Offset: offset, Err: ErrUnsortedKeys}
+					return
+				}
+
+				previousKey = encodedKey.Bytes()
+			}
+
 			var value Element
 			value, err = d.Read()
 			if err != nil {
@@ -71,7 +258,7 @@ func (d *ValueReader) Read() (dataElement Element, err error) {
 
 		dataElement = Element{Fields: fields}
 	default:
-		err = fmt.Errorf("invalid data type")
+		err = &ReadError{Offset: d.offset() - 1, Err: ErrInvalidElementType}
 		return
 	}
 
@@ -79,7 +266,7 @@ func (d *ValueReader) Read() (dataElement Element, err error) {
 }
 
 func (d *ValueReader) readValueType() (valueType ValueType, err error) {
-	data, err := d.Reader.ReadByte()
+	data, err := d.readU8()
 	if err != nil {
 		return
 	}
@@ -89,7 +276,7 @@ func (d *ValueReader) readValueType() (valueType ValueType, err error) {
 }
 
 func (d *ValueReader) readBool() (value bool, err error) {
-	data, err := d.Reader.ReadByte()
+	data, err := d.readU8()
 	if err != nil {
 		return
 	}
@@ -99,31 +286,50 @@ func (d *ValueReader) readBool() (value bool, err error) {
 		value = false
 	case 1:
 		value = true
+	default:
+		err = &ReadError{Offset: d.offset() - 1, Err: ErrInvalidBool}
 	}
 
 	return
 }
 
 func (d *ValueReader) readString() (value string, err error) {
-	size, err := d.Reader.ReadByte()
+	size, err := d.readU8()
 	if err != nil {
 		return
 	}
 
-	data := make([]byte, size)
-	_, err = d.Reader.Read(data)
+	offset := d.offset()
+	data, err := d.readBytes(int(size))
 	if err != nil {
 		return
 	}
 
+	if !utf8.Valid(data) {
+		err = &ReadError{Offset: offset, Err: ErrInvalidString}
+		return
+	}
+
 	value = string(data)
 	return
 }
 
 func (d *ValueReader) readBytes(size int) (value []byte, err error) {
+	err = d.reserve(size)
+	if err != nil {
+		err = d.fail(err)
+		return
+	}
+
 	data := make([]byte, size)
-	_, err = d.Reader.Read(data)
+	n, err := io.ReadFull(d.source(), data)
+	d.streamOffset += int64(n)
 	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = ErrShortRead
+		}
+
+		err = d.fail(err)
 		return
 	}
 
@@ -132,12 +338,20 @@ func (d *ValueReader) readBytes(size int) (value []byte, err error) {
 }
 
 func (d *ValueReader) readU8() (value uint8, err error) {
-	data, err := d.Reader.ReadByte()
+	err = d.reserve(1)
+	if err != nil {
+		err = d.fail(err)
+		return
+	}
+
+	value, err = d.source().ReadByte()
 	if err != nil {
+		err = d.fail(err)
 		return
 	}
 
-	value = uint8(data)
+	d.streamOffset++
+
 	return
 }
 
@@ -182,12 +396,16 @@ func (d *ValueReader) readU128() (value big.Int, err error) {
 }
 
 func (d *ValueReader) readBlob() (value Blob, err error) {
-	data, err := d.readBytes(4)
+	offset := d.offset()
+	size, err := d.readU32()
 	if err != nil {
 		return
 	}
 
-	size := binary.BigEndian.Uint32(data)
+	if size > uint32(MaxBlobSize) {
+		err = &ReadError{Offset: offset, Err: ErrMaxBlobSize}
+		return
+	}
 
 	value, err = d.readBytes(int(size))
 	if err != nil {
@@ -232,6 +450,8 @@ func (d *ValueReader) readValue() (value Value, err error) {
 		value, err = d.readHash()
 	case BlobType:
 		value, err = d.readBlob()
+	default:
+		err = &ReadError{Offset: d.offset() - 1, Err: ErrInvalidValueType}
 	}
 
 	return
@@ -0,0 +1,140 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		err    error
+		offset int64
+	}{
+		{"empty", []byte{}, ErrShortRead, 0},
+		{"short string", []byte{0, 1, 10, 'a', 'b'}, ErrShortRead, 3},
+		{"short u64", []byte{0, 5, 1, 2}, ErrShortRead, 2},
+		{"huge blob", []byte{0, 8, 0xff, 0xff, 0xff, 0xff}, ErrMaxBlobSize, 2},
+		{"invalid bool", []byte{0, 0, 2}, ErrInvalidBool, 2},
+		{"invalid utf-8", []byte{0, 1, 2, 0xc3, 0x28}, ErrInvalidString, 3},
+		{"invalid element type", []byte{3}, ErrInvalidElementType, 0},
+		{"invalid value type", []byte{0, 9}, ErrInvalidValueType, 1},
+		{"duplicate key", []byte{2, 2, 2, 1, 0, 0, 1, 2, 1, 0, 0, 0}, ErrDuplicateKey, 7},
+		{"trailing bytes", []byte{0, 2, 1, 0}, ErrTrailingBytes, 3},
+	}
+
+	for _, test := range tests {
+		_, err := ElementFromBytes(test.data)
+
+		var readErr *ReadError
+		if !errors.Is(err, test.err) || !errors.As(err, &readErr) || readErr.Offset != test.offset {
+			t.Fatalf("%s: expected %s at offset %d, got %v", test.name, test.err, test.offset, err)
+		}
+	}
+}
+
+func TestReaderCanonical(t *testing.T) {
+	sorted, err := Element{Fields: map[Value]Element{uint8(1): {Value: false}, uint8(2): {Value: false}}}.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same fields with the keys 2 and 1 swapped
+	unsorted := []byte{2, 2, 2, 2, 0, 0, 0, 2, 1, 0, 0, 0}
+
+	reader := ValueReader{Reader: bytes.NewReader(sorted), Canonical: true}
+	_, err = reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader = ValueReader{Reader: bytes.NewReader(unsorted), Canonical: true}
+	_, err = reader.Read()
+
+	var readErr *ReadError
+	if !errors.Is(err, ErrUnsortedKeys) || !errors.As(err, &readErr) || readErr.Offset != 7 {
+		t.Fatalf("expected %s at offset 7, got %v", ErrUnsortedKeys, err)
+	}
+
+	// wallets write fields in insertion order
+	_, err = ElementFromBytes(unsorted)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReaderLimits(t *testing.T) {
+	// 40 nested arrays
+	var nested []byte
+	for i := 0; i < 40; i++ {
+		nested = append(nested, 1, 1)
+	}
+	nested = append(nested, 0, 0, 1)
+
+	_, err := ElementFromBytes(nested)
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Fatalf("expected %s, got %v", ErrMaxDepth, err)
+	}
+
+	reader := ValueReader{Reader: bytes.NewReader(nested), MaxDepth: 64}
+	_, err = reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Element{Value: Blob(make([]byte, 100))}.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader = ValueReader{Reader: bytes.NewReader(data), MaxSize: 50}
+	_, err = reader.Read()
+	if !errors.Is(err, ErrMaxSize) {
+		t.Fatalf("expected %s, got %v", ErrMaxSize, err)
+	}
+}
+
+func FuzzElementFromBytes(f *testing.F) {
+	seeds := []Element{
+		{Value: "hello"},
+		{Value: uint64(42)},
+		{Value: Hash{1, 2, 3}},
+		{Value: Blob{1, 2, 3}},
+		{Array: []Element{{Value: true}, {Value: uint16(7)}}},
+		{Fields: map[Value]Element{"a": {Value: uint32(1)}, uint8(2): {Array: []Element{}}}},
+	}
+
+	for _, seed := range seeds {
+		data, err := seed.ToBytes()
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		element, err := ElementFromBytes(data)
+		if err != nil {
+			return
+		}
+
+		// anything we can read must be written and read back to the same element
+		encoded, err := element.ToBytes()
+		if err != nil {
+			t.Fatalf("can't encode decoded element: %s", err)
+		}
+
+		decoded, err := ElementFromBytes(encoded)
+		if err != nil {
+			t.Fatalf("can't decode encoded element: %s", err)
+		}
+
+		if !reflect.DeepEqual(element, decoded) {
+			t.Fatalf("expected %+v, got %+v", element, decoded)
+		}
+	})
+}
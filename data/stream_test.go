@@ -0,0 +1,85 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStreamReadAll(t *testing.T) {
+	elements := []Element{
+		{Value: "first"},
+		{Array: []Element{{Value: uint64(1)}, {Value: Hash{1}}}},
+		{Fields: map[Value]Element{"blob": {Value: Blob{1, 2, 3}}}},
+	}
+
+	var buf bytes.Buffer
+	writer := NewValueWriter(&buf)
+	for _, element := range elements {
+		err := writer.Write(element)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if buf.Len() != 0 {
+		t.Fatal("expected buffered writes")
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one byte at a time to check short reads of the stream are completed
+	reader := NewValueReader(iotest.OneByteReader(bytes.NewReader(buf.Bytes())))
+	decoded, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(elements, decoded) {
+		t.Fatalf("expected %+v, got %+v", elements, decoded)
+	}
+
+	// truncated stream
+	reader = NewValueReader(iotest.OneByteReader(bytes.NewReader(buf.Bytes()[:buf.Len()-2])))
+	_, err = reader.ReadAll()
+
+	var readErr *ReadError
+	if !errors.Is(err, ErrShortRead) || !errors.As(err, &readErr) || readErr.Offset != int64(buf.Len()-2) {
+		t.Fatalf("expected %s at offset %d, got %v", ErrShortRead, buf.Len()-2, err)
+	}
+}
+
+func TestEncodedSize(t *testing.T) {
+	elements := []Element{
+		{Value: true},
+		{Value: "hello"},
+		{Value: Blob(make([]byte, 300))},
+		{Fields: map[Value]Element{uint16(1): {Array: []Element{{Value: Hash{}}}}}},
+	}
+
+	for _, element := range elements {
+		size, err := EncodedSize(element)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := element.ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if size != len(b) {
+			t.Fatalf("expected size %d, got %d", len(b), size)
+		}
+	}
+
+	_, err := EncodedSize(Element{Value: int8(1)})
+	if err == nil {
+		t.Fatal("expected unsupported value error")
+	}
+}
@@ -1,7 +1,7 @@
 package data
 
 import (
-	"bytes"
+	"bufio"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -20,6 +20,46 @@ var ErrMaxBlobSize = errors.New("blob max size is 65535 bytes")
 
 type ValueWriter struct {
 	Writer io.Writer
+
+	buffered *bufio.Writer
+	scratch  [16]byte
+}
+
+// NewValueWriter buffers the writes to w, Flush must be called after the last element
+func NewValueWriter(w io.Writer) *ValueWriter {
+	buffered := bufio.NewWriter(w)
+	return &ValueWriter{Writer: buffered, buffered: buffered}
+}
+
+// Flush writes the buffered data, no-op if the writer is not buffered
+func (d *ValueWriter) Flush() error {
+	if d.buffered == nil {
+		return nil
+	}
+
+	return d.buffered.Flush()
+}
+
+type countWriter struct {
+	size int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.size += len(p)
+	return len(p), nil
+}
+
+// EncodedSize returns the size of the element bytes without encoding it in memory
+func EncodedSize(element Element) (size int, err error) {
+	counter := &countWriter{}
+	writer := ValueWriter{Writer: counter}
+	err = writer.Write(element)
+	if err != nil {
+		return
+	}
+
+	size = counter.size
+	return
 }
 
 func (d *ValueWriter) Write(dataElement Element) (err error) {
@@ -45,6 +85,11 @@ func (d *ValueWriter) Write(dataElement Element) (err error) {
 			return
 		}
 
+		if len(dataElement.Array) > MaxElements {
+			err = ErrMaxElements
+			return
+		}
+
 		err = d.writeByte(byte(len(dataElement.Array)))
 		if err != nil {
 			return
@@ -62,18 +107,30 @@ func (d *ValueWriter) Write(dataElement Element) (err error) {
 			return
 		}
 
+		if len(dataElement.Fields) > MaxElements {
+			err = ErrMaxElements
+			return
+		}
+
 		err = d.writeByte(byte(len(dataElement.Fields)))
 		if err != nil {
 			return
 		}
 
-		for key, item := range dataElement.Fields {
+		// keys are written in canonical order so the bytes are deterministic
+		var keys []Value
+		keys, err = SortedKeys(dataElement.Fields)
+		if err != nil {
+			return
+		}
+
+		for _, key := range keys {
 			err = d.writeValue(key)
 			if err != nil {
 				return
 			}
 
-			err = d.Write(item)
+			err = d.Write(dataElement.Fields[key])
 			if err != nil {
 				return
 			}
@@ -84,25 +141,19 @@ func (d *ValueWriter) Write(dataElement Element) (err error) {
 }
 
 func (d *ValueWriter) writeByte(value byte) (err error) {
-	data := make([]byte, 1)
-	data[0] = value
-
-	_, err = d.Writer.Write(data)
-	if err != nil {
-		return
-	}
-
+	d.scratch[0] = value
+	_, err = d.Writer.Write(d.scratch[:1])
 	return
 }
 
 func (d *ValueWriter) writeBlob(value Blob) (err error) {
 	size := uint32(len(value))
-	if size > 65535 {
+	if size > uint32(MaxBlobSize) {
 		err = ErrMaxBlobSize
 		return
 	}
 
-	err = binary.Write(d.Writer, binary.BigEndian, size)
+	err = d.writeU32(size)
 	if err != nil {
 		return
 	}
@@ -117,67 +168,54 @@ func (d *ValueWriter) writeBlob(value Blob) (err error) {
 }
 
 func (d *ValueWriter) writeBool(value bool) (err error) {
-	data := make([]byte, 1)
-
 	if value {
-		data[0] = 1
-	} else {
-		data[0] = 0
-	}
-
-	_, err = d.Writer.Write(data)
-	if err != nil {
-		return
+		return d.writeByte(1)
 	}
 
-	return
+	return d.writeByte(0)
 }
 
 func (d *ValueWriter) writeString(value string) (err error) {
-	buf := bytes.NewBufferString(value)
-
-	if buf.Len() > MaxStringSize {
+	if len(value) > MaxStringSize {
 		err = ErrMaxStringSize
 		return
 	}
 
-	size := byte(buf.Len())
-	err = d.writeByte(size)
-	if err != nil {
-		return
-	}
-
-	_, err = d.Writer.Write(buf.Bytes())
+	err = d.writeByte(byte(len(value)))
 	if err != nil {
 		return
 	}
 
+	_, err = io.WriteString(d.Writer, value)
 	return
 }
 
 func (d *ValueWriter) writeU16(value uint16) (err error) {
-	data := make([]byte, 2)
-	binary.BigEndian.PutUint16(data, value)
-	_, err = d.Writer.Write(data)
+	binary.BigEndian.PutUint16(d.scratch[:2], value)
+	_, err = d.Writer.Write(d.scratch[:2])
 	return
 }
 
 func (d *ValueWriter) writeU32(value uint32) (err error) {
-	data := make([]byte, 4)
-	binary.BigEndian.PutUint32(data, value)
-	_, err = d.Writer.Write(data)
+	binary.BigEndian.PutUint32(d.scratch[:4], value)
+	_, err = d.Writer.Write(d.scratch[:4])
 	return
 }
 
 func (d *ValueWriter) writeU64(value uint64) (err error) {
-	data := make([]byte, 8)
-	binary.BigEndian.PutUint64(data, value)
-	_, err = d.Writer.Write(data)
+	binary.BigEndian.PutUint64(d.scratch[:8], value)
+	_, err = d.Writer.Write(d.scratch[:8])
 	return
 }
 
 func (d *ValueWriter) writeU128(value big.Int) (err error) {
-	_, err = d.Writer.Write(value.Bytes())
+	if value.Sign() < 0 || value.BitLen() > 128 {
+		err = ErrOverflow
+		return
+	}
+
+	value.FillBytes(d.scratch[:16])
+	_, err = d.Writer.Write(d.scratch[:16])
 	return
 }
 
@@ -0,0 +1,287 @@
+package extradata
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/gtank/ristretto255"
+	"github.com/xelis-project/xelis-go-sdk/data"
+	"github.com/xelis-project/xelis-go-sdk/transaction"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/sha3"
+)
+
+// based on https://github.com/xelis-project/xelis-blockchain/blob/master/xelis_common/src/transaction/extra_data.rs
+// the shared key is the sha3-256 of the compressed point opening * H, which the receiver (or the sender)
+// recomputes as private key * handle with handle = opening * public key
+
+var ErrInvalidScalar = errors.New("invalid scalar")
+var ErrInvalidPoint = errors.New("invalid ristretto point")
+var ErrInvalidSharedKey = errors.New("shared key must be 32 bytes")
+var ErrNoExtraData = errors.New("transfer has no extra data")
+var ErrInvalidRole = errors.New("invalid tx role")
+var ErrDecrypt = errors.New("can't decrypt extra data")
+
+var ristrettoBasepointCompressed = []byte{
+	0xe2, 0xf2, 0xae, 0x0a, 0x6a, 0xbc, 0x4e, 0x71, 0xa8, 0x84, 0xa9, 0x61, 0xc5, 0x00, 0x51, 0x5f,
+	0x58, 0xe3, 0x0b, 0x6a, 0xa5, 0x82, 0xdd, 0x8d, 0xb6, 0xa6, 0x59, 0x45, 0xe0, 0x8d, 0x2d, 0x76,
+}
+
+// the cipher is only used once per shared key
+var nonce = make([]byte, chacha20poly1305.NonceSize)
+
+type SharedKey [32]byte
+
+func (k SharedKey) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// SharedKeyFromHex decodes the shared key returned by the wallet in PlaintextExtraData
+func SharedKeyFromHex(value string) (key SharedKey, err error) {
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return
+	}
+
+	if len(b) != len(key) {
+		err = ErrInvalidSharedKey
+		return
+	}
+
+	copy(key[:], b)
+	return
+}
+
+// Encrypted is an outgoing payload with the handles to put in the transfer
+type Encrypted struct {
+	Cipher         []byte
+	SenderHandle   [32]byte
+	ReceiverHandle [32]byte
+	SharedKey      SharedKey
+}
+
+func blinding() *ristretto255.Element {
+	hash := sha3.New512()
+	hash.Write(ristrettoBasepointCompressed)
+	return (&ristretto255.Element{}).FromUniformBytes(hash.Sum(nil))
+}
+
+func decodeScalar(value [32]byte) (scalar *ristretto255.Scalar, err error) {
+	scalar = &ristretto255.Scalar{}
+	err = scalar.Decode(value[:])
+	if err != nil {
+		err = ErrInvalidScalar
+	}
+
+	return
+}
+
+func decodePoint(value [32]byte) (point *ristretto255.Element, err error) {
+	point = &ristretto255.Element{}
+	err = point.Decode(value[:])
+	if err != nil {
+		err = ErrInvalidPoint
+	}
+
+	return
+}
+
+func deriveSharedKey(point *ristretto255.Element) SharedKey {
+	return sha3.Sum256(point.Encode(nil))
+}
+
+// DeriveSharedKeyFromOpening derives the key from the opening used to build the transfer
+func DeriveSharedKeyFromOpening(opening [32]byte) (key SharedKey, err error) {
+	scalar, err := decodeScalar(opening)
+	if err != nil {
+		return
+	}
+
+	key = deriveSharedKey((&ristretto255.Element{}).ScalarMult(scalar, blinding()))
+	return
+}
+
+// DeriveSharedKeyFromHandle derives the key from our private key and our handle of the transfer
+func DeriveSharedKeyFromHandle(privateKey [32]byte, handle [32]byte) (key SharedKey, err error) {
+	scalar, err := decodeScalar(privateKey)
+	if err != nil {
+		return
+	}
+
+	point, err := decodePoint(handle)
+	if err != nil {
+		return
+	}
+
+	key = deriveSharedKey((&ristretto255.Element{}).ScalarMult(scalar, point))
+	return
+}
+
+// Seal encrypts the element bytes with the shared key
+func Seal(key SharedKey, element data.Element) (cipher []byte, err error) {
+	plaintext, err := element.ToBytes()
+	if err != nil {
+		return
+	}
+
+	aead, err := chacha20poly1305.New(key[:])
+	if err != nil {
+		return
+	}
+
+	cipher = aead.Seal(nil, nonce, plaintext, nil)
+	if len(cipher) > wallet.ExtraDataLimit {
+		cipher = nil
+		err = wallet.ErrExtraDataLimit
+	}
+
+	return
+}
+
+// Open decrypts the cipher with the shared key and decodes the element
+func Open(key SharedKey, cipher []byte) (element data.Element, err error) {
+	if len(cipher) > wallet.ExtraDataLimit {
+		err = wallet.ErrExtraDataLimit
+		return
+	}
+
+	aead, err := chacha20poly1305.New(key[:])
+	if err != nil {
+		return
+	}
+
+	plaintext, err := aead.Open(nil, nonce, cipher, nil)
+	if err != nil {
+		err = ErrDecrypt
+		return
+	}
+
+	return data.ElementFromBytes(plaintext)
+}
+
+// Encrypt encrypts the element for a transfer from sender to receiver with a new random opening
+func Encrypt(sender [32]byte, receiver [32]byte, element data.Element) (encrypted Encrypted, err error) {
+	var uniform [64]byte
+	_, err = rand.Read(uniform[:])
+	if err != nil {
+		return
+	}
+
+	opening := (&ristretto255.Scalar{}).FromUniformBytes(uniform[:])
+	var openingBytes [32]byte
+	copy(openingBytes[:], opening.Encode(nil))
+
+	return EncryptWithOpening(openingBytes, sender, receiver, element)
+}
+
+// EncryptWithOpening encrypts the element with the opening of the transfer
+func EncryptWithOpening(opening [32]byte, sender [32]byte, receiver [32]byte, element data.Element) (encrypted Encrypted, err error) {
+	scalar, err := decodeScalar(opening)
+	if err != nil {
+		return
+	}
+
+	senderPoint, err := decodePoint(sender)
+	if err != nil {
+		return
+	}
+
+	receiverPoint, err := decodePoint(receiver)
+	if err != nil {
+		return
+	}
+
+	key, err := DeriveSharedKeyFromOpening(opening)
+	if err != nil {
+		return
+	}
+
+	cipher, err := Seal(key, element)
+	if err != nil {
+		return
+	}
+
+	encrypted.Cipher = cipher
+	encrypted.SharedKey = key
+	copy(encrypted.SenderHandle[:], (&ristretto255.Element{}).ScalarMult(scalar, senderPoint).Encode(nil))
+	copy(encrypted.ReceiverHandle[:], (&ristretto255.Element{}).ScalarMult(scalar, receiverPoint).Encode(nil))
+	return
+}
+
+// Decrypt decrypts the cipher with our private key and our handle of the transfer
+func Decrypt(privateKey [32]byte, handle [32]byte, cipher []byte) (element data.Element, key SharedKey, err error) {
+	key, err = DeriveSharedKeyFromHandle(privateKey, handle)
+	if err != nil {
+		return
+	}
+
+	element, err = Open(key, cipher)
+	return
+}
+
+// DecryptTransfer decrypts the extra data of a transfer we sent or received,
+// the role selects which handle belongs to the private key
+func DecryptTransfer(privateKey [32]byte, transfer transaction.Transfer, role wallet.TxRole) (element data.Element, key SharedKey, err error) {
+	if transfer.ExtraData == nil {
+		err = ErrNoExtraData
+		return
+	}
+
+	var handleValues []uint
+	switch role {
+	case wallet.TxSenderRole:
+		handleValues = transfer.SenderHandle
+	case wallet.TxReceiverRole:
+		handleValues = transfer.ReceiverHandle
+	default:
+		err = ErrInvalidRole
+		return
+	}
+
+	handleBytes, err := Bytes(handleValues)
+	if err != nil {
+		return
+	}
+
+	if len(handleBytes) != 32 {
+		err = ErrInvalidPoint
+		return
+	}
+
+	cipher, err := Bytes(*transfer.ExtraData)
+	if err != nil {
+		return
+	}
+
+	var handle [32]byte
+	copy(handle[:], handleBytes)
+	return Decrypt(privateKey, handle, cipher)
+}
+
+// Bytes converts the byte arrays of the rpc json to bytes
+func Bytes(values []uint) (b []byte, err error) {
+	b = make([]byte, len(values))
+	for i, value := range values {
+		if value > 0xff {
+			err = fmt.Errorf("invalid byte %d at index %d", value, i)
+			return
+		}
+
+		b[i] = byte(value)
+	}
+
+	return
+}
+
+// Uints converts bytes to the byte arrays of the rpc json
+func Uints(b []byte) (values []uint) {
+	values = make([]uint, len(b))
+	for i, value := range b {
+		values[i] = uint(value)
+	}
+
+	return
+}
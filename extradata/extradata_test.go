@@ -0,0 +1,179 @@
+package extradata
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gtank/ristretto255"
+	"github.com/xelis-project/xelis-go-sdk/data"
+	"github.com/xelis-project/xelis-go-sdk/transaction"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+type testKeys struct {
+	private [32]byte
+	public  [32]byte
+}
+
+func newTestKeys(t *testing.T) (keys testKeys) {
+	var uniform [64]byte
+	_, err := rand.Read(uniform[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	private := (&ristretto255.Scalar{}).FromUniformBytes(uniform[:])
+	inverted := (&ristretto255.Scalar{}).Invert(private)
+	public := (&ristretto255.Element{}).ScalarMult(inverted, blinding())
+
+	copy(keys.private[:], private.Encode(nil))
+	copy(keys.public[:], public.Encode(nil))
+	return
+}
+
+func testElement() data.Element {
+	return data.Element{Fields: map[data.Value]data.Element{
+		"invoice": {Value: "INV-42"},
+		"amount":  {Value: uint64(1000)},
+	}}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	sender := newTestKeys(t)
+	receiver := newTestKeys(t)
+	element := testElement()
+
+	encrypted, err := Encrypt(sender.public, receiver.public, element)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfer := transaction.Transfer{
+		ExtraData:      &[]uint{},
+		SenderHandle:   Uints(encrypted.SenderHandle[:]),
+		ReceiverHandle: Uints(encrypted.ReceiverHandle[:]),
+	}
+	*transfer.ExtraData = Uints(encrypted.Cipher)
+
+	roles := map[wallet.TxRole]testKeys{
+		wallet.TxSenderRole:   sender,
+		wallet.TxReceiverRole: receiver,
+	}
+
+	for role, keys := range roles {
+		decrypted, key, err := DecryptTransfer(keys.private, transfer, role)
+		if err != nil {
+			t.Fatalf("%s: %s", role, err)
+		}
+
+		if key != encrypted.SharedKey {
+			t.Fatalf("%s: expected shared key %s, got %s", role, encrypted.SharedKey, key)
+		}
+
+		if !reflect.DeepEqual(element, decrypted) {
+			t.Fatalf("%s: expected %+v, got %+v", role, element, decrypted)
+		}
+	}
+
+	// the key given by the wallet is enough to read the payload
+	key, err := SharedKeyFromHex(encrypted.SharedKey.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := Open(key, encrypted.Cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(element, decrypted) {
+		t.Fatalf("expected %+v, got %+v", element, decrypted)
+	}
+
+	// wrong keys and tampered payloads are rejected
+	_, _, err = DecryptTransfer(newTestKeys(t).private, transfer, wallet.TxReceiverRole)
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected %s, got %v", ErrDecrypt, err)
+	}
+
+	encrypted.Cipher[0] ^= 1
+	_, err = Open(key, encrypted.Cipher)
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected %s, got %v", ErrDecrypt, err)
+	}
+}
+
+func TestEncryptLimit(t *testing.T) {
+	sender := newTestKeys(t)
+	receiver := newTestKeys(t)
+
+	_, err := Encrypt(sender.public, receiver.public, data.Element{Value: data.Blob(make([]byte, wallet.ExtraDataLimit))})
+	if !errors.Is(err, wallet.ErrExtraDataLimit) {
+		t.Fatalf("expected %s, got %v", wallet.ErrExtraDataLimit, err)
+	}
+}
+
+type vector struct {
+	PrivateKey string               `json:"private_key"`
+	Role       wallet.TxRole        `json:"role"`
+	Transfer   transaction.Transfer `json:"transfer"`
+	SharedKey  string               `json:"shared_key"`
+	Data       json.RawMessage      `json:"data"`
+}
+
+// TestVectors checks transfers captured from a wallet: the transfer of a transaction,
+// the private key of the wallet and the result of its decrypt_extra_data
+func TestVectors(t *testing.T) {
+	b, err := os.ReadFile("testdata/vectors.json")
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatal("missing testdata/vectors.json, shared key and payload layout must be checked against a wallet, see testdata/README.md")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors []vector
+	err = json.Unmarshal(b, &vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vectors) == 0 {
+		t.Fatal("testdata/vectors.json has no vectors")
+	}
+
+	for i, v := range vectors {
+		privateKeyBytes, err := hex.DecodeString(v.PrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var privateKey [32]byte
+		copy(privateKey[:], privateKeyBytes)
+
+		element, key, err := DecryptTransfer(privateKey, v.Transfer, v.Role)
+		if err != nil {
+			t.Fatalf("vector %d: %s", i, err)
+		}
+
+		if key.String() != v.SharedKey {
+			t.Fatalf("vector %d: expected shared key %s, got %s", i, v.SharedKey, key)
+		}
+
+		var expected data.Element
+		err = json.Unmarshal(v.Data, &expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(expected, element) {
+			t.Fatalf("vector %d: expected %+v, got %+v", i, expected, element)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package getwork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var ErrNotConnected = errors.New("getwork client is not connected")
+var ErrConnectionLost = errors.New("connection lost before the submission result")
+var ErrUnexpectedResult = errors.New("submission result without pending submission")
+
+type ClientOptions struct {
+	// MinBackoff is the first reconnection delay, doubled after each failure up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Dialer     *websocket.Dialer
+	// ResultsBuffer is the number of results kept when they are not read, the oldest are dropped
+	ResultsBuffer int
+}
+
+var DefaultClientOptions = ClientOptions{
+	MinBackoff:    500 * time.Millisecond,
+	MaxBackoff:    30 * time.Second,
+	ResultsBuffer: 64,
+}
+
+// Job is a block template with a local id increasing with each job received
+type Job struct {
+	BlockTemplate
+	Id         uint64
+	ReceivedAt time.Time
+}
+
+// SubmitResult is the answer of the node to a submission of the job,
+// Err is set if the connection was lost before the answer
+type SubmitResult struct {
+	Job       Job
+	MinerWork string
+	Accepted  bool
+	Reason    string
+	Err       error
+}
+
+type submission struct {
+	job       Job
+	minerWork string
+}
+
+// Client is a getwork connection that reconnects until its context is done.
+// Only the latest job is kept until it is read so miners never work on stale jobs,
+// the node answers submissions in order so each result is matched to its job.
+type Client struct {
+	endpoint string
+	options  ClientOptions
+
+	jobs    chan Job
+	results chan SubmitResult
+	errs    chan error
+
+	mutex   sync.Mutex
+	conn    *websocket.Conn
+	pending []submission
+	jobId   uint64
+}
+
+func NewClient(endpoint, minerAddress, worker string, options ClientOptions) (client *Client, err error) {
+	socketUrl, err := url.Parse(fmt.Sprintf("%s/%s/%s", endpoint, minerAddress, worker))
+	if err != nil {
+		return
+	}
+
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = DefaultClientOptions.MinBackoff
+	}
+
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = DefaultClientOptions.MaxBackoff
+		if options.MaxBackoff < options.MinBackoff {
+			options.MaxBackoff = options.MinBackoff
+		}
+	}
+
+	if options.Dialer == nil {
+		options.Dialer = websocket.DefaultDialer
+	}
+
+	if options.ResultsBuffer <= 0 {
+		options.ResultsBuffer = DefaultClientOptions.ResultsBuffer
+	}
+
+	client = &Client{
+		endpoint: socketUrl.String(),
+		options:  options,
+		jobs:     make(chan Job, 1),
+		results:  make(chan SubmitResult, options.ResultsBuffer),
+		errs:     make(chan error, 16),
+	}
+
+	return
+}
+
+// Jobs delivers the latest job, a job not read before the next one is dropped
+func (c *Client) Jobs() <-chan Job {
+	return c.jobs
+}
+
+func (c *Client) Results() <-chan SubmitResult {
+	return c.results
+}
+
+// Errors reports connection and message errors, they are dropped if not read
+func (c *Client) Errors() <-chan error {
+	return c.errs
+}
+
+func (c *Client) Connected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.conn != nil
+}
+
+// Run connects and reads messages until ctx is done, reconnecting with backoff
+func (c *Client) Run(ctx context.Context) error {
+	backoff := c.options.MinBackoff
+	for {
+		conn, _, err := c.options.Dialer.DialContext(ctx, c.endpoint, nil)
+		if err == nil {
+			backoff = c.options.MinBackoff
+			err = c.serve(ctx, conn)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		c.reportErr(err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > c.options.MaxBackoff {
+			backoff = c.options.MaxBackoff
+		}
+	}
+}
+
+func (c *Client) serve(ctx context.Context, conn *websocket.Conn) error {
+	c.mutex.Lock()
+	c.conn = conn
+	c.mutex.Unlock()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	defer c.disconnect(conn)
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		m, err := parseMessage(msg)
+		if err != nil {
+			c.reportErr(err)
+			continue
+		}
+
+		switch m.Type {
+		case newJobMessage:
+			c.deliverJob(m.Job)
+		case blockAcceptedMessage:
+			c.deliverResult(true, "")
+		case blockRejectedMessage:
+			c.deliverResult(false, m.Reason)
+		}
+	}
+}
+
+// disconnect fails the submissions that will never be answered
+func (c *Client) disconnect(conn *websocket.Conn) {
+	conn.Close()
+
+	c.mutex.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.conn = nil
+	c.mutex.Unlock()
+
+	for _, s := range pending {
+		c.sendResult(SubmitResult{Job: s.job, MinerWork: s.minerWork, Err: ErrConnectionLost})
+	}
+}
+
+func (c *Client) deliverJob(template BlockTemplate) {
+	c.mutex.Lock()
+	c.jobId++
+	job := Job{BlockTemplate: template, Id: c.jobId, ReceivedAt: time.Now()}
+	c.mutex.Unlock()
+
+	// replace the unread job if any, only the reader goroutine sends so this can't block
+	select {
+	case <-c.jobs:
+	default:
+	}
+
+	c.jobs <- job
+}
+
+func (c *Client) deliverResult(accepted bool, reason string) {
+	c.mutex.Lock()
+	if len(c.pending) == 0 {
+		c.mutex.Unlock()
+		c.reportErr(ErrUnexpectedResult)
+		return
+	}
+
+	s := c.pending[0]
+	c.pending = c.pending[1:]
+	c.mutex.Unlock()
+
+	c.sendResult(SubmitResult{Job: s.job, MinerWork: s.minerWork, Accepted: accepted, Reason: reason})
+}
+
+func (c *Client) sendResult(result SubmitResult) {
+	for {
+		select {
+		case c.results <- result:
+			return
+		default:
+		}
+
+		// drop the oldest result
+		select {
+		case <-c.results:
+		default:
+		}
+	}
+}
+
+func (c *Client) reportErr(err error) {
+	select {
+	case c.errs <- err:
+	default:
+	}
+}
+
+// Submit sends the miner work found for the job, the result is delivered on Results
+func (c *Client) Submit(job Job, minerWork string) (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+
+	err = c.conn.WriteJSON(submitMessage{BlockTemplate: minerWork})
+	if err != nil {
+		return
+	}
+
+	c.pending = append(c.pending, submission{job: job, minerWork: minerWork})
+	return
+}
@@ -0,0 +1,155 @@
+package getwork
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xelis-project/xelis-go-sdk/getwork/internal/testutil"
+)
+
+func newTestServer(t *testing.T, handler func(conn *websocket.Conn, connection int32)) (endpoint string) {
+	var connections int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		handler(conn, atomic.AddInt32(&connections, 1))
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func writeJob(t *testing.T, conn *websocket.Conn, height uint64) {
+	err := conn.WriteJSON(map[string]interface{}{
+		NewJob: map[string]interface{}{"miner_work": "00", "height": height, "difficulty": "1000", "algorithm": "xel/v2"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func readSubmit(t *testing.T, conn *websocket.Conn) string {
+	var submit submitMessage
+	err := conn.ReadJSON(&submit)
+	if err != nil {
+		t.Error(err)
+	}
+
+	return submit.BlockTemplate
+}
+
+func TestClient(t *testing.T) {
+	endpoint := newTestServer(t, func(conn *websocket.Conn, connection int32) {
+		if connection == 1 {
+			writeJob(t, conn, 1)
+			writeJob(t, conn, 2)
+			// no pending submission, used to know both jobs were read
+			conn.WriteJSON(BlockAccepted)
+
+			if readSubmit(t, conn) != "aa" {
+				t.Error("unexpected submission")
+			}
+			conn.WriteJSON(BlockAccepted)
+
+			readSubmit(t, conn)
+			conn.WriteJSON(map[string]string{BlockRejected: "invalid pow"})
+
+			// never answered, lost with the connection
+			readSubmit(t, conn)
+			return
+		}
+
+		writeJob(t, conn, 3)
+		conn.ReadMessage()
+	})
+
+	client, err := NewClient(endpoint, TESTNET_WALLET, "test", ClientOptions{MinBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.Run(ctx)
+	}()
+
+	err = testutil.Receive(t, client.Errors())
+	if !errors.Is(err, ErrUnexpectedResult) {
+		t.Fatalf("expected %s, got %v", ErrUnexpectedResult, err)
+	}
+
+	// the first job was replaced before being read
+	job := testutil.Receive(t, client.Jobs())
+	if job.Id != 2 || job.Height != 2 || job.Template != "00" || job.Algorithm != "xel/v2" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	for _, work := range []string{"aa", "bb", "cc"} {
+		err = client.Submit(job, work)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := []SubmitResult{testutil.Receive(t, client.Results()), testutil.Receive(t, client.Results()), testutil.Receive(t, client.Results())}
+	if !results[0].Accepted || results[0].Job.Id != 2 {
+		t.Fatalf("unexpected result %+v", results[0])
+	}
+
+	if results[1].Accepted || results[1].Reason != "invalid pow" {
+		t.Fatalf("unexpected result %+v", results[1])
+	}
+
+	if !errors.Is(results[2].Err, ErrConnectionLost) {
+		t.Fatalf("expected %s, got %+v", ErrConnectionLost, results[2])
+	}
+
+	// reconnected
+	job = testutil.Receive(t, client.Jobs())
+	if job.Id != 3 || job.Height != 3 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	cancel()
+	err = testutil.Receive(t, done)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %s, got %v", context.Canceled, err)
+	}
+
+	if client.Connected() {
+		t.Fatal("expected disconnected client")
+	}
+
+	err = client.Submit(job, "dd")
+	if !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected %s, got %v", ErrNotConnected, err)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	invalid := []string{`"unknown"`, `{"other":1}`, `{"new_job":"x"}`, `{"block_rejected":5}`, `[]`, `1`}
+	for _, msg := range invalid {
+		_, err := parseMessage([]byte(msg))
+		if err == nil {
+			t.Fatalf("%s: expected error", msg)
+		}
+	}
+
+	m, err := parseMessage([]byte(`{"new_job":{"template":"ff","height":7}}`))
+	if err != nil || m.Type != newJobMessage || m.Job.Template != "ff" || m.Job.Height != 7 {
+		t.Fatalf("unexpected message %+v: %v", m, err)
+	}
+}
@@ -1,15 +1,17 @@
 package getwork
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/url"
+	"sync"
 
 	"github.com/gorilla/websocket"
 )
 
 type Getwork struct {
-	conn *websocket.Conn
+	conn      *websocket.Conn
+	done      chan struct{}
+	closeOnce sync.Once
 
 	Job           chan BlockTemplate
 	AcceptedBlock chan bool
@@ -17,6 +19,7 @@ type Getwork struct {
 	Err           chan error
 }
 
+// NewGetwork connects once and never reconnects, use NewClient for long running miners
 func NewGetwork(endpoint, minerAddress, worker string) (*Getwork, error) {
 	socketUrl, err := url.Parse(fmt.Sprintf("%s/%s/%s", endpoint, minerAddress, worker))
 	if err != nil {
@@ -30,6 +33,7 @@ func NewGetwork(endpoint, minerAddress, worker string) (*Getwork, error) {
 
 	getwork := &Getwork{
 		conn:          conn,
+		done:          make(chan struct{}),
 		Job:           make(chan BlockTemplate),
 		AcceptedBlock: make(chan bool),
 		RejectedBlock: make(chan string),
@@ -37,16 +41,25 @@ func NewGetwork(endpoint, minerAddress, worker string) (*Getwork, error) {
 	}
 
 	go func() {
+		// only the reader closes the channels so it never sends on a closed channel
+		defer func() {
+			close(getwork.Job)
+			close(getwork.AcceptedBlock)
+			close(getwork.RejectedBlock)
+			close(getwork.Err)
+		}()
 		defer getwork.Close()
 
 		for {
 			_, msg, err := conn.ReadMessage()
 			if err != nil {
-				getwork.Err <- err
+				getwork.sendErr(err)
 				return
 			}
 
-			getwork.handleMessage(msg)
+			if !getwork.handleMessage(msg) {
+				return
+			}
 		}
 	}()
 
@@ -54,63 +67,52 @@ func NewGetwork(endpoint, minerAddress, worker string) (*Getwork, error) {
 }
 
 func (w *Getwork) Close() {
-	close(w.Job)
-	close(w.AcceptedBlock)
-	close(w.RejectedBlock)
-	close(w.Err)
-	w.conn.Close()
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.conn.Close()
+	})
 }
 
-func (w *Getwork) handleMessage(msg []byte) {
-	var res interface{}
-	err := json.Unmarshal(msg, &res)
-	if err != nil {
-		w.Err <- err
-		return
+func (w *Getwork) sendErr(err error) bool {
+	select {
+	case w.Err <- err:
+		return true
+	case <-w.done:
+		return false
 	}
+}
 
-	jsonMap, ok := res.(map[string]interface{})
-	if ok {
-		blockTemplate, ok := jsonMap[NewJob].(map[string]interface{})
-		if ok {
-			bt := BlockTemplate{}
-
-			if blockTemplate["difficulty"] != nil {
-				bt.Difficulty = blockTemplate["difficulty"].(string)
-			}
-			if blockTemplate["height"] != nil {
-				bt.Height = uint64(blockTemplate["height"].(float64))
-			}
-			if blockTemplate["miner_work"] != nil {
-				bt.Template = blockTemplate["miner_work"].(string)
-			} else if blockTemplate["template"] != nil {
-				bt.Template = blockTemplate["template"].(string)
-			}
-			if blockTemplate["algorithm"] != nil {
-				bt.Algorithm = blockTemplate["algorithm"].(string)
-			}
+// handleMessage returns false if the getwork was closed while delivering the message
+func (w *Getwork) handleMessage(msg []byte) bool {
+	m, err := parseMessage(msg)
+	if err != nil {
+		return w.sendErr(err)
+	}
 
-			w.Job <- bt
-			return
+	switch m.Type {
+	case newJobMessage:
+		select {
+		case w.Job <- m.Job:
+		case <-w.done:
+			return false
 		}
-
-		rejected, ok := jsonMap[BlockRejected].(string)
-		if ok {
-			w.RejectedBlock <- rejected
-			return
+	case blockAcceptedMessage:
+		select {
+		case w.AcceptedBlock <- true:
+		case <-w.done:
+			return false
 		}
-	}
-
-	value, ok := res.(string)
-	if ok {
-		if value == BlockAccepted {
-			w.AcceptedBlock <- true
-			return
+	case blockRejectedMessage:
+		select {
+		case w.RejectedBlock <- m.Reason:
+		case <-w.done:
+			return false
 		}
 	}
+
+	return true
 }
 
 func (w *Getwork) SubmitBlock(hexData string) (err error) {
-	data := map[string]interface{}{"block_template": hexData}
-	return w.conn.WriteJSON(data)
+	return w.conn.WriteJSON(submitMessage{BlockTemplate: hexData})
 }
@@ -0,0 +1,20 @@
+// Package testutil holds the helpers shared by the getwork tests
+package testutil
+
+import (
+	"testing"
+	"time"
+)
+
+// Receive waits for a value on c and fails the test after 5 seconds
+func Receive[T any](t testing.TB, c <-chan T) (value T) {
+	t.Helper()
+
+	select {
+	case value = <-c:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	return
+}
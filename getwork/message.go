@@ -0,0 +1,73 @@
+package getwork
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownMessage = errors.New("unknown getwork message")
+
+type messageType int
+
+const (
+	newJobMessage messageType = iota
+	blockAcceptedMessage
+	blockRejectedMessage
+)
+
+// the node sends the work as miner_work, older nodes as template
+type jobMessage struct {
+	BlockTemplate
+	MinerWork string `json:"miner_work"`
+}
+
+type objectMessage struct {
+	NewJob        *jobMessage `json:"new_job"`
+	BlockRejected *string     `json:"block_rejected"`
+}
+
+type message struct {
+	Type   messageType
+	Job    BlockTemplate
+	Reason string
+}
+
+func parseMessage(msg []byte) (m message, err error) {
+	var value string
+	if json.Unmarshal(msg, &value) == nil {
+		if value == BlockAccepted {
+			m.Type = blockAcceptedMessage
+			return
+		}
+
+		err = fmt.Errorf("%w: %s", ErrUnknownMessage, value)
+		return
+	}
+
+	var object objectMessage
+	err = json.Unmarshal(msg, &object)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case object.NewJob != nil:
+		m.Type = newJobMessage
+		m.Job = object.NewJob.BlockTemplate
+		if object.NewJob.MinerWork != "" {
+			m.Job.Template = object.NewJob.MinerWork
+		}
+	case object.BlockRejected != nil:
+		m.Type = blockRejectedMessage
+		m.Reason = *object.BlockRejected
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownMessage, msg)
+	}
+
+	return
+}
+
+type submitMessage struct {
+	BlockTemplate string `json:"block_template"`
+}
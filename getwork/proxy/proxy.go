@@ -0,0 +1,375 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xelis-project/xelis-go-sdk/getwork"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+)
+
+// ExtraNonceOffset is where the proxy writes the miner id in the extra nonce,
+// the first bytes are left to the upstream pool and the last ones to the miners
+const ExtraNonceOffset = 8
+
+// jobs and results waiting to be written to a miner, a miner falling further behind is dropped
+const minerQueueSize = 4
+
+// rejection reasons sent to miners for submissions not forwarded upstream
+const (
+	ReasonInvalidWork  = "invalid miner work"
+	ReasonStaleJob     = "stale job"
+	ReasonDuplicate    = "duplicate share"
+	ReasonUpstreamDown = "upstream not connected"
+	ReasonUpstreamLost = "upstream connection lost"
+)
+
+// Stats are the counters of the proxy since it started
+type Stats struct {
+	Miners     int    `json:"miners"`
+	Jobs       uint64 `json:"jobs"`
+	Submitted  uint64 `json:"submitted"`
+	Accepted   uint64 `json:"accepted"`
+	Rejected   uint64 `json:"rejected"`
+	Duplicates uint64 `json:"duplicates"`
+	Stale      uint64 `json:"stale"`
+	Invalid    uint64 `json:"invalid"`
+	Lost       uint64 `json:"lost"`
+}
+
+type miner struct {
+	id     uint64
+	worker string
+	conn   *websocket.Conn
+	mutex  sync.Mutex
+	jobs   chan interface{}
+}
+
+func (m *miner) write(value interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return m.conn.WriteJSON(value)
+}
+
+// queue sends the job or result to the miner without waiting for it, a miner too slow to keep up is dropped
+func (m *miner) queue(value interface{}) {
+	select {
+	case m.jobs <- value:
+	default:
+		m.conn.Close()
+	}
+}
+
+// writeJobs writes the queued jobs and results until done, closing the connection drops the miner
+func (m *miner) writeJobs(done <-chan struct{}) {
+	for {
+		select {
+		case value := <-m.jobs:
+			err := m.write(value)
+			if err != nil {
+				m.conn.Close()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+type job struct {
+	upstream  getwork.Job
+	minerWork []byte
+	shares    map[string]struct{}
+}
+
+// Proxy keeps one upstream getwork connection for all the miners connecting to it.
+// Each miner gets the upstream job with its own extra nonce range and its submissions are
+// checked and deduplicated before being forwarded upstream.
+type Proxy struct {
+	upstream *getwork.Client
+	upgrader websocket.Upgrader
+
+	mutex   sync.Mutex
+	jobs    []*job
+	miners  map[uint64]*miner
+	pending map[string]*miner
+	stats   Stats
+	nextId  uint64
+}
+
+// New connects upstream as minerAddress/worker, miners of the proxy mine for this address
+func New(endpoint, minerAddress, worker string, options getwork.ClientOptions) (*Proxy, error) {
+	upstream, err := getwork.NewClient(endpoint, minerAddress, worker, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Proxy{
+		upstream: upstream,
+		miners:   make(map[uint64]*miner),
+		pending:  make(map[string]*miner),
+	}, nil
+}
+
+// Errors reports the upstream errors, they are dropped if not read
+func (p *Proxy) Errors() <-chan error {
+	return p.upstream.Errors()
+}
+
+func (p *Proxy) Stats() Stats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats
+	stats.Miners = len(p.miners)
+	return stats
+}
+
+// Run keeps the upstream connection and dispatches jobs and results until ctx is done
+func (p *Proxy) Run(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- p.upstream.Run(ctx)
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			p.closeMiners()
+			return err
+		case upstreamJob := <-p.upstream.Jobs():
+			p.setJob(upstreamJob)
+		case result := <-p.upstream.Results():
+			p.handleResult(result)
+		}
+	}
+}
+
+func (p *Proxy) setJob(upstreamJob getwork.Job) {
+	minerWork, err := hex.DecodeString(upstreamJob.Template)
+	if err != nil || len(minerWork) != work.Size {
+		return
+	}
+
+	j := &job{upstream: upstreamJob, minerWork: minerWork, shares: make(map[string]struct{})}
+
+	p.mutex.Lock()
+	p.jobs = append([]*job{j}, p.jobs...)
+	if len(p.jobs) > 2 {
+		p.jobs = p.jobs[:2]
+	}
+	p.stats.Jobs++
+
+	miners := make([]*miner, 0, len(p.miners))
+	for _, m := range p.miners {
+		miners = append(miners, m)
+	}
+	p.mutex.Unlock()
+
+	for _, m := range miners {
+		m.queue(p.message(j, m))
+	}
+}
+
+func extraNonceRange() (start int, end int) {
+	start = work.ExtraNonceOffset + ExtraNonceOffset
+	return start, start + 8
+}
+
+// message is the upstream job with the extra nonce range of the miner
+func (p *Proxy) message(j *job, m *miner) interface{} {
+	minerWork := append([]byte(nil), j.minerWork...)
+	start, _ := extraNonceRange()
+	binary.BigEndian.PutUint64(minerWork[start:], m.id)
+
+	return map[string]getwork.MinerWork{getwork.NewJob: {
+		Algorithm:  j.upstream.Algorithm,
+		MinerWork:  hex.EncodeToString(minerWork),
+		Height:     j.upstream.Height,
+		Difficulty: j.upstream.Difficulty,
+		TopoHeight: j.upstream.TopoHeight,
+	}}
+}
+
+func (p *Proxy) handleResult(result getwork.SubmitResult) {
+	p.mutex.Lock()
+	m, ok := p.pending[result.MinerWork]
+	delete(p.pending, result.MinerWork)
+
+	reason := result.Reason
+	switch {
+	case result.Err != nil:
+		p.stats.Lost++
+		reason = ReasonUpstreamLost
+	case result.Accepted:
+		p.stats.Accepted++
+	default:
+		p.stats.Rejected++
+	}
+	p.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	// queued like the jobs so a slow miner doesn't hold the upstream results
+	if result.Accepted {
+		m.queue(getwork.BlockAccepted)
+	} else {
+		m.queue(map[string]string{getwork.BlockRejected: reason})
+	}
+}
+
+// ServeHTTP accepts miners at /getwork/{address}/{worker}, the address is ignored
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "getwork" {
+		http.NotFound(w, r)
+		return
+	}
+
+	conn, err := p.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	p.serve(conn, parts[2])
+}
+
+func (p *Proxy) serve(conn *websocket.Conn, worker string) {
+	p.mutex.Lock()
+	p.nextId++
+	m := &miner{id: p.nextId, worker: worker, conn: conn, jobs: make(chan interface{}, minerQueueSize)}
+	p.miners[m.id] = m
+
+	// queued under the lock so a new job can't be written before it
+	if len(p.jobs) > 0 {
+		m.queue(p.message(p.jobs[0], m))
+	}
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go m.writeJobs(done)
+
+	defer func() {
+		close(done)
+		p.mutex.Lock()
+		delete(p.miners, m.id)
+		for work, pending := range p.pending {
+			if pending == m {
+				delete(p.pending, work)
+			}
+		}
+		p.mutex.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var submit struct {
+			BlockTemplate string `json:"block_template"`
+			MinerWork     string `json:"miner_work"`
+		}
+
+		err = json.Unmarshal(msg, &submit)
+		if err != nil {
+			return
+		}
+
+		minerWork := submit.BlockTemplate
+		if submit.MinerWork != "" {
+			minerWork = submit.MinerWork
+		}
+
+		reason := p.submit(m, minerWork)
+		if reason != "" {
+			err = m.write(map[string]string{getwork.BlockRejected: reason})
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// submit forwards the work upstream, the answer is sent to the miner when the upstream result arrives
+func (p *Proxy) submit(m *miner, workHex string) (reason string) {
+	minerWork, err := hex.DecodeString(workHex)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err != nil || len(minerWork) != work.Size {
+		p.stats.Invalid++
+		return ReasonInvalidWork
+	}
+
+	var j *job
+	for _, candidate := range p.jobs {
+		if bytes.Equal(candidate.minerWork[:32], minerWork[:32]) {
+			j = candidate
+			break
+		}
+	}
+
+	if j == nil {
+		p.stats.Stale++
+		return ReasonStaleJob
+	}
+
+	start, end := extraNonceRange()
+	if binary.BigEndian.Uint64(minerWork[start:end]) != m.id ||
+		!bytes.Equal(minerWork[work.ExtraNonceOffset:start], j.minerWork[work.ExtraNonceOffset:start]) ||
+		!bytes.Equal(minerWork[work.MinerKeyOffset:], j.minerWork[work.MinerKeyOffset:]) {
+		p.stats.Invalid++
+		return ReasonInvalidWork
+	}
+
+	workHex = hex.EncodeToString(minerWork)
+	if _, ok := j.shares[workHex]; ok {
+		p.stats.Duplicates++
+		return ReasonDuplicate
+	}
+
+	// registered before submitting so the result can't arrive before it,
+	// the share is kept only if the submission is sent
+	p.pending[workHex] = m
+	j.shares[workHex] = struct{}{}
+	p.mutex.Unlock()
+
+	err = p.upstream.Submit(j.upstream, workHex)
+
+	p.mutex.Lock()
+	if err != nil {
+		if p.pending[workHex] == m {
+			delete(p.pending, workHex)
+		}
+		delete(j.shares, workHex)
+		return ReasonUpstreamDown
+	}
+
+	p.stats.Submitted++
+	return
+}
+
+func (p *Proxy) closeMiners() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, m := range p.miners {
+		m.conn.Close()
+	}
+}
@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/xelis-project/xelis-go-sdk/getwork"
+	"github.com/xelis-project/xelis-go-sdk/getwork/internal/testutil"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+)
+
+const MINER_ADDR = "xel:ys4peuzztwl67rzhsdu0yxfzwcfmgt85uu53hycpeeary7n8qvysqmxznt0"
+
+func wsEndpoint(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/getwork"
+}
+
+// upstreamWork has the upstream extra nonce filled with 0xaa and the miner key with 7
+func upstreamWork() []byte {
+	minerWork := make([]byte, work.Size)
+	copy(minerWork, "minerWork hash")
+	for i := work.ExtraNonceOffset; i < work.ExtraNonceOffset+8; i++ {
+		minerWork[i] = 0xaa
+	}
+
+	for i := work.MinerKeyOffset; i < work.Size; i++ {
+		minerWork[i] = 7
+	}
+
+	return minerWork
+}
+
+// the upstream accepts nonce 1 and rejects anything else
+func newUpstream(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteJSON(map[string]getwork.MinerWork{getwork.NewJob: {
+			MinerWork:  hex.EncodeToString(upstreamWork()),
+			Height:     5,
+			Difficulty: "100",
+			Algorithm:  "xel/v2",
+		}})
+
+		for {
+			var submit struct {
+				BlockTemplate string `json:"block_template"`
+			}
+
+			err := conn.ReadJSON(&submit)
+			if err != nil {
+				return
+			}
+
+			minerWork, _ := hex.DecodeString(submit.BlockTemplate)
+			if binary.BigEndian.Uint64(minerWork[work.NonceOffset:]) == 1 {
+				conn.WriteJSON(getwork.BlockAccepted)
+			} else {
+				conn.WriteJSON(map[string]string{getwork.BlockRejected: "low diff"})
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newMiner(t *testing.T, ctx context.Context, endpoint string, worker string) (*getwork.Client, getwork.Job, []byte) {
+	client, err := getwork.NewClient(endpoint, MINER_ADDR, worker, getwork.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go client.Run(ctx)
+
+	job := testutil.Receive(t, client.Jobs())
+	minerWork, err := hex.DecodeString(job.Template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, job, minerWork
+}
+
+func withNonce(minerWork []byte, nonce uint64) string {
+	w := append([]byte(nil), minerWork...)
+	binary.BigEndian.PutUint64(w[40:], nonce)
+	return hex.EncodeToString(w)
+}
+
+func TestProxy(t *testing.T) {
+	upstream := newUpstream(t)
+	proxy, err := New(wsEndpoint(upstream), MINER_ADDR, "proxy", getwork.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go proxy.Run(ctx)
+
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	minerA, jobA, workA := newMiner(t, ctx, wsEndpoint(server), "a")
+	minerB, jobB, workB := newMiner(t, ctx, wsEndpoint(server), "b")
+
+	start, end := extraNonceRange()
+	if bytes.Equal(workA[start:end], workB[start:end]) {
+		t.Fatal("miners must have different extra nonce ranges")
+	}
+
+	expected := upstreamWork()
+	if !bytes.Equal(workA[:start], expected[:start]) || !bytes.Equal(workA[end:], expected[end:]) || jobA.Height != 5 || jobA.Difficulty != "100" {
+		t.Fatalf("unexpected job %+v", jobA)
+	}
+
+	stale := append([]byte(nil), workA...)
+	stale[0] ^= 1
+
+	submissions := []struct {
+		client *getwork.Client
+		job    getwork.Job
+		work   string
+		reason string
+	}{
+		{minerA, jobA, withNonce(workA, 1), ""},
+		{minerB, jobB, withNonce(workB, 2), "low diff"},
+		{minerA, jobA, withNonce(workA, 1), ReasonDuplicate},
+		{minerA, jobA, withNonce(workB, 3), ReasonInvalidWork},
+		{minerA, jobA, hex.EncodeToString(stale), ReasonStaleJob},
+	}
+
+	for _, submission := range submissions {
+		err = submission.client.Submit(submission.job, submission.work)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := testutil.Receive(t, submission.client.Results())
+		if result.Err != nil || result.Accepted != (submission.reason == "") || result.Reason != submission.reason {
+			t.Fatalf("expected %q, got %+v", submission.reason, result)
+		}
+	}
+
+	stats := proxy.Stats()
+	expectedStats := Stats{Miners: 2, Jobs: 1, Submitted: 2, Accepted: 1, Rejected: 1, Duplicates: 1, Stale: 1, Invalid: 1}
+	if stats != expectedStats {
+		t.Fatalf("expected %+v, got %+v", expectedStats, stats)
+	}
+}
+
+func TestSlowMiner(t *testing.T) {
+	conns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conns <- conn
+		}
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// no writer is running, the second job overflows the queue
+	m := &miner{conn: testutil.Receive(t, conns), jobs: make(chan interface{}, 1)}
+	m.queue(getwork.BlockAccepted)
+	m.queue(getwork.BlockAccepted)
+
+	err = m.write(getwork.BlockAccepted)
+	if err == nil {
+		t.Fatal("expected the slow miner to be dropped")
+	}
+}
+
+func TestResultQueued(t *testing.T) {
+	// no writer is running, the result is queued instead of written by the upstream loop
+	m := &miner{jobs: make(chan interface{}, 1)}
+	proxy := &Proxy{miners: make(map[uint64]*miner), pending: map[string]*miner{"work": m}}
+	proxy.handleResult(getwork.SubmitResult{MinerWork: "work", Accepted: true})
+
+	select {
+	case value := <-m.jobs:
+		if value != getwork.BlockAccepted {
+			t.Fatalf("expected %v, got %v", getwork.BlockAccepted, value)
+		}
+	default:
+		t.Fatal("expected the result to be queued")
+	}
+
+	if stats := proxy.Stats(); stats.Accepted != 1 || len(proxy.pending) != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
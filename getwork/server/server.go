@@ -0,0 +1,490 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/getwork"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+)
+
+var ErrNoHasher = errors.New("a hasher is required to validate shares")
+
+// rejection reasons sent to miners
+const (
+	ReasonInvalidWork = "invalid miner work"
+	ReasonStaleJob    = "stale job"
+	ReasonDuplicate   = "duplicate share"
+	ReasonLowDiff     = "low difficulty share"
+	ReasonHashError   = "can't hash miner work"
+)
+
+// Daemon is implemented by daemon.RPC and daemon.WebSocket
+type Daemon interface {
+	GetBlockTemplate(params daemon.GetBlockTemplateParams) (daemon.GetBlockTemplateResult, error)
+	GetMinerWork(params daemon.GetMinerWorkParams) (daemon.GetMinerWorkResult, error)
+	SubmitBlock(params daemon.SubmitBlockParams) (bool, error)
+}
+
+// TemplateNotifier is implemented by daemon.WebSocket, templates are pushed instead of waiting for the next poll
+type TemplateNotifier interface {
+	NewBlockTemplateFunc(onData func(daemon.GetBlockTemplateResult, error)) error
+}
+
+// Hasher returns the pow hash of the miner work for the algorithm of the job
+type Hasher func(algorithm daemon.AlgorithmVersion, minerWork []byte) ([32]byte, error)
+
+type Options struct {
+	// PoolAddress receives the block rewards
+	PoolAddress string
+	// Network of the miner addresses, not checked if empty
+	Network config.Network
+	// ShareDifficulty is sent to miners, capped to the network difficulty
+	ShareDifficulty uint64
+	// RefreshInterval is the template polling interval, 5s by default
+	RefreshInterval time.Duration
+	Hasher          Hasher
+	// OnError receives daemon and connection errors
+	OnError func(error)
+}
+
+// WorkerStats are the counters of a worker since the server started
+type WorkerStats struct {
+	Address       string `json:"address"`
+	Worker        string `json:"worker"`
+	Connections   int    `json:"connections"`
+	ValidShares   uint64 `json:"valid_shares"`
+	InvalidShares uint64 `json:"invalid_shares"`
+	StaleShares   uint64 `json:"stale_shares"`
+	Blocks        uint64 `json:"blocks"`
+	// sum of the difficulty of the valid shares
+	Work        *big.Int  `json:"work"`
+	LastShareAt time.Time `json:"last_share_at"`
+}
+
+type template struct {
+	result            daemon.GetBlockTemplateResult
+	minerWork         []byte
+	networkDifficulty *big.Int
+	shares            map[string]struct{}
+}
+
+type miner struct {
+	id      uint64
+	address string
+	worker  string
+	conn    *websocket.Conn
+	mutex   sync.Mutex
+}
+
+func (m *miner) write(value interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return m.conn.WriteJSON(value)
+}
+
+// Server is a getwork pool: miners connect to /getwork/{address}/{worker}
+// and get the pool template with their own extra nonce and the share difficulty
+type Server struct {
+	daemon   Daemon
+	options  Options
+	upgrader websocket.Upgrader
+
+	// serializes template updates so a template is never added twice
+	templateMutex sync.Mutex
+
+	mutex     sync.Mutex
+	templates []*template
+	miners    map[uint64]*miner
+	stats     map[string]*WorkerStats
+	nextId    uint64
+}
+
+func NewServer(d Daemon, options Options) (*Server, error) {
+	if options.Hasher == nil {
+		return nil, ErrNoHasher
+	}
+
+	_, err := address.NewAddressFromString(options.PoolAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool address: %w", err)
+	}
+
+	if options.ShareDifficulty == 0 {
+		options.ShareDifficulty = 1
+	}
+
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = 5 * time.Second
+	}
+
+	return &Server{
+		daemon:  d,
+		options: options,
+		miners:  make(map[uint64]*miner),
+		stats:   make(map[string]*WorkerStats),
+	}, nil
+}
+
+func (s *Server) reportErr(err error) {
+	if s.options.OnError != nil {
+		s.options.OnError(err)
+	}
+}
+
+// Run polls templates until ctx is done, templates pushed by a TemplateNotifier daemon are used immediately
+func (s *Server) Run(ctx context.Context) error {
+	if notifier, ok := s.daemon.(TemplateNotifier); ok {
+		err := notifier.NewBlockTemplateFunc(func(result daemon.GetBlockTemplateResult, err error) {
+			if err != nil {
+				s.reportErr(err)
+				return
+			}
+
+			// the event is for any address, fetch the template of the pool
+			s.Refresh()
+		})
+		if err != nil {
+			s.reportErr(err)
+		}
+	}
+
+	ticker := time.NewTicker(s.options.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Refresh()
+
+		select {
+		case <-ctx.Done():
+			s.closeMiners()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches the pool template and sends new jobs to the miners if it changed
+func (s *Server) Refresh() {
+	result, err := s.daemon.GetBlockTemplate(daemon.GetBlockTemplateParams{Address: s.options.PoolAddress})
+	if err != nil {
+		s.reportErr(err)
+		return
+	}
+
+	err = s.SetTemplate(result)
+	if err != nil {
+		s.reportErr(err)
+	}
+}
+
+// SetTemplate replaces the current template, the previous one is still accepted for late shares
+func (s *Server) SetTemplate(result daemon.GetBlockTemplateResult) (err error) {
+	s.templateMutex.Lock()
+	defer s.templateMutex.Unlock()
+
+	s.mutex.Lock()
+	if len(s.templates) > 0 && s.templates[0].result.Template == result.Template {
+		s.mutex.Unlock()
+		return
+	}
+	s.mutex.Unlock()
+
+	minerWorkResult, err := s.daemon.GetMinerWork(daemon.GetMinerWorkParams{Template: result.Template})
+	if err != nil {
+		return
+	}
+
+	minerWork, err := work.DecodeHex(minerWorkResult.MinerWork)
+	if err != nil {
+		return
+	}
+
+	difficulty, err := work.ParseDifficulty(result.Difficulty)
+	if err != nil {
+		return
+	}
+
+	t := &template{
+		result:            result,
+		minerWork:         minerWork.Bytes(),
+		networkDifficulty: difficulty,
+		shares:            make(map[string]struct{}),
+	}
+
+	s.mutex.Lock()
+	s.templates = append([]*template{t}, s.templates...)
+	if len(s.templates) > 2 {
+		s.templates = s.templates[:2]
+	}
+
+	miners := make([]*miner, 0, len(s.miners))
+	for _, m := range s.miners {
+		miners = append(miners, m)
+	}
+	s.mutex.Unlock()
+
+	for _, m := range miners {
+		err := m.write(s.job(t, m))
+		if err != nil {
+			s.reportErr(err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) shareDifficulty(t *template) *big.Int {
+	difficulty := new(big.Int).SetUint64(s.options.ShareDifficulty)
+	if difficulty.Cmp(t.networkDifficulty) > 0 {
+		return t.networkDifficulty
+	}
+
+	return difficulty
+}
+
+// job is the template miner work with the extra nonce of the miner
+func (s *Server) job(t *template, m *miner) interface{} {
+	minerWork := append([]byte(nil), t.minerWork...)
+	binary.BigEndian.PutUint64(minerWork[work.ExtraNonceOffset:], m.id)
+
+	return map[string]getwork.MinerWork{getwork.NewJob: {
+		Algorithm:  t.result.Algorithm,
+		MinerWork:  hex.EncodeToString(minerWork),
+		Height:     t.result.Height,
+		Difficulty: s.shareDifficulty(t).String(),
+		TopoHeight: t.result.Topoheight,
+	}}
+}
+
+// ServeHTTP accepts miners at /getwork/{address}/{worker}
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "getwork" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	minerAddress, worker := parts[1], parts[2]
+	addr, err := address.NewAddressFromString(minerAddress)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.options.Network != "" && !addr.IsNetwork(s.options.Network) {
+		http.Error(w, address.ErrNetworkMismatch.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.serve(conn, minerAddress, worker)
+}
+
+func statsKey(minerAddress, worker string) string {
+	return minerAddress + "/" + worker
+}
+
+func (s *Server) serve(conn *websocket.Conn, minerAddress, worker string) {
+	s.mutex.Lock()
+	s.nextId++
+	m := &miner{id: s.nextId, address: minerAddress, worker: worker, conn: conn}
+	s.miners[m.id] = m
+
+	key := statsKey(minerAddress, worker)
+	stats, ok := s.stats[key]
+	if !ok {
+		stats = &WorkerStats{Address: minerAddress, Worker: worker, Work: new(big.Int)}
+		s.stats[key] = stats
+	}
+	stats.Connections++
+
+	var current *template
+	if len(s.templates) > 0 {
+		current = s.templates[0]
+	}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.miners, m.id)
+		stats.Connections--
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	if current != nil {
+		err := m.write(s.job(current, m))
+		if err != nil {
+			return
+		}
+	}
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var submit struct {
+			BlockTemplate string `json:"block_template"`
+			MinerWork     string `json:"miner_work"`
+		}
+
+		err = json.Unmarshal(msg, &submit)
+		if err != nil {
+			s.reportErr(err)
+			return
+		}
+
+		work := submit.BlockTemplate
+		if submit.MinerWork != "" {
+			work = submit.MinerWork
+		}
+
+		reason := s.submit(m, work)
+		if reason == "" {
+			err = m.write(getwork.BlockAccepted)
+		} else {
+			err = m.write(map[string]string{getwork.BlockRejected: reason})
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// submit validates a share and returns the rejection reason, empty if accepted
+func (s *Server) submit(m *miner, workHex string) (reason string) {
+	minerWork, err := hex.DecodeString(workHex)
+	if err != nil || len(minerWork) != work.Size {
+		s.count(m, func(stats *WorkerStats) { stats.InvalidShares++ })
+		return ReasonInvalidWork
+	}
+
+	s.mutex.Lock()
+	var t *template
+	for _, candidate := range s.templates {
+		if bytes.Equal(candidate.minerWork[:32], minerWork[:32]) {
+			t = candidate
+			break
+		}
+	}
+
+	if t == nil {
+		s.mutex.Unlock()
+		s.count(m, func(stats *WorkerStats) { stats.StaleShares++ })
+		return ReasonStaleJob
+	}
+
+	// the extra nonce is set by the pool and the reward goes to the pool key
+	if binary.BigEndian.Uint64(minerWork[work.ExtraNonceOffset:]) != m.id || !bytes.Equal(minerWork[work.MinerKeyOffset:], t.minerWork[work.MinerKeyOffset:]) {
+		s.mutex.Unlock()
+		s.count(m, func(stats *WorkerStats) { stats.InvalidShares++ })
+		return ReasonInvalidWork
+	}
+
+	// keyed by the bytes, the same work can be sent in another hex case
+	if _, ok := t.shares[string(minerWork)]; ok {
+		s.mutex.Unlock()
+		s.count(m, func(stats *WorkerStats) { stats.InvalidShares++ })
+		return ReasonDuplicate
+	}
+	t.shares[string(minerWork)] = struct{}{}
+	s.mutex.Unlock()
+
+	hash, err := s.options.Hasher(t.result.Algorithm, minerWork)
+	if err != nil {
+		s.reportErr(err)
+		s.count(m, func(stats *WorkerStats) { stats.InvalidShares++ })
+		return ReasonHashError
+	}
+
+	shareDifficulty := s.shareDifficulty(t)
+	if !work.MeetsDifficulty(hash, shareDifficulty) {
+		s.count(m, func(stats *WorkerStats) { stats.InvalidShares++ })
+		return ReasonLowDiff
+	}
+
+	block := work.MeetsDifficulty(hash, t.networkDifficulty)
+	if block {
+		minerWorkHex := hex.EncodeToString(minerWork)
+		_, err := s.daemon.SubmitBlock(daemon.SubmitBlockParams{BlockTemplate: t.result.Template, MinerWork: &minerWorkHex})
+		if err != nil {
+			// the share is still valid for the miner
+			s.reportErr(fmt.Errorf("block found by %s/%s rejected: %w", m.address, m.worker, err))
+			block = false
+		}
+	}
+
+	s.count(m, func(stats *WorkerStats) {
+		stats.ValidShares++
+		stats.Work.Add(stats.Work, shareDifficulty)
+		stats.LastShareAt = time.Now()
+		if block {
+			stats.Blocks++
+		}
+	})
+
+	return
+}
+
+func (s *Server) count(m *miner, update func(stats *WorkerStats)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	update(s.stats[statsKey(m.address, m.worker)])
+}
+
+// Stats returns a copy of the stats of every worker seen, sorted by address and worker
+func (s *Server) Stats() (stats []WorkerStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, worker := range s.stats {
+		copied := *worker
+		copied.Work = new(big.Int).Set(worker.Work)
+		stats = append(stats, copied)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Address != stats[j].Address {
+			return stats[i].Address < stats[j].Address
+		}
+
+		return stats[i].Worker < stats[j].Worker
+	})
+
+	return
+}
+
+func (s *Server) closeMiners() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, m := range s.miners {
+		m.conn.Close()
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/getwork"
+	"github.com/xelis-project/xelis-go-sdk/getwork/internal/testutil"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+)
+
+const POOL_ADDR = "xel:ys4peuzztwl67rzhsdu0yxfzwcfmgt85uu53hycpeeary7n8qvysqmxznt0"
+
+type fakeDaemon struct {
+	mutex      sync.Mutex
+	template   daemon.GetBlockTemplateResult
+	submitted  []daemon.SubmitBlockParams
+	minerWorks int
+}
+
+func (f *fakeDaemon) GetBlockTemplate(params daemon.GetBlockTemplateParams) (daemon.GetBlockTemplateResult, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.template, nil
+}
+
+// the work hash is the template repeated and the miner key is filled with 7
+func (f *fakeDaemon) GetMinerWork(params daemon.GetMinerWorkParams) (result daemon.GetMinerWorkResult, err error) {
+	f.mutex.Lock()
+	f.minerWorks++
+	f.mutex.Unlock()
+
+	minerWork := make([]byte, work.Size)
+	copy(minerWork, params.Template)
+	for i := work.MinerKeyOffset; i < work.Size; i++ {
+		minerWork[i] = 7
+	}
+
+	result.MinerWork = hex.EncodeToString(minerWork)
+	return
+}
+
+func (f *fakeDaemon) SubmitBlock(params daemon.SubmitBlockParams) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.submitted = append(f.submitted, params)
+	return true, nil
+}
+
+// nonce 1 finds a block, nonce 2 a share of difficulty 100 and any other nonce nothing
+func fakeHasher(algorithm daemon.AlgorithmVersion, minerWork []byte) (hash [32]byte, err error) {
+	switch binary.BigEndian.Uint64(minerWork[work.NonceOffset:]) {
+	case 1:
+	case 2:
+		target, _ := work.Target(big.NewInt(100))
+		target.FillBytes(hash[:])
+	default:
+		for i := range hash {
+			hash[i] = 0xff
+		}
+	}
+
+	return
+}
+
+func TestServer(t *testing.T) {
+	d := &fakeDaemon{template: daemon.GetBlockTemplateResult{Template: "template-1", Algorithm: daemon.AlgorithmV2, Height: 10, Difficulty: "1000000"}}
+	server, err := NewServer(d, Options{PoolAddress: POOL_ADDR, ShareDifficulty: 10, Hasher: fakeHasher})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Refresh()
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	endpoint := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/getwork"
+
+	res, err := http.Get(httpServer.URL + "/getwork/xel:invalid/rig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %d", res.StatusCode)
+	}
+
+	client, err := getwork.NewClient(endpoint, POOL_ADDR, "rig1", getwork.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Run(ctx)
+
+	job := testutil.Receive(t, client.Jobs())
+	if job.Height != 10 || job.Difficulty != "10" || job.Algorithm != daemon.AlgorithmV2 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	minerWork, err := hex.DecodeString(job.Template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if binary.BigEndian.Uint64(minerWork[work.ExtraNonceOffset:]) != 1 {
+		t.Fatalf("expected extra nonce of the first miner, got %x", minerWork[work.ExtraNonceOffset:work.MinerKeyOffset])
+	}
+
+	withNonce := func(nonce uint64) string {
+		w := append([]byte(nil), minerWork...)
+		binary.BigEndian.PutUint64(w[40:], nonce)
+		return hex.EncodeToString(w)
+	}
+
+	stale := append([]byte(nil), minerWork...)
+	stale[0] ^= 1
+
+	submissions := []struct {
+		work   string
+		reason string
+	}{
+		{withNonce(2), ""},
+		{withNonce(1), ""},
+		{withNonce(1), ReasonDuplicate},
+		{strings.ToUpper(withNonce(1)), ReasonDuplicate},
+		{withNonce(3), ReasonLowDiff},
+		{hex.EncodeToString(stale), ReasonStaleJob},
+		{"zz", ReasonInvalidWork},
+	}
+
+	for _, submission := range submissions {
+		err = client.Submit(job, submission.work)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := testutil.Receive(t, client.Results())
+		if result.Err != nil || result.Accepted != (submission.reason == "") || result.Reason != submission.reason {
+			t.Fatalf("expected %q, got %+v", submission.reason, result)
+		}
+	}
+
+	d.mutex.Lock()
+	if len(d.submitted) != 1 || d.submitted[0].BlockTemplate != "template-1" || *d.submitted[0].MinerWork != withNonce(1) {
+		t.Fatalf("unexpected submitted blocks %+v", d.submitted)
+	}
+
+	d.template = daemon.GetBlockTemplateResult{Template: "template-2", Algorithm: daemon.AlgorithmV2, Height: 11, Difficulty: "5"}
+	d.mutex.Unlock()
+
+	// share difficulty is capped to the network difficulty
+	server.Refresh()
+	job = testutil.Receive(t, client.Jobs())
+	if job.Height != 11 || job.Difficulty != "5" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	stats := server.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	s := stats[0]
+	if s.Worker != "rig1" || s.Connections != 1 || s.ValidShares != 2 || s.InvalidShares != 4 || s.StaleShares != 1 || s.Blocks != 1 || s.Work.Int64() != 20 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	d := &fakeDaemon{template: daemon.GetBlockTemplateResult{Template: "template-1", Algorithm: daemon.AlgorithmV2, Difficulty: "1000"}}
+	server, err := NewServer(d, Options{PoolAddress: POOL_ADDR, Hasher: fakeHasher})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the notifier and the ticker refresh the same template
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.Refresh()
+		}()
+	}
+	wg.Wait()
+
+	server.mutex.Lock()
+	templates := len(server.templates)
+	server.mutex.Unlock()
+
+	if templates != 1 || d.minerWorks != 1 {
+		t.Fatalf("expected a single template, got %d templates and %d miner works", templates, d.minerWorks)
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	_, err := NewServer(&fakeDaemon{}, Options{PoolAddress: POOL_ADDR})
+	if !errors.Is(err, ErrNoHasher) {
+		t.Fatalf("expected %s, got %v", ErrNoHasher, err)
+	}
+
+	_, err = NewServer(&fakeDaemon{}, Options{PoolAddress: "xel:invalid", Hasher: fakeHasher})
+	if err == nil {
+		t.Fatal("expected invalid pool address")
+	}
+}
@@ -0,0 +1,115 @@
+package work
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+)
+
+// BlockTime is the block time target of the network
+const BlockTime = 15 * time.Second
+
+var ErrInvalidDifficulty = errors.New("invalid difficulty")
+var ErrInvalidTarget = errors.New("invalid target")
+
+// MaxTarget is 2^256 - 1, the target of the difficulty 1
+var MaxTarget = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// ParseDifficulty parses the decimal difficulty of the node, it must be positive
+func ParseDifficulty(value string) (difficulty *big.Int, err error) {
+	difficulty, ok := new(big.Int).SetString(value, 10)
+	if !ok || difficulty.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidDifficulty, value)
+	}
+
+	return
+}
+
+// Target is the highest hash value meeting the difficulty
+func Target(difficulty *big.Int) (target *big.Int, err error) {
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		err = ErrInvalidDifficulty
+		return
+	}
+
+	target = new(big.Int).Div(MaxTarget, difficulty)
+	return
+}
+
+// Difficulty is the difficulty of the target, the inverse of Target
+func Difficulty(target *big.Int) (difficulty *big.Int, err error) {
+	if target == nil || target.Sign() <= 0 || target.Cmp(MaxTarget) > 0 {
+		err = ErrInvalidTarget
+		return
+	}
+
+	difficulty = new(big.Int).Div(MaxTarget, target)
+	return
+}
+
+// HashDifficulty is the highest difficulty met by the hash
+func HashDifficulty(hash [32]byte) *big.Int {
+	value := new(big.Int).SetBytes(hash[:])
+	if value.Sign() == 0 {
+		return new(big.Int).Set(MaxTarget)
+	}
+
+	return value.Div(MaxTarget, value)
+}
+
+// MeetsTarget is true if the hash read as a big endian number is not above the target
+func MeetsTarget(hash [32]byte, target *big.Int) bool {
+	return new(big.Int).SetBytes(hash[:]).Cmp(target) <= 0
+}
+
+func MeetsDifficulty(hash [32]byte, difficulty *big.Int) bool {
+	target, err := Target(difficulty)
+	if err != nil {
+		return false
+	}
+
+	return MeetsTarget(hash, target)
+}
+
+// ExpectedHashes is the average number of hashes needed to find a block
+func ExpectedHashes(result daemon.GetDifficultyResult) (hashes *big.Int, err error) {
+	return ParseDifficulty(result.Difficulty)
+}
+
+// NetworkHashrate is the hashes per second needed to find a block every BlockTime
+func NetworkHashrate(result daemon.GetDifficultyResult) (hashrate float64, err error) {
+	difficulty, err := ParseDifficulty(result.Difficulty)
+	if err != nil {
+		return
+	}
+
+	hashrate, _ = new(big.Float).SetInt(difficulty).Float64()
+	hashrate /= BlockTime.Seconds()
+	return
+}
+
+// ExpectedTime is the average time to find a block at the hashrate in hashes per second
+func ExpectedTime(result daemon.GetDifficultyResult, hashrate float64) (duration time.Duration, err error) {
+	if hashrate <= 0 {
+		err = fmt.Errorf("invalid hashrate %v", hashrate)
+		return
+	}
+
+	hashes, err := ExpectedHashes(result)
+	if err != nil {
+		return
+	}
+
+	seconds, _ := new(big.Float).SetInt(hashes).Float64()
+	seconds /= hashrate
+	if seconds >= float64(1<<63-1)/float64(time.Second) {
+		duration = time.Duration(1<<63 - 1)
+		return
+	}
+
+	duration = time.Duration(seconds * float64(time.Second))
+	return
+}
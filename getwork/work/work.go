@@ -0,0 +1,108 @@
+package work
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+)
+
+// miner work layout: work hash, timestamp, nonce, extra nonce, miner public key
+const (
+	WorkHashOffset   = 0
+	TimestampOffset  = 32
+	NonceOffset      = 40
+	ExtraNonceOffset = 48
+	MinerKeyOffset   = 80
+	Size             = 112
+)
+
+const ExtraNonceSize = MinerKeyOffset - ExtraNonceOffset
+
+var ErrInvalidSize = errors.New("invalid miner work size")
+var ErrExtraNonceTooLarge = errors.New("extra nonce is too large")
+
+// MinerWork is the header hashed by the miners, the timestamp is in milliseconds
+type MinerWork struct {
+	WorkHash   [32]byte
+	Timestamp  uint64
+	Nonce      uint64
+	ExtraNonce [ExtraNonceSize]byte
+	MinerKey   [32]byte
+}
+
+func Decode(data []byte) (work MinerWork, err error) {
+	if len(data) != Size {
+		err = fmt.Errorf("%w: %d", ErrInvalidSize, len(data))
+		return
+	}
+
+	copy(work.WorkHash[:], data[WorkHashOffset:TimestampOffset])
+	work.Timestamp = binary.BigEndian.Uint64(data[TimestampOffset:])
+	work.Nonce = binary.BigEndian.Uint64(data[NonceOffset:])
+	copy(work.ExtraNonce[:], data[ExtraNonceOffset:MinerKeyOffset])
+	copy(work.MinerKey[:], data[MinerKeyOffset:])
+	return
+}
+
+// DecodeHex decodes BlockTemplate.Template of getwork jobs or GetMinerWorkResult.MinerWork
+func DecodeHex(value string) (work MinerWork, err error) {
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		return
+	}
+
+	return Decode(data)
+}
+
+func (w *MinerWork) Bytes() []byte {
+	data := make([]byte, Size)
+	w.Write(data)
+	return data
+}
+
+// Write encodes the work in data without allocating, data must be at least Size bytes
+func (w *MinerWork) Write(data []byte) {
+	copy(data[WorkHashOffset:], w.WorkHash[:])
+	binary.BigEndian.PutUint64(data[TimestampOffset:], w.Timestamp)
+	binary.BigEndian.PutUint64(data[NonceOffset:], w.Nonce)
+	copy(data[ExtraNonceOffset:], w.ExtraNonce[:])
+	copy(data[MinerKeyOffset:], w.MinerKey[:])
+}
+
+// String is the hex encoding expected by SubmitBlockParams.MinerWork and getwork submissions
+func (w *MinerWork) String() string {
+	return hex.EncodeToString(w.Bytes())
+}
+
+func (w *MinerWork) SetNonce(nonce uint64) {
+	w.Nonce = nonce
+}
+
+func (w *MinerWork) SetTimestamp(timestamp time.Time) {
+	w.Timestamp = uint64(timestamp.UnixMilli())
+}
+
+func (w *MinerWork) Time() time.Time {
+	return time.UnixMilli(int64(w.Timestamp))
+}
+
+// SetExtraNonce copies extraNonce at the start of the extra nonce, the remaining bytes are unchanged
+func (w *MinerWork) SetExtraNonce(extraNonce []byte) (err error) {
+	if len(extraNonce) > ExtraNonceSize {
+		err = fmt.Errorf("%w: %d bytes", ErrExtraNonceTooLarge, len(extraNonce))
+		return
+	}
+
+	copy(w.ExtraNonce[:], extraNonce)
+	return
+}
+
+// SubmitParams submits the work found for the block template returned by GetBlockTemplate
+func (w *MinerWork) SubmitParams(blockTemplate string) daemon.SubmitBlockParams {
+	minerWork := w.String()
+	return daemon.SubmitBlockParams{BlockTemplate: blockTemplate, MinerWork: &minerWork}
+}
@@ -0,0 +1,134 @@
+package work
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+)
+
+func TestMinerWork(t *testing.T) {
+	data := make([]byte, Size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	w, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.WorkHash[0] != 0 || w.Timestamp != 0x2021222324252627 || w.Nonce != 0x28292a2b2c2d2e2f || w.ExtraNonce[0] != 48 || w.MinerKey[31] != 111 {
+		t.Fatalf("unexpected decoding %+v", w)
+	}
+
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Fatal("encoding must be the inverse of decoding")
+	}
+
+	now := time.UnixMilli(1700000000123)
+	w.SetNonce(42)
+	w.SetTimestamp(now)
+	err = w.SetExtraNonce([]byte{0xff, 0xfe})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeHex(w.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != w || decoded.Nonce != 42 || !decoded.Time().Equal(now) || decoded.ExtraNonce[0] != 0xff || decoded.ExtraNonce[2] != 50 {
+		t.Fatalf("unexpected work %+v", decoded)
+	}
+
+	params := w.SubmitParams("template")
+	if params.BlockTemplate != "template" || params.MinerWork == nil || *params.MinerWork != w.String() {
+		t.Fatalf("unexpected params %+v", params)
+	}
+
+	err = w.SetExtraNonce(make([]byte, ExtraNonceSize+1))
+	if !errors.Is(err, ErrExtraNonceTooLarge) {
+		t.Fatalf("expected ErrExtraNonceTooLarge, got %v", err)
+	}
+
+	_, err = Decode(data[1:])
+	if !errors.Is(err, ErrInvalidSize) {
+		t.Fatalf("expected ErrInvalidSize, got %v", err)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	_, err := ParseDifficulty("0")
+	if !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("expected ErrInvalidDifficulty, got %v", err)
+	}
+
+	difficulty, err := ParseDifficulty("1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := Target(difficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := Difficulty(target)
+	if err != nil || back.Cmp(difficulty) != 0 {
+		t.Fatalf("expected %s, got %s (%v)", difficulty, back, err)
+	}
+
+	var hash [32]byte
+	target.FillBytes(hash[:])
+	if !MeetsTarget(hash, target) || !MeetsDifficulty(hash, difficulty) || HashDifficulty(hash).Cmp(difficulty) != 0 {
+		t.Fatal("a hash equal to the target must meet it")
+	}
+
+	new(big.Int).Add(target, big.NewInt(1)).FillBytes(hash[:])
+	if MeetsTarget(hash, target) || MeetsDifficulty(hash, difficulty) {
+		t.Fatal("a hash above the target must not meet it")
+	}
+
+	if !MeetsDifficulty([32]byte{}, difficulty) || HashDifficulty([32]byte{}).Cmp(MaxTarget) != 0 {
+		t.Fatal("a zero hash meets any difficulty")
+	}
+
+	_, err = Difficulty(big.NewInt(0))
+	if !errors.Is(err, ErrInvalidTarget) {
+		t.Fatalf("expected ErrInvalidTarget, got %v", err)
+	}
+}
+
+func TestHashrate(t *testing.T) {
+	result := daemon.GetDifficultyResult{Difficulty: "1500000"}
+
+	hashes, err := ExpectedHashes(result)
+	if err != nil || hashes.Int64() != 1500000 {
+		t.Fatalf("unexpected expected hashes %v (%v)", hashes, err)
+	}
+
+	hashrate, err := NetworkHashrate(result)
+	if err != nil || hashrate != 100000 {
+		t.Fatalf("unexpected hashrate %v (%v)", hashrate, err)
+	}
+
+	duration, err := ExpectedTime(result, 1000)
+	if err != nil || duration != 1500*time.Second {
+		t.Fatalf("unexpected expected time %v (%v)", duration, err)
+	}
+
+	_, err = ExpectedTime(result, 0)
+	if err == nil {
+		t.Fatal("expected an error for a zero hashrate")
+	}
+
+	_, err = NetworkHashrate(daemon.GetDifficultyResult{Difficulty: "abc"})
+	if !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("expected ErrInvalidDifficulty, got %v", err)
+	}
+}
@@ -4,9 +4,13 @@ go 1.19
 
 require github.com/gtank/ristretto255 v0.1.2
 
-require golang.org/x/sys v0.29.0 // indirect
+require (
+	github.com/klauspost/cpuid/v2 v2.0.12 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+)
 
 require (
 	github.com/gorilla/websocket v1.5.0
+	github.com/zeebo/blake3 v0.2.4
 	golang.org/x/crypto v0.32.0
 )
@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"math/big"
+	"time"
+)
+
+// Hashrate is the hashes per second needed to mine the blocks in the time between the first and the last one,
+// the work of the first block was done before its timestamp so it is not counted.
+// Orphaned blocks are counted as their work was done.
+func Hashrate(blocks []BlockInfo) float64 {
+	if len(blocks) < 2 {
+		return 0
+	}
+
+	first, last := blocks[0].Timestamp, blocks[0].Timestamp
+	firstIndex := 0
+	for i, block := range blocks {
+		if block.Timestamp < first {
+			first = block.Timestamp
+			firstIndex = i
+		}
+
+		if block.Timestamp > last {
+			last = block.Timestamp
+		}
+	}
+
+	if last == first {
+		return 0
+	}
+
+	work := new(big.Int)
+	for i, block := range blocks {
+		if i != firstIndex {
+			work.Add(work, block.Difficulty)
+		}
+	}
+
+	hashes, _ := new(big.Float).SetInt(work).Float64()
+	return hashes / (float64(last-first) / 1000)
+}
+
+// MinerHashrate is the hashrate of the address estimated from the difficulty of its blocks found in the window
+// before now, the estimate is only meaningful once the miner found enough blocks
+func (t *Tracker) MinerHashrate(address string, window time.Duration, now time.Time) float64 {
+	if window <= 0 {
+		return 0
+	}
+
+	since := uint64(now.Add(-window).UnixMilli())
+	work := new(big.Int)
+
+	t.mutex.Lock()
+	for _, block := range t.blocks {
+		if block.Miner == address && block.Timestamp >= since {
+			work.Add(work, block.Difficulty)
+		}
+	}
+	t.mutex.Unlock()
+
+	hashes, _ := new(big.Float).SetInt(work).Float64()
+	return hashes / window.Seconds()
+}
+
+// Luck is the expected work of the blocks divided by the work done to find them,
+// above 1 the blocks were found faster than expected, 0 without work
+func Luck(blocks []BlockInfo, work *big.Int) float64 {
+	if work == nil || work.Sign() <= 0 {
+		return 0
+	}
+
+	expected := new(big.Int)
+	for _, block := range blocks {
+		if block.Difficulty != nil {
+			expected.Add(expected, block.Difficulty)
+		}
+	}
+
+	luck, _ := new(big.Rat).SetFrac(expected, work).Float64()
+	return luck
+}
@@ -0,0 +1,205 @@
+package stats
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// MaxFeeBasisPoints is a fee of 100%
+const MaxFeeBasisPoints = 10000
+
+var ErrNoShares = errors.New("no shares to reward")
+var ErrInvalidFee = errors.New("fee is above 10000 basis points")
+var ErrInvalidDifficulty = errors.New("network difficulty must be positive")
+
+// Share is a valid share accepted by the pool
+type Share struct {
+	Miner      string    `json:"miner"`
+	Difficulty uint64    `json:"difficulty"`
+	Time       time.Time `json:"time"`
+}
+
+// Rewards are the amounts credited to each miner, Fee is kept by the pool with the rounding leftovers
+type Rewards struct {
+	Fee    uint64            `json:"fee"`
+	Miners map[string]uint64 `json:"miners"`
+}
+
+// split shares the reward minus the fee in proportion to the work of each miner
+func split(reward uint64, feeBasisPoints uint64, work map[string]*big.Int) (rewards Rewards, err error) {
+	if feeBasisPoints > MaxFeeBasisPoints {
+		err = ErrInvalidFee
+		return
+	}
+
+	total := new(big.Int)
+	for _, w := range work {
+		total.Add(total, w)
+	}
+
+	if total.Sign() == 0 {
+		err = ErrNoShares
+		return
+	}
+
+	fee := new(big.Int).SetUint64(reward)
+	fee.Mul(fee, new(big.Int).SetUint64(feeBasisPoints))
+	fee.Div(fee, big.NewInt(MaxFeeBasisPoints))
+	distributed := reward - fee.Uint64()
+
+	rewards.Miners = make(map[string]uint64, len(work))
+	paid := uint64(0)
+	for miner, w := range work {
+		amount := new(big.Int).SetUint64(distributed)
+		amount.Mul(amount, w)
+		amount.Div(amount, total)
+
+		rewards.Miners[miner] = amount.Uint64()
+		paid += amount.Uint64()
+	}
+
+	rewards.Fee = reward - paid
+	return
+}
+
+// PPLNS pays a block reward to the last shares whose difficulty sum reaches Window,
+// the oldest share is only counted for its part inside the window
+type PPLNS struct {
+	// difficulty sum of the rewarded shares, all the shares if 0
+	Window uint64
+	// FeeBasisPoints of the reward kept by the pool, 100 is 1%
+	FeeBasisPoints uint64
+}
+
+// Split rewards the shares ordered from the oldest to the newest
+func (p PPLNS) Split(reward uint64, shares []Share) (rewards Rewards, err error) {
+	work := make(map[string]*big.Int)
+	counted := uint64(0)
+	for i := len(shares) - 1; i >= 0; i-- {
+		difficulty := shares[i].Difficulty
+		if p.Window > 0 {
+			if counted >= p.Window {
+				break
+			}
+
+			if difficulty > p.Window-counted {
+				difficulty = p.Window - counted
+			}
+		}
+		counted += difficulty
+
+		w, ok := work[shares[i].Miner]
+		if !ok {
+			w = new(big.Int)
+			work[shares[i].Miner] = w
+		}
+
+		w.Add(w, new(big.Int).SetUint64(difficulty))
+	}
+
+	return split(reward, p.FeeBasisPoints, work)
+}
+
+// PPS pays each share its expected value, the block reward times the share difficulty over the network difficulty
+type PPS struct {
+	// FeeBasisPoints of the value kept by the pool, 100 is 1%
+	FeeBasisPoints uint64
+}
+
+// ShareValue is the amount credited for a share of the difficulty
+func (p PPS) ShareValue(difficulty uint64, networkDifficulty *big.Int, blockReward uint64) (value uint64, err error) {
+	credits, err := p.Credit([]Share{{Difficulty: difficulty}}, networkDifficulty, blockReward)
+	if err != nil {
+		return
+	}
+
+	return credits[""], nil
+}
+
+// Credit returns the amount owed to each miner for the shares,
+// the shares of a miner are summed before rounding down
+func (p PPS) Credit(shares []Share, networkDifficulty *big.Int, blockReward uint64) (credits map[string]uint64, err error) {
+	if p.FeeBasisPoints > MaxFeeBasisPoints {
+		err = ErrInvalidFee
+		return
+	}
+
+	if networkDifficulty == nil || networkDifficulty.Sign() <= 0 {
+		err = ErrInvalidDifficulty
+		return
+	}
+
+	work := make(map[string]*big.Int)
+	for _, share := range shares {
+		w, ok := work[share.Miner]
+		if !ok {
+			w = new(big.Int)
+			work[share.Miner] = w
+		}
+
+		w.Add(w, new(big.Int).SetUint64(share.Difficulty))
+	}
+
+	// reward * (10000 - fee) * work / (difficulty * 10000)
+	numerator := new(big.Int).SetUint64(blockReward)
+	numerator.Mul(numerator, new(big.Int).SetUint64(MaxFeeBasisPoints-p.FeeBasisPoints))
+	denominator := new(big.Int).Mul(networkDifficulty, big.NewInt(MaxFeeBasisPoints))
+
+	credits = make(map[string]uint64, len(work))
+	for miner, w := range work {
+		amount := new(big.Int).Mul(numerator, w)
+		amount.Div(amount, denominator)
+		credits[miner] = amount.Uint64()
+	}
+
+	return
+}
+
+// ShareWindow keeps the newest shares with a difficulty sum of at least the window,
+// older shares are dropped as they can't be rewarded by PPLNS anymore
+type ShareWindow struct {
+	window uint64
+
+	mutex  sync.Mutex
+	shares []Share
+	work   uint64
+}
+
+func NewShareWindow(window uint64) *ShareWindow {
+	return &ShareWindow{window: window}
+}
+
+func (w *ShareWindow) Add(share Share) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.shares = append(w.shares, share)
+	w.work += share.Difficulty
+
+	// drop the oldest shares while the others still fill the window
+	drop := 0
+	for drop < len(w.shares)-1 && w.work-w.shares[drop].Difficulty >= w.window {
+		w.work -= w.shares[drop].Difficulty
+		drop++
+	}
+
+	w.shares = w.shares[drop:]
+}
+
+// Shares returns a copy of the shares from the oldest to the newest
+func (w *ShareWindow) Shares() []Share {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return append([]Share(nil), w.shares...)
+}
+
+// Work is the difficulty sum of the shares in the window
+func (w *ShareWindow) Work() uint64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.work
+}
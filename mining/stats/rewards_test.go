@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPPLNS(t *testing.T) {
+	shares := []Share{
+		{Miner: "a", Difficulty: 100},
+		{Miner: "b", Difficulty: 100},
+		{Miner: "a", Difficulty: 100},
+		{Miner: "c", Difficulty: 200},
+	}
+
+	// c 200, a 100 and b only 50 in the window
+	rewards, err := PPLNS{Window: 350, FeeBasisPoints: 100}.Split(1000, shares)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 990 distributed, 565.71 + 282.85 + 141.42 rounded down
+	if rewards.Miners["c"] != 565 || rewards.Miners["a"] != 282 || rewards.Miners["b"] != 141 || rewards.Fee != 12 {
+		t.Fatalf("unexpected rewards %+v", rewards)
+	}
+
+	rewards, err = PPLNS{}.Split(500, shares)
+	if err != nil || rewards.Miners["a"] != 200 || rewards.Miners["b"] != 100 || rewards.Miners["c"] != 200 || rewards.Fee != 0 {
+		t.Fatalf("unexpected rewards %+v (%v)", rewards, err)
+	}
+
+	_, err = PPLNS{}.Split(500, nil)
+	if !errors.Is(err, ErrNoShares) {
+		t.Fatalf("expected ErrNoShares, got %v", err)
+	}
+
+	_, err = PPLNS{FeeBasisPoints: MaxFeeBasisPoints + 1}.Split(500, shares)
+	if !errors.Is(err, ErrInvalidFee) {
+		t.Fatalf("expected ErrInvalidFee, got %v", err)
+	}
+}
+
+func TestPPS(t *testing.T) {
+	pps := PPS{FeeBasisPoints: 200}
+	value, err := pps.ShareValue(1000, big.NewInt(1000000), 100000000)
+	if err != nil || value != 98000 {
+		t.Fatalf("unexpected share value %d (%v)", value, err)
+	}
+
+	credits, err := pps.Credit([]Share{{Miner: "a", Difficulty: 1}, {Miner: "a", Difficulty: 1}, {Miner: "b", Difficulty: 1}}, big.NewInt(3), 100)
+	if err != nil || credits["a"] != 65 || credits["b"] != 32 {
+		t.Fatalf("unexpected credits %+v (%v)", credits, err)
+	}
+
+	_, err = pps.Credit(nil, big.NewInt(0), 100)
+	if !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("expected ErrInvalidDifficulty, got %v", err)
+	}
+}
+
+func TestShareWindow(t *testing.T) {
+	window := NewShareWindow(250)
+	for _, miner := range []string{"a", "b", "c", "d"} {
+		window.Add(Share{Miner: miner, Difficulty: 100})
+	}
+
+	// b is still needed to fill the window
+	shares := window.Shares()
+	if len(shares) != 3 || shares[0].Miner != "b" || window.Work() != 300 {
+		t.Fatalf("unexpected window %+v", shares)
+	}
+
+	rewards, err := PPLNS{Window: 250}.Split(500, shares)
+	if err != nil || rewards.Miners["b"] != 100 || rewards.Miners["d"] != 200 {
+		t.Fatalf("unexpected rewards %+v (%v)", rewards, err)
+	}
+}
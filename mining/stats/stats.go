@@ -0,0 +1,307 @@
+package stats
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+)
+
+// MaxBlocksRange is the number of blocks returned by the node per range request
+const MaxBlocksRange = 20
+
+type BlockInfo struct {
+	Hash       string           `json:"hash"`
+	Miner      string           `json:"miner"`
+	Height     uint64           `json:"height"`
+	Topoheight *uint64          `json:"topoheight,omitempty"`
+	Type       daemon.BlockType `json:"block_type"`
+	Difficulty *big.Int         `json:"difficulty"`
+	// block timestamp in milliseconds
+	Timestamp   uint64 `json:"timestamp"`
+	Reward      uint64 `json:"reward"`
+	MinerReward uint64 `json:"miner_reward"`
+	DevReward   uint64 `json:"dev_reward"`
+}
+
+func (b *BlockInfo) Time() time.Time {
+	return time.UnixMilli(int64(b.Timestamp))
+}
+
+// Orphaned blocks are not in the DAG order anymore and give no reward
+func (b *BlockInfo) Orphaned() bool {
+	return b.Type == daemon.BlockOrphaned
+}
+
+type MinerStats struct {
+	Address  string `json:"address"`
+	Blocks   uint64 `json:"blocks"`
+	Normal   uint64 `json:"normal"`
+	Sync     uint64 `json:"sync"`
+	Side     uint64 `json:"side"`
+	Orphaned uint64 `json:"orphaned"`
+	// miner rewards of the blocks that are not orphaned
+	Rewards uint64 `json:"rewards"`
+	// sum of the difficulty of the blocks that are not orphaned
+	Work *big.Int `json:"work"`
+}
+
+func (s *MinerStats) OrphanRate() float64 {
+	if s.Blocks == 0 {
+		return 0
+	}
+
+	return float64(s.Orphaned) / float64(s.Blocks)
+}
+
+func (s *MinerStats) SideRate() float64 {
+	if s.Blocks == 0 {
+		return 0
+	}
+
+	return float64(s.Side) / float64(s.Blocks)
+}
+
+func (s *MinerStats) add(block *BlockInfo) {
+	s.Blocks++
+	switch block.Type {
+	case daemon.BlockNormal:
+		s.Normal++
+	case daemon.BlockSync:
+		s.Sync++
+	case daemon.BlockSide:
+		s.Side++
+	case daemon.BlockOrphaned:
+		s.Orphaned++
+	}
+
+	if !block.Orphaned() {
+		s.Rewards += block.MinerReward
+		s.Work.Add(s.Work, block.Difficulty)
+	}
+}
+
+// Tracker attributes blocks to their miner and follows their type until they are stable
+type Tracker struct {
+	mutex  sync.Mutex
+	blocks map[string]*BlockInfo
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{blocks: make(map[string]*BlockInfo)}
+}
+
+// NewTrackerWS creates a tracker adding blocks on NewBlock events
+// and updating them on BlockOrdered and BlockOrphaned events.
+// onError is called with the event errors and the blocks that can't be added, it can be nil.
+func NewTrackerWS(ws *daemon.WebSocket, onError func(error)) (tracker *Tracker, err error) {
+	tracker = NewTracker()
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	err = ws.NewBlockFunc(func(block daemon.Block, err error) {
+		if err == nil {
+			err = tracker.AddBlock(block)
+		}
+
+		report(err)
+	})
+	if err != nil {
+		return
+	}
+
+	err = ws.BlockOrderedFunc(func(event daemon.BlockOrderedEvent, err error) {
+		if err == nil {
+			tracker.BlockOrdered(event)
+		}
+
+		report(err)
+	})
+	if err != nil {
+		return
+	}
+
+	err = ws.BlockOrphanedFunc(func(event daemon.BlockOrphanedEvent, err error) {
+		if err == nil {
+			tracker.BlockOrphaned(event)
+		}
+
+		report(err)
+	})
+	return
+}
+
+func optional(value *uint64) uint64 {
+	if value == nil {
+		return 0
+	}
+
+	return *value
+}
+
+// AddBlock adds or replaces the block with the same hash
+func (t *Tracker) AddBlock(block daemon.Block) (err error) {
+	difficulty, ok := new(big.Int).SetString(block.Difficulty, 10)
+	if !ok {
+		return fmt.Errorf("invalid difficulty %q of block %s", block.Difficulty, block.Hash)
+	}
+
+	info := &BlockInfo{
+		Hash:        block.Hash,
+		Miner:       block.Miner,
+		Height:      block.Height,
+		Topoheight:  block.Topoheight,
+		Type:        block.BlockType,
+		Difficulty:  difficulty,
+		Timestamp:   block.Timestamp,
+		Reward:      optional(block.Reward),
+		MinerReward: optional(block.MinerReward),
+		DevReward:   optional(block.DevReward),
+	}
+
+	t.mutex.Lock()
+	t.blocks[block.Hash] = info
+	t.mutex.Unlock()
+	return
+}
+
+func (t *Tracker) AddBlocks(blocks []daemon.Block) (err error) {
+	for _, block := range blocks {
+		err = t.AddBlock(block)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// BlocksClient is implemented by daemon.RPC and daemon.WebSocket
+type BlocksClient interface {
+	GetBlocksRangeByTopoheight(params daemon.GetTopoheightRangeParams) ([]daemon.Block, error)
+}
+
+// Sync adds the blocks from start to end topoheight included, MaxBlocksRange at a time.
+// Blocks already known are refreshed so syncing again the unstable topoheights updates their type.
+func (t *Tracker) Sync(client BlocksClient, start, end uint64) (err error) {
+	for start <= end {
+		last := start + MaxBlocksRange - 1
+		if last > end || last < start {
+			last = end
+		}
+
+		from, to := start, last
+		blocks, err := client.GetBlocksRangeByTopoheight(daemon.GetTopoheightRangeParams{StartTopoheight: &from, EndTopoheight: &to})
+		if err != nil {
+			return err
+		}
+
+		err = t.AddBlocks(blocks)
+		if err != nil || last == end {
+			return err
+		}
+
+		start = last + 1
+	}
+
+	return
+}
+
+// BlockOrdered updates the type and topoheight of a known block
+func (t *Tracker) BlockOrdered(event daemon.BlockOrderedEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if block, ok := t.blocks[event.BlockHash]; ok {
+		topoheight := event.Topoheight
+		block.Topoheight = &topoheight
+		block.Type = event.BlockType
+	}
+}
+
+// BlockOrphaned marks a known block as orphaned
+func (t *Tracker) BlockOrphaned(event daemon.BlockOrphanedEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if block, ok := t.blocks[event.BlockHash]; ok {
+		block.Topoheight = nil
+		block.Type = daemon.BlockOrphaned
+	}
+}
+
+// Blocks returns a copy of the blocks sorted by height then hash
+func (t *Tracker) Blocks() (blocks []BlockInfo) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, block := range t.blocks {
+		blocks = append(blocks, *block)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].Height != blocks[j].Height {
+			return blocks[i].Height < blocks[j].Height
+		}
+
+		return blocks[i].Hash < blocks[j].Hash
+	})
+	return
+}
+
+// Miners returns the stats of each miner sorted by address
+func (t *Tracker) Miners() (miners []MinerStats) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stats := make(map[string]*MinerStats)
+	for _, block := range t.blocks {
+		s, ok := stats[block.Miner]
+		if !ok {
+			s = &MinerStats{Address: block.Miner, Work: new(big.Int)}
+			stats[block.Miner] = s
+		}
+
+		s.add(block)
+	}
+
+	for _, s := range stats {
+		miners = append(miners, *s)
+	}
+
+	sort.Slice(miners, func(i, j int) bool { return miners[i].Address < miners[j].Address })
+	return
+}
+
+// Miner returns the stats of the blocks found by the address
+func (t *Tracker) Miner(address string) (stats MinerStats) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stats = MinerStats{Address: address, Work: new(big.Int)}
+	for _, block := range t.blocks {
+		if block.Miner == address {
+			stats.add(block)
+		}
+	}
+
+	return
+}
+
+// Prune forgets the blocks below the height
+func (t *Tracker) Prune(height uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for hash, block := range t.blocks {
+		if block.Height < height {
+			delete(t.blocks, hash)
+		}
+	}
+}
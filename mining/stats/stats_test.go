@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+)
+
+func uint64Ptr(value uint64) *uint64 {
+	return &value
+}
+
+// one block per second, miner a finds 2 blocks out of 3
+type fakeClient struct {
+	requests int
+}
+
+func testBlock(topoheight uint64) daemon.Block {
+	miner := "a"
+	if topoheight%3 == 2 {
+		miner = "b"
+	}
+
+	return daemon.Block{
+		Hash:        fmt.Sprintf("hash%d", topoheight),
+		Topoheight:  uint64Ptr(topoheight),
+		Height:      topoheight,
+		BlockType:   daemon.BlockNormal,
+		Difficulty:  "1000",
+		Timestamp:   1700000000000 + topoheight*1000,
+		Reward:      uint64Ptr(110),
+		MinerReward: uint64Ptr(100),
+		DevReward:   uint64Ptr(10),
+		Miner:       miner,
+	}
+}
+
+func (f *fakeClient) GetBlocksRangeByTopoheight(params daemon.GetTopoheightRangeParams) (blocks []daemon.Block, err error) {
+	f.requests++
+	if *params.EndTopoheight-*params.StartTopoheight >= MaxBlocksRange {
+		return nil, fmt.Errorf("range too large")
+	}
+
+	for topoheight := *params.StartTopoheight; topoheight <= *params.EndTopoheight; topoheight++ {
+		blocks = append(blocks, testBlock(topoheight))
+	}
+
+	return
+}
+
+func TestTracker(t *testing.T) {
+	client := &fakeClient{}
+	tracker := NewTracker()
+	err := tracker.Sync(client, 0, 44)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.requests != 3 || len(tracker.Blocks()) != 45 {
+		t.Fatalf("expected 45 blocks in 3 requests, got %d in %d", len(tracker.Blocks()), client.requests)
+	}
+
+	tracker.BlockOrdered(daemon.BlockOrderedEvent{BlockHash: "hash0", BlockType: daemon.BlockSide, Topoheight: 0})
+	tracker.BlockOrphaned(daemon.BlockOrphanedEvent{BlockHash: "hash3", OldTopoheight: 3})
+	tracker.BlockOrphaned(daemon.BlockOrphanedEvent{BlockHash: "unknown"})
+
+	miners := tracker.Miners()
+	if len(miners) != 2 || miners[0].Address != "a" || miners[1].Address != "b" {
+		t.Fatalf("unexpected miners %+v", miners)
+	}
+
+	a := tracker.Miner("a")
+	if a.Blocks != 30 || a.Normal != 28 || a.Side != 1 || a.Orphaned != 1 || a.Rewards != 29*100 || a.Work.Int64() != 29*1000 {
+		t.Fatalf("unexpected stats %+v", a)
+	}
+
+	if a.OrphanRate() != 1.0/30 || a.SideRate() != 1.0/30 {
+		t.Fatalf("unexpected rates %v %v", a.OrphanRate(), a.SideRate())
+	}
+
+	if b := tracker.Miner("b"); b.Blocks != 15 || b.Rewards != 1500 || b.OrphanRate() != 0 {
+		t.Fatalf("unexpected stats %+v", b)
+	}
+
+	// 44 blocks of difficulty 1000 in 44 seconds
+	if hashrate := Hashrate(tracker.Blocks()); hashrate != 1000 {
+		t.Fatalf("unexpected hashrate %v", hashrate)
+	}
+
+	// the blocks 35 to 44 are in the window, 6 found by a
+	now := time.UnixMilli(1700000000000 + 44*1000)
+	if hashrate := tracker.MinerHashrate("a", 9*time.Second, now); math.Abs(hashrate-6000.0/9) > 1e-9 {
+		t.Fatalf("unexpected miner hashrate %v", hashrate)
+	}
+
+	if luck := Luck(tracker.Blocks()[:2], big.NewInt(4000)); luck != 0.5 {
+		t.Fatalf("unexpected luck %v", luck)
+	}
+
+	if Luck(tracker.Blocks(), nil) != 0 || Luck(tracker.Blocks(), big.NewInt(0)) != 0 {
+		t.Fatal("expected no luck without work")
+	}
+
+	tracker.Prune(40)
+	if blocks := tracker.Blocks(); len(blocks) != 5 || blocks[0].Height != 40 {
+		t.Fatalf("unexpected blocks after prune %+v", blocks)
+	}
+
+	err = tracker.AddBlock(daemon.Block{Hash: "bad", Difficulty: "abc"})
+	if err == nil {
+		t.Fatal("expected invalid difficulty error")
+	}
+}
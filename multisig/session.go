@@ -0,0 +1,333 @@
+package multisig
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/signature"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+// SessionVersion is bumped when the serialized format changes
+const SessionVersion = 1
+
+var ErrNoMultisig = errors.New("account has no active multisig")
+var ErrMissingUnsigned = errors.New("unsigned transaction is missing, build it with tx_as_hex enabled")
+var ErrUnknownSigner = errors.New("signer id is not a participant of the multisig")
+var ErrInvalidSignature = errors.New("invalid signature for the unsigned transaction hash")
+var ErrDuplicateSignature = errors.New("signer already signed with a different signature")
+var ErrThresholdNotReached = errors.New("multisig threshold not reached")
+var ErrUnsupportedVersion = errors.New("unsupported session version")
+
+// Daemon is the subset of daemon.RPC and daemon.WebSocket used to load the multisig setup
+type Daemon interface {
+	GetMultisig(params daemon.GetMultisigParams) (daemon.GetMultisigResult, error)
+}
+
+// Signer is the subset of wallet.RPC and wallet.WebSocket used by a participant to sign
+type Signer interface {
+	SignUnsignedTransaction(params wallet.SignUnsignedTransactionParams) (wallet.SignatureId, error)
+}
+
+// Finalizer is the subset of wallet.RPC and wallet.WebSocket of the multisig owner
+type Finalizer interface {
+	FinalizeUnsignedTransaction(params wallet.FinalizeUnsignedTransactionParams) (wallet.TransactionResponse, error)
+}
+
+// Session holds everything needed to gather M-of-N signatures for an unsigned transaction.
+// It can be passed around between participants as a file or a string.
+type Session struct {
+	Version uint8 `json:"version"`
+	// Address of the multisig account
+	Address string `json:"address"`
+	// Participants in the order registered on chain, the signer id is the index in this list
+	Participants []string `json:"participants"`
+	Threshold    uint8    `json:"threshold"`
+	// Hash signed by each participant
+	Hash string `json:"hash"`
+	// Unsigned transaction as hex
+	Unsigned   string               `json:"unsigned"`
+	Signatures []wallet.SignatureId `json:"signatures"`
+}
+
+// NewSession creates a session from the multisig state of the account and the unsigned transaction built by its wallet
+func NewSession(addr string, state daemon.MultisigState, unsigned wallet.UnsignedTransactionResponse) (session *Session, err error) {
+	if state.Deleted || state.Active == nil {
+		err = ErrNoMultisig
+		return
+	}
+
+	if unsigned.TxAsHex == nil || *unsigned.TxAsHex == "" {
+		err = ErrMissingUnsigned
+		return
+	}
+
+	session = &Session{
+		Version:      SessionVersion,
+		Address:      addr,
+		Participants: state.Active.Participants,
+		Threshold:    state.Active.Threshold,
+		Hash:         unsigned.Hash,
+		Unsigned:     *unsigned.TxAsHex,
+		Signatures:   []wallet.SignatureId{},
+	}
+
+	err = session.validate()
+	if err != nil {
+		session = nil
+	}
+
+	return
+}
+
+// FetchSession loads the participants and threshold of the account from the daemon
+func FetchSession(node Daemon, addr string, unsigned wallet.UnsignedTransactionResponse) (session *Session, err error) {
+	result, err := node.GetMultisig(daemon.GetMultisigParams{Address: addr})
+	if err != nil {
+		return
+	}
+
+	return NewSession(addr, result.State, unsigned)
+}
+
+func (s *Session) validate() (err error) {
+	if s.Version != SessionVersion {
+		return ErrUnsupportedVersion
+	}
+
+	if s.Threshold == 0 || int(s.Threshold) > len(s.Participants) {
+		return wallet.ErrInvalidThreshold
+	}
+
+	if s.Unsigned == "" {
+		return ErrMissingUnsigned
+	}
+
+	unsigned, err := hex.DecodeString(s.Unsigned)
+	if err != nil {
+		return
+	}
+
+	err = VerifyHash(s.Hash, unsigned)
+	if err != nil {
+		return
+	}
+
+	for _, participant := range s.Participants {
+		_, err = address.NewAddressFromString(participant)
+		if err != nil {
+			return fmt.Errorf("invalid participant %s: %w", participant, err)
+		}
+	}
+
+	for _, sig := range s.Signatures {
+		err = s.verify(sig)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// SignerId returns the id to use when signing with the wallet of this participant
+func (s *Session) SignerId(participant string) (id uint8, ok bool) {
+	for i, p := range s.Participants {
+		if p == participant {
+			return uint8(i), true
+		}
+	}
+
+	return
+}
+
+func (s *Session) verify(sig wallet.SignatureId) (err error) {
+	if int(sig.Id) >= len(s.Participants) {
+		return ErrUnknownSigner
+	}
+
+	addr, err := address.NewAddressFromString(s.Participants[sig.Id])
+	if err != nil {
+		return
+	}
+
+	hash, err := hex.DecodeString(s.Hash)
+	if err != nil {
+		return
+	}
+
+	if len(sig.Signature) != 128 {
+		return ErrInvalidSignature
+	}
+
+	var publicKey [32]byte
+	copy(publicKey[:], addr.GetPublicKey())
+
+	valid, err := signature.Verify(publicKey, sig.Signature, hash)
+	if err != nil {
+		return
+	}
+
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	return
+}
+
+// AddSignature verifies the signature against the participant public key and stores it.
+// Adding the same signature twice is a no-op.
+func (s *Session) AddSignature(sig wallet.SignatureId) (err error) {
+	err = s.verify(sig)
+	if err != nil {
+		return
+	}
+
+	s.Signatures, err = AddSignature(s.Signatures, sig)
+	return
+}
+
+// Collect asks the participant wallet to sign the transaction hash and adds its signature
+func (s *Session) Collect(w Signer, signerId uint8) (sig wallet.SignatureId, err error) {
+	if int(signerId) >= len(s.Participants) {
+		err = ErrUnknownSigner
+		return
+	}
+
+	sig, err = w.SignUnsignedTransaction(wallet.SignUnsignedTransactionParams{
+		Hash:     s.Hash,
+		SignerId: signerId,
+	})
+	if err != nil {
+		return
+	}
+
+	err = s.AddSignature(sig)
+	return
+}
+
+// Missing returns the signer ids that did not sign yet
+func (s *Session) Missing() (ids []uint8) {
+	signed := make(map[uint8]bool)
+	for _, sig := range s.Signatures {
+		signed[sig.Id] = true
+	}
+
+	for i := range s.Participants {
+		if !signed[uint8(i)] {
+			ids = append(ids, uint8(i))
+		}
+	}
+
+	return
+}
+
+func (s *Session) IsComplete() bool {
+	return len(s.Signatures) >= int(s.Threshold)
+}
+
+// FinalizeParams returns the params for FinalizeUnsignedTransaction with exactly threshold signatures
+func (s *Session) FinalizeParams(broadcast bool) (params wallet.FinalizeUnsignedTransactionParams, err error) {
+	if !s.IsComplete() {
+		err = ErrThresholdNotReached
+		return
+	}
+
+	params = wallet.FinalizeUnsignedTransactionParams{
+		Unsigned:   s.Unsigned,
+		Signatures: s.Signatures[:s.Threshold],
+		Broadcast:  broadcast,
+		TxAsHex:    true,
+	}
+
+	return
+}
+
+// Finalize sends the signatures to the wallet of the multisig account once the threshold is reached
+func (s *Session) Finalize(w Finalizer, broadcast bool) (tx wallet.TransactionResponse, err error) {
+	params, err := s.FinalizeParams(broadcast)
+	if err != nil {
+		return
+	}
+
+	return w.FinalizeUnsignedTransaction(params)
+}
+
+// Encode returns the session as a base64 string to share over any text channel
+func (s *Session) Encode() (encoded string, err error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+
+	encoded = base64.RawURLEncoding.EncodeToString(b)
+	return
+}
+
+// DecodeSession parses a session from Encode and verifies all its signatures
+func DecodeSession(encoded string) (session *Session, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+
+	return parseSession(b)
+}
+
+// WriteFile saves the session as indented JSON
+func (s *Session) WriteFile(path string) (err error) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return os.WriteFile(path, b, 0600)
+}
+
+// ReadFile loads a session saved with WriteFile and verifies all its signatures
+func ReadFile(path string) (session *Session, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	return parseSession(b)
+}
+
+func parseSession(b []byte) (session *Session, err error) {
+	session = &Session{}
+	err = json.Unmarshal(b, session)
+	if err != nil {
+		session = nil
+		return
+	}
+
+	err = session.validate()
+	if err != nil {
+		session = nil
+	}
+
+	return
+}
+
+// Merge adds the valid signatures of another copy of the same session
+func (s *Session) Merge(other *Session) (err error) {
+	if other.Hash != s.Hash || other.Unsigned != s.Unsigned {
+		return fmt.Errorf("cannot merge sessions of different transactions")
+	}
+
+	for _, sig := range other.Signatures {
+		err = s.AddSignature(sig)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
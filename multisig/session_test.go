@@ -0,0 +1,290 @@
+package multisig
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/gtank/ristretto255"
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/sha3"
+)
+
+var basepointCompressed = []byte{
+	0xe2, 0xf2, 0xae, 0x0a, 0x6a, 0xbc, 0x4e, 0x71, 0xa8, 0x84, 0xa9, 0x61, 0xc5, 0x00, 0x51, 0x5f,
+	0x58, 0xe3, 0x0b, 0x6a, 0xa5, 0x82, 0xdd, 0x8d, 0xb6, 0xa6, 0x59, 0x45, 0xe0, 0x8d, 0x2d, 0x76,
+}
+
+// testSigner signs like a xelis wallet, public key is priv^-1 * H
+type testSigner struct {
+	private   *ristretto255.Scalar
+	publicKey [32]byte
+	address   string
+}
+
+func blinding() *ristretto255.Element {
+	hash := sha3.Sum512(basepointCompressed)
+	h := &ristretto255.Element{}
+	h.FromUniformBytes(hash[:])
+	return h
+}
+
+func randomScalar(t *testing.T) *ristretto255.Scalar {
+	b := make([]byte, 64)
+	_, err := rand.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &ristretto255.Scalar{}
+	s.FromUniformBytes(b)
+	return s
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	private := randomScalar(t)
+	inverted := (&ristretto255.Scalar{}).Invert(private)
+	publicKey := (&ristretto255.Element{}).ScalarMult(inverted, blinding())
+
+	signer := &testSigner{private: private}
+	copy(signer.publicKey[:], publicKey.Encode(nil))
+
+	addr, err := address.NewAddressFromData(append(signer.publicKey[:], 0), config.TESTNET_ADDRESS_PREFIX)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer.address, err = addr.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func (s *testSigner) sign(t *testing.T, data []byte) string {
+	k := randomScalar(t)
+	r := (&ristretto255.Element{}).ScalarMult(k, blinding())
+
+	hash := sha3.New512()
+	hash.Write(s.publicKey[:])
+	hash.Write(data)
+	hash.Write(r.Encode(nil))
+
+	e := &ristretto255.Scalar{}
+	e.FromUniformBytes(hash.Sum(nil))
+
+	inverted := (&ristretto255.Scalar{}).Invert(s.private)
+	sig := (&ristretto255.Scalar{}).Multiply(inverted, e)
+	sig.Add(sig, k)
+
+	return hex.EncodeToString(append(sig.Encode(nil), e.Encode(nil)...))
+}
+
+// testUnsigned returns a version 1 unsigned transaction with an empty multisig and its hash
+func testUnsigned() (unsigned []byte, hash [32]byte) {
+	unsigned = append([]byte{1}, make([]byte, 32)...)
+	unsigned = append(unsigned, []byte("payload, fee, nonce, proofs and reference")...)
+	hash = blake3.Sum256(unsigned)
+	unsigned = append(unsigned, 0)
+	return
+}
+
+type fakeWallet struct {
+	t         *testing.T
+	signer    *testSigner
+	finalized *wallet.FinalizeUnsignedTransactionParams
+}
+
+func (f *fakeWallet) SignUnsignedTransaction(params wallet.SignUnsignedTransactionParams) (sig wallet.SignatureId, err error) {
+	hash, err := hex.DecodeString(params.Hash)
+	if err != nil {
+		return
+	}
+
+	sig.Id = params.SignerId
+	sig.Signature = f.signer.sign(f.t, hash)
+	return
+}
+
+func (f *fakeWallet) FinalizeUnsignedTransaction(params wallet.FinalizeUnsignedTransactionParams) (tx wallet.TransactionResponse, err error) {
+	f.finalized = &params
+	tx.Hash = "final"
+	return
+}
+
+type fakeDaemon struct {
+	state daemon.MultisigState
+}
+
+func (f *fakeDaemon) GetMultisig(params daemon.GetMultisigParams) (result daemon.GetMultisigResult, err error) {
+	result.State = f.state
+	return
+}
+
+func newTestSession(t *testing.T) (*Session, []*testSigner) {
+	var signers []*testSigner
+	var participants []string
+	for i := 0; i < 3; i++ {
+		signer := newTestSigner(t)
+		signers = append(signers, signer)
+		participants = append(participants, signer.address)
+	}
+
+	node := &fakeDaemon{state: daemon.MultisigState{Active: &daemon.MultisigActiveState{
+		Participants: participants,
+		Threshold:    2,
+	}}}
+
+	unsigned, hash := testUnsigned()
+	txAsHex := hex.EncodeToString(unsigned)
+	session, err := FetchSession(node, participants[0], wallet.UnsignedTransactionResponse{
+		Hash:    hex.EncodeToString(hash[:]),
+		TxAsHex: &txAsHex,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return session, signers
+}
+
+func TestSessionFinalize(t *testing.T) {
+	session, signers := newTestSession(t)
+
+	_, err := session.Finalize(&fakeWallet{}, false)
+	if err != ErrThresholdNotReached {
+		t.Fatalf("expected %s, got %v", ErrThresholdNotReached, err)
+	}
+
+	for _, i := range []uint8{0, 2} {
+		_, err = session.Collect(&fakeWallet{t: t, signer: signers[i]}, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	missing := session.Missing()
+	if len(missing) != 1 || missing[0] != 1 {
+		t.Fatalf("unexpected missing signers %v", missing)
+	}
+
+	owner := &fakeWallet{}
+	_, err = session.Finalize(owner, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if owner.finalized == nil || len(owner.finalized.Signatures) != 2 || owner.finalized.Unsigned != session.Unsigned {
+		t.Fatalf("unexpected finalize params %+v", owner.finalized)
+	}
+}
+
+func TestSessionInvalidSignature(t *testing.T) {
+	session, signers := newTestSession(t)
+
+	// signer 1 signs with the id of signer 0
+	_, err := session.Collect(&fakeWallet{t: t, signer: signers[1]}, 0)
+	if err != ErrInvalidSignature {
+		t.Fatalf("expected %s, got %v", ErrInvalidSignature, err)
+	}
+
+	_, err = session.Collect(&fakeWallet{t: t, signer: signers[1]}, 5)
+	if err != ErrUnknownSigner {
+		t.Fatalf("expected %s, got %v", ErrUnknownSigner, err)
+	}
+
+	if len(session.Signatures) != 0 {
+		t.Fatalf("unexpected signatures %+v", session.Signatures)
+	}
+}
+
+func TestSessionSerialization(t *testing.T) {
+	session, signers := newTestSession(t)
+
+	_, err := session.Collect(&fakeWallet{t: t, signer: signers[1]}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := session.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeSession(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	err = decoded.WriteFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loaded.Collect(&fakeWallet{t: t, signer: signers[0]}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = session.Merge(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !session.IsComplete() {
+		t.Fatalf("expected complete session, got %+v", session.Signatures)
+	}
+
+	// tampered signature is rejected on load
+	loaded.Signatures[0].Signature = session.Signatures[1].Signature
+	encoded, err = loaded.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = DecodeSession(encoded)
+	if err != ErrInvalidSignature {
+		t.Fatalf("expected %s, got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestSessionHash(t *testing.T) {
+	session, _ := newTestSession(t)
+
+	encoded, err := session.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the hash of another transaction would make participants sign something else
+	session.Hash = hex.EncodeToString(make([]byte, 32))
+	err = session.validate()
+	if err != ErrInvalidHash {
+		t.Fatalf("expected %s, got %v", ErrInvalidHash, err)
+	}
+
+	_, err = DecodeSession(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsigned, _ := testUnsigned()
+	unsigned[len(unsigned)-1] = 1
+	_, err = UnsignedHash(unsigned)
+	if !errors.Is(err, ErrInvalidUnsigned) {
+		t.Fatalf("expected %s, got %v", ErrInvalidUnsigned, err)
+	}
+}
@@ -0,0 +1,88 @@
+package multisig
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	"github.com/zeebo/blake3"
+)
+
+var ErrInvalidHash = errors.New("hash does not match the unsigned transaction")
+var ErrInvalidUnsigned = errors.New("invalid unsigned transaction")
+
+// header of an unsigned transaction: version and source public key
+const unsignedHeaderSize = 1 + 32
+
+// UnsignedHash returns the hash signed by the participants, the blake3 hash of the
+// unsigned transaction without its multisig. Since version 1 the multisig is the last
+// field and is still empty (a single zero byte) when the transaction is built.
+func UnsignedHash(unsigned []byte) (hash [32]byte, err error) {
+	data, err := UnsignedData(unsigned)
+	if err != nil {
+		return
+	}
+
+	hash = blake3.Sum256(data)
+	return
+}
+
+// UnsignedData returns the unsigned transaction without its empty multisig, the bytes covered by UnsignedHash
+func UnsignedData(unsigned []byte) (data []byte, err error) {
+	if len(unsigned) <= unsignedHeaderSize {
+		err = ErrInvalidUnsigned
+		return
+	}
+
+	if unsigned[0] == 0 {
+		data = unsigned
+		return
+	}
+
+	if unsigned[len(unsigned)-1] != 0 {
+		err = fmt.Errorf("%w: multisig is already set", ErrInvalidUnsigned)
+		return
+	}
+
+	data = unsigned[:len(unsigned)-1]
+	return
+}
+
+// VerifyHash checks the hex hash against the unsigned transaction
+func VerifyHash(hash string, unsigned []byte) (err error) {
+	expected, err := UnsignedHash(unsigned)
+	if err != nil {
+		return
+	}
+
+	b, err := hex.DecodeString(hash)
+	if err != nil || !bytes.Equal(b, expected[:]) {
+		return ErrInvalidHash
+	}
+
+	return
+}
+
+// AddSignature inserts the signature sorted by signer id.
+// Adding the same signature twice is a no-op.
+func AddSignature(signatures []wallet.SignatureId, sig wallet.SignatureId) ([]wallet.SignatureId, error) {
+	for _, existing := range signatures {
+		if existing.Id == sig.Id {
+			if existing.Signature == sig.Signature {
+				return signatures, nil
+			}
+
+			return signatures, ErrDuplicateSignature
+		}
+	}
+
+	signatures = append(signatures, sig)
+	sort.Slice(signatures, func(i, j int) bool {
+		return signatures[i].Id < signatures[j].Id
+	})
+
+	return signatures, nil
+}
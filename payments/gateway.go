@@ -0,0 +1,448 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/data"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+// key of the integrated data field holding the invoice id
+const InvoiceIDKey = "invoice_id"
+
+var ErrInvoiceNotFound = errors.New("invoice not found")
+var ErrInvoiceExists = errors.New("invoice already exists")
+var ErrInvalidAmount = errors.New("invoice amount must be greater than zero")
+
+// Wallet is the subset of wallet.RPC and wallet.WebSocket used by the gateway
+type Wallet interface {
+	GetAddress(params wallet.GetAddressParams) (string, error)
+	ListTransactions(params wallet.ListTransactionsParams) ([]wallet.TransactionEntry, error)
+	NetworkInfo() (wallet.NetworkInfoResult, error)
+}
+
+type Config struct {
+	// Number of topoheights a payment must be below the stable topoheight to be confirmed
+	Confirmations uint64
+	// Interval used by Run() to poll the wallet
+	PollInterval time.Duration
+	// Called for every invoice state transition
+	OnStateChange func(StateChange)
+	// Called with the Sync() errors of Run(), it keeps polling after them
+	OnError func(error)
+}
+
+type InvoiceParams struct {
+	// Generated if empty
+	ID          string
+	Amount      uint64
+	Asset       string
+	Description string
+	// Zero value means the invoice never expires
+	Expiry time.Duration
+}
+
+type Gateway struct {
+	wallet  Wallet
+	config  Config
+	address *address.Address
+
+	mutex            sync.Mutex
+	invoices         map[string]*Invoice
+	stableTopoheight uint64
+	syncTopoheight   uint64
+
+	now func() time.Time
+}
+
+func NewGateway(w Wallet, config Config) (*Gateway, error) {
+	addr, err := w.GetAddress(wallet.GetAddressParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	walletAddress, err := address.NewAddressFromString(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.PollInterval == 0 {
+		config.PollInterval = 5 * time.Second
+	}
+
+	return &Gateway{
+		wallet:   w,
+		config:   config,
+		address:  walletAddress,
+		invoices: make(map[string]*Invoice),
+		now:      time.Now,
+	}, nil
+}
+
+func newInvoiceID() (id string, err error) {
+	buf := make([]byte, 16)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return
+	}
+
+	id = hex.EncodeToString(buf)
+	return
+}
+
+func (g *Gateway) integratedAddress(invoiceID string) (addr string, err error) {
+	integrated := *g.address
+	integrated.SetExtraData(&data.Element{
+		Fields: map[data.Value]data.Element{
+			InvoiceIDKey: {Value: invoiceID},
+		},
+	})
+
+	return integrated.Format()
+}
+
+func (g *Gateway) CreateInvoice(params InvoiceParams) (invoice Invoice, err error) {
+	if params.Amount == 0 {
+		err = ErrInvalidAmount
+		return
+	}
+
+	id := params.ID
+	if id == "" {
+		id, err = newInvoiceID()
+		if err != nil {
+			return
+		}
+	}
+
+	if len(id) > data.MaxStringSize {
+		err = data.ErrMaxStringSize
+		return
+	}
+
+	asset := params.Asset
+	if asset == "" {
+		asset = config.XELIS_ASSET
+	}
+
+	addr, err := g.integratedAddress(id)
+	if err != nil {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.invoices[id]; ok {
+		err = ErrInvoiceExists
+		return
+	}
+
+	now := g.now()
+	newInvoice := &Invoice{
+		ID:          id,
+		Address:     addr,
+		Asset:       asset,
+		Amount:      params.Amount,
+		Description: params.Description,
+		CreatedAt:   now,
+		State:       InvoicePending,
+	}
+
+	if params.Expiry > 0 {
+		expiresAt := now.Add(params.Expiry)
+		newInvoice.ExpiresAt = &expiresAt
+	}
+
+	g.invoices[id] = newInvoice
+	invoice = newInvoice.clone()
+	return
+}
+
+func (g *Gateway) GetInvoice(id string) (invoice Invoice, err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	i, ok := g.invoices[id]
+	if !ok {
+		err = ErrInvoiceNotFound
+		return
+	}
+
+	invoice = i.clone()
+	return
+}
+
+func (g *Gateway) Invoices() (invoices []Invoice) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, invoice := range g.invoices {
+		invoices = append(invoices, invoice.clone())
+	}
+
+	return
+}
+
+// Restore invoices previously returned by Invoices(), for example after a restart
+func (g *Gateway) Restore(invoices []Invoice) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, invoice := range invoices {
+		i := invoice.clone()
+		g.invoices[i.ID] = &i
+	}
+}
+
+func (g *Gateway) RemoveInvoice(id string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	delete(g.invoices, id)
+}
+
+// extract the invoice id from the decrypted extra data of an incoming transfer,
+// either the field of integrated data or the data itself
+func invoiceIDFromExtraData(extraData *wallet.PlaintextExtraData) (id string, ok bool) {
+	if extraData == nil {
+		return
+	}
+
+	element, err := extraData.Element()
+	if err != nil {
+		return
+	}
+
+	if field, found := element.Fields[InvoiceIDKey]; found {
+		element = field
+	}
+
+	id, ok = element.Value.(string)
+	return
+}
+
+// HandleTransaction matches the incoming transfers of a wallet transaction with the issued invoices.
+// It can be fed with the NewTransaction event or with ListTransactions results, duplicates are ignored.
+func (g *Gateway) HandleTransaction(tx wallet.TransactionEntry) {
+	if tx.Incoming == nil {
+		return
+	}
+
+	g.mutex.Lock()
+	var changes []StateChange
+	for _, transfer := range tx.Incoming.Transfers {
+		id, ok := invoiceIDFromExtraData(transfer.ExtraData)
+		if !ok {
+			continue
+		}
+
+		invoice, ok := g.invoices[id]
+		if !ok {
+			continue
+		}
+
+		previous := invoice.State
+		g.addPayment(invoice, tx, transfer)
+		changes = g.appendChange(changes, invoice, previous)
+	}
+	g.mutex.Unlock()
+
+	g.emit(changes)
+}
+
+func (g *Gateway) addPayment(invoice *Invoice, tx wallet.TransactionEntry, transfer wallet.TransferIn) {
+	var wrongAsset string
+	if transfer.Asset != invoice.Asset {
+		wrongAsset = transfer.Asset
+	}
+
+	index, found := invoice.hasPayment(tx.Hash, wrongAsset)
+	if found {
+		// the transaction may have been orphaned and executed again in another block
+		payment := &invoice.Payments[index]
+		if payment.Topoheight != tx.Topoheight {
+			payment.Topoheight = tx.Topoheight
+			payment.Confirmed = g.isConfirmed(tx.Topoheight)
+		}
+		return
+	}
+
+	payment := Payment{
+		TxHash:     tx.Hash,
+		Topoheight: tx.Topoheight,
+		Timestamp:  tx.Timestamp,
+		From:       tx.Incoming.From,
+		Amount:     transfer.Amount,
+		Confirmed:  g.isConfirmed(tx.Topoheight),
+		WrongAsset: wrongAsset,
+	}
+
+	// timestamp of the transaction entry is in milliseconds
+	if invoice.ExpiresAt != nil && time.UnixMilli(int64(tx.Timestamp)).After(*invoice.ExpiresAt) {
+		payment.Late = true
+	}
+
+	if payment.counted() {
+		invoice.Received += payment.Amount
+	}
+
+	invoice.Payments = append(invoice.Payments, payment)
+}
+
+// removePayments drops the payments between min and max topoheight that are not in txs anymore,
+// their transaction was orphaned and not executed again
+func (g *Gateway) removePayments(minTopoheight uint64, maxTopoheight uint64, txs map[string]bool) (changes []StateChange) {
+	for _, invoice := range g.invoices {
+		previous := invoice.State
+		payments := invoice.Payments[:0]
+		for _, payment := range invoice.Payments {
+			if payment.Topoheight < minTopoheight || payment.Topoheight > maxTopoheight || txs[payment.TxHash] {
+				payments = append(payments, payment)
+				continue
+			}
+
+			if payment.counted() {
+				invoice.Received -= payment.Amount
+			}
+		}
+
+		invoice.Payments = payments
+		changes = g.appendChange(changes, invoice, previous)
+	}
+
+	return
+}
+
+func (g *Gateway) isConfirmed(topoheight uint64) bool {
+	return g.stableTopoheight >= topoheight+g.config.Confirmations
+}
+
+func (g *Gateway) appendChange(changes []StateChange, invoice *Invoice, previous InvoiceState) []StateChange {
+	state := invoice.computeState(g.now())
+	invoice.State = state
+	if state == previous {
+		return changes
+	}
+
+	return append(changes, StateChange{
+		Invoice:  invoice.clone(),
+		Previous: previous,
+		State:    state,
+	})
+}
+
+func (g *Gateway) emit(changes []StateChange) {
+	if g.config.OnStateChange == nil {
+		return
+	}
+
+	for _, change := range changes {
+		g.config.OnStateChange(change)
+	}
+}
+
+// Update confirms payments below the stable topoheight and expires overdue invoices
+func (g *Gateway) Update(stableTopoheight uint64) {
+	g.mutex.Lock()
+	if stableTopoheight > g.stableTopoheight {
+		g.stableTopoheight = stableTopoheight
+	}
+
+	var changes []StateChange
+	for _, invoice := range g.invoices {
+		previous := invoice.State
+		for i := range invoice.Payments {
+			payment := &invoice.Payments[i]
+			if !payment.Confirmed {
+				payment.Confirmed = g.isConfirmed(payment.Topoheight)
+			}
+		}
+
+		changes = g.appendChange(changes, invoice, previous)
+	}
+	g.mutex.Unlock()
+
+	g.emit(changes)
+}
+
+// Sync fetches incoming transactions from the wallet since the last synced topoheight
+// and updates invoices with the current stable topoheight.
+// Payments above the last synced topoheight that the wallet does not report anymore are removed.
+func (g *Gateway) Sync() (err error) {
+	info, err := g.wallet.NetworkInfo()
+	if err != nil {
+		return
+	}
+
+	g.mutex.Lock()
+	minTopoheight := g.syncTopoheight
+	g.mutex.Unlock()
+
+	txs, err := g.wallet.ListTransactions(wallet.ListTransactionsParams{
+		MinTopoheight:  &minTopoheight,
+		AcceptIncoming: true,
+	})
+	if err != nil {
+		return
+	}
+
+	hashes := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		hashes[tx.Hash] = true
+		g.HandleTransaction(tx)
+	}
+
+	g.mutex.Lock()
+	// payments above the wallet topoheight may come from events received after the listing
+	changes := g.removePayments(minTopoheight, info.Topoheight, hashes)
+	// transactions above the stable topoheight are fetched again on next sync in case they get orphaned
+	if info.StableTopoheight > g.syncTopoheight {
+		g.syncTopoheight = info.StableTopoheight
+	}
+	g.mutex.Unlock()
+
+	g.emit(changes)
+
+	g.Update(info.StableTopoheight)
+	return
+}
+
+// Run polls the wallet until the context is done, sync errors are passed to Config.OnError
+func (g *Gateway) Run(ctx context.Context) error {
+	ticker := time.NewTicker(g.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := g.Sync()
+		if err != nil && g.config.OnError != nil {
+			g.config.OnError(fmt.Errorf("payments sync: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Listen feeds the gateway with the NewTransaction event of a wallet websocket.
+// Confirmations and expirations are still applied by Sync() or Update().
+func (g *Gateway) Listen(ws *wallet.WebSocket) error {
+	return ws.NewTransactionFunc(func(tx wallet.TransactionEntry, err error) {
+		if err != nil {
+			return
+		}
+
+		g.HandleTransaction(tx)
+	})
+}
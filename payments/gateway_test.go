@@ -0,0 +1,320 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+const TESTING_ADDR = "xet:qf5u2p46jpgqmypqc2xwtq25yek2t7qhnqtdhw5kpfwcrlavs5asq0r83r7"
+
+type fakeWallet struct {
+	txs  []wallet.TransactionEntry
+	info wallet.NetworkInfoResult
+	// number of NetworkInfo calls failing before it answers
+	failures int
+}
+
+func (f *fakeWallet) GetAddress(params wallet.GetAddressParams) (string, error) {
+	return TESTING_ADDR, nil
+}
+
+func (f *fakeWallet) ListTransactions(params wallet.ListTransactionsParams) ([]wallet.TransactionEntry, error) {
+	var txs []wallet.TransactionEntry
+	for _, tx := range f.txs {
+		if params.MinTopoheight == nil || tx.Topoheight >= *params.MinTopoheight {
+			txs = append(txs, tx)
+		}
+	}
+
+	return txs, nil
+}
+
+func (f *fakeWallet) NetworkInfo() (wallet.NetworkInfoResult, error) {
+	if f.failures > 0 {
+		f.failures--
+		return f.info, errors.New("connection refused")
+	}
+
+	return f.info, nil
+}
+
+func incomingTx(hash string, topoheight uint64, invoiceID string, amount uint64) wallet.TransactionEntry {
+	return wallet.TransactionEntry{
+		Hash:       hash,
+		Topoheight: topoheight,
+		Timestamp:  uint64(time.Now().UnixMilli()),
+		Incoming: &wallet.Incoming{
+			From: TESTING_ADDR,
+			Transfers: []wallet.TransferIn{
+				{
+					Amount: amount,
+					Asset:  config.XELIS_ASSET,
+					ExtraData: &wallet.PlaintextExtraData{
+						Data: map[string]interface{}{InvoiceIDKey: invoiceID},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestInvoiceAddress(t *testing.T) {
+	gateway, err := NewGateway(&fakeWallet{}, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, err := gateway.CreateInvoice(InvoiceParams{Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := address.NewAddressFromString(invoice.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !addr.IsIntegrated() {
+		t.Fatal("expected an integrated address")
+	}
+
+	id := addr.GetExtraData().Fields[InvoiceIDKey].Value
+	if id != invoice.ID {
+		t.Fatalf("expected invoice id %s, got %v", invoice.ID, id)
+	}
+
+	_, err = gateway.CreateInvoice(InvoiceParams{ID: invoice.ID, Amount: 100})
+	if err != ErrInvoiceExists {
+		t.Fatalf("expected %s, got %v", ErrInvoiceExists, err)
+	}
+}
+
+func TestInvoicePartialAndOverpayment(t *testing.T) {
+	fake := &fakeWallet{}
+
+	var changes []StateChange
+	gateway, err := NewGateway(fake, Config{
+		Confirmations: 2,
+		OnStateChange: func(change StateChange) {
+			changes = append(changes, change)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, err := gateway.CreateInvoice(InvoiceParams{ID: "inv_1", Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.txs = append(fake.txs, incomingTx("tx1", 10, invoice.ID, 40))
+	fake.info.StableTopoheight = 5
+	err = gateway.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, _ = gateway.GetInvoice("inv_1")
+	if invoice.State != InvoicePartiallyPaid || invoice.Remaining() != 60 {
+		t.Fatalf("unexpected invoice %+v", invoice)
+	}
+
+	// the same transaction seen from the event must not be counted twice
+	gateway.HandleTransaction(incomingTx("tx1", 10, invoice.ID, 40))
+	fake.txs = append(fake.txs, incomingTx("tx2", 11, invoice.ID, 80))
+	err = gateway.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, _ = gateway.GetInvoice("inv_1")
+	if invoice.State != InvoicePaid || invoice.Received != 120 || !invoice.IsOverpaid() {
+		t.Fatalf("unexpected invoice %+v", invoice)
+	}
+
+	fake.info.StableTopoheight = 13
+	err = gateway.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, _ = gateway.GetInvoice("inv_1")
+	if invoice.State != InvoiceConfirmed {
+		t.Fatalf("expected confirmed invoice, got %s", invoice.State)
+	}
+
+	expected := []InvoiceState{InvoicePartiallyPaid, InvoicePaid, InvoiceConfirmed}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d state changes, got %d", len(expected), len(changes))
+	}
+
+	for i, change := range changes {
+		if change.State != expected[i] {
+			t.Errorf("expected state %s, got %s", expected[i], change.State)
+		}
+	}
+}
+
+func TestInvoiceExpiration(t *testing.T) {
+	gateway, err := NewGateway(&fakeWallet{}, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	gateway.now = func() time.Time { return now }
+
+	invoice, err := gateway.CreateInvoice(InvoiceParams{Amount: 100, Expiry: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	gateway.Update(0)
+
+	invoice, _ = gateway.GetInvoice(invoice.ID)
+	if invoice.State != InvoiceExpired {
+		t.Fatalf("expected expired invoice, got %s", invoice.State)
+	}
+
+	// payment made after expiration is recorded but not counted
+	tx := incomingTx("tx1", 10, invoice.ID, 100)
+	tx.Timestamp = uint64(now.UnixMilli())
+	gateway.HandleTransaction(tx)
+
+	invoice, _ = gateway.GetInvoice(invoice.ID)
+	if invoice.State != InvoiceExpired || invoice.Received != 0 || !invoice.Payments[0].Late {
+		t.Fatalf("unexpected invoice %+v", invoice)
+	}
+}
+
+func TestOrphanedPayment(t *testing.T) {
+	fake := &fakeWallet{}
+	gateway, err := NewGateway(fake, Config{Confirmations: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, err := gateway.CreateInvoice(InvoiceParams{ID: "inv_1", Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.txs = []wallet.TransactionEntry{incomingTx("tx1", 10, invoice.ID, 100)}
+	fake.info.Topoheight = 12
+	fake.info.StableTopoheight = 5
+	err = gateway.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, _ = gateway.GetInvoice("inv_1")
+	if invoice.State != InvoicePaid {
+		t.Fatalf("expected paid invoice, got %+v", invoice)
+	}
+
+	// the block was orphaned and the transaction is not in the wallet history anymore
+	fake.txs = nil
+	// a payment received from an event after the listing is kept
+	gateway.HandleTransaction(incomingTx("tx2", 13, invoice.ID, 30))
+	err = gateway.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, _ = gateway.GetInvoice("inv_1")
+	if invoice.State != InvoicePartiallyPaid || invoice.Received != 30 || len(invoice.Payments) != 1 {
+		t.Fatalf("expected the orphaned payment to be removed, got %+v", invoice)
+	}
+}
+
+func TestWrongAssetPayment(t *testing.T) {
+	fake := &fakeWallet{}
+	gateway, err := NewGateway(fake, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, err := gateway.CreateInvoice(InvoiceParams{ID: "inv_1", Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := incomingTx("tx1", 10, invoice.ID, 100)
+	otherAsset := "0000000000000000000000000000000000000000000000000000000000000001"
+	tx.Incoming.Transfers = append(tx.Incoming.Transfers, wallet.TransferIn{
+		Amount: 500,
+		Asset:  otherAsset,
+		// plain data without integrated address fields
+		ExtraData: &wallet.PlaintextExtraData{Data: invoice.ID},
+	})
+	gateway.HandleTransaction(tx)
+
+	invoice, _ = gateway.GetInvoice("inv_1")
+	if invoice.Received != 100 || len(invoice.Payments) != 2 {
+		t.Fatalf("unexpected invoice %+v", invoice)
+	}
+
+	payment := invoice.Payments[1]
+	if payment.WrongAsset != otherAsset || payment.Amount != 500 {
+		t.Fatalf("expected the payment in the wrong asset to be recorded, got %+v", payment)
+	}
+}
+
+func TestRunKeepsPolling(t *testing.T) {
+	fake := &fakeWallet{failures: 2}
+	fake.txs = append(fake.txs, incomingTx("tx1", 10, "inv_1", 100))
+
+	errs := make(chan error, 2)
+	changes := make(chan StateChange, 1)
+	gateway, err := NewGateway(fake, Config{
+		PollInterval:  time.Millisecond,
+		OnError:       func(err error) { errs <- err },
+		OnStateChange: func(change StateChange) { changes <- change },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = gateway.CreateInvoice(InvoiceParams{ID: "inv_1", Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- gateway.Run(ctx)
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-errs:
+		case <-ctx.Done():
+			t.Fatal("sync error not reported")
+		}
+	}
+
+	select {
+	case change := <-changes:
+		if change.Invoice.ID != "inv_1" || change.Invoice.State != InvoicePaid {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-ctx.Done():
+		t.Fatal("stopped polling after the sync errors")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+}
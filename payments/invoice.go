@@ -0,0 +1,117 @@
+package payments
+
+import (
+	"time"
+)
+
+type InvoiceState string
+
+const (
+	// Invoice was issued and nothing was received yet
+	InvoicePending InvoiceState = "pending"
+	// Some funds were received but less than the requested amount
+	InvoicePartiallyPaid InvoiceState = "partially_paid"
+	// The requested amount was received but some payments are not confirmed yet
+	InvoicePaid InvoiceState = "paid"
+	// The requested amount was received and every payment reached the required confirmations
+	InvoiceConfirmed InvoiceState = "confirmed"
+	// The invoice expired before the requested amount was received
+	InvoiceExpired InvoiceState = "expired"
+)
+
+type Payment struct {
+	TxHash     string `json:"tx_hash"`
+	Topoheight uint64 `json:"topoheight"`
+	Timestamp  uint64 `json:"timestamp"`
+	From       string `json:"from"`
+	Amount     uint64 `json:"amount"`
+	Confirmed  bool   `json:"confirmed"`
+	// Late payments arrived after the invoice expiration and are not counted in Received
+	Late bool `json:"late"`
+	// Asset of a payment sent in another asset than the invoice one, it is not counted in Received
+	WrongAsset string `json:"wrong_asset,omitempty"`
+}
+
+// counted reports if the payment amount is part of the invoice Received
+func (p Payment) counted() bool {
+	return !p.Late && p.WrongAsset == ""
+}
+
+type Invoice struct {
+	ID          string       `json:"id"`
+	Address     string       `json:"address"`
+	Asset       string       `json:"asset"`
+	Amount      uint64       `json:"amount"`
+	Description string       `json:"description,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+	State       InvoiceState `json:"state"`
+	Received    uint64       `json:"received"`
+	Payments    []Payment    `json:"payments"`
+}
+
+type StateChange struct {
+	Invoice  Invoice      `json:"invoice"`
+	Previous InvoiceState `json:"previous"`
+	State    InvoiceState `json:"state"`
+}
+
+func (i *Invoice) IsOverpaid() bool {
+	return i.Received > i.Amount
+}
+
+func (i *Invoice) Remaining() uint64 {
+	if i.Received >= i.Amount {
+		return 0
+	}
+
+	return i.Amount - i.Received
+}
+
+func (i *Invoice) isExpiredAt(timestamp time.Time) bool {
+	return i.ExpiresAt != nil && timestamp.After(*i.ExpiresAt)
+}
+
+func (i *Invoice) hasPayment(txHash string, wrongAsset string) (index int, found bool) {
+	for index, payment := range i.Payments {
+		if payment.TxHash == txHash && payment.WrongAsset == wrongAsset {
+			return index, true
+		}
+	}
+
+	return
+}
+
+// compute the state from received payments, confirmations and expiration
+func (i *Invoice) computeState(now time.Time) InvoiceState {
+	if i.Received >= i.Amount {
+		for _, payment := range i.Payments {
+			if payment.counted() && !payment.Confirmed {
+				return InvoicePaid
+			}
+		}
+
+		return InvoiceConfirmed
+	}
+
+	if i.isExpiredAt(now) {
+		return InvoiceExpired
+	}
+
+	if i.Received > 0 {
+		return InvoicePartiallyPaid
+	}
+
+	return InvoicePending
+}
+
+func (i *Invoice) clone() Invoice {
+	invoice := *i
+	invoice.Payments = append([]Payment(nil), i.Payments...)
+	if i.ExpiresAt != nil {
+		expiresAt := *i.ExpiresAt
+		invoice.ExpiresAt = &expiresAt
+	}
+
+	return invoice
+}
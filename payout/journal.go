@@ -0,0 +1,98 @@
+package payout
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+type journalEntryType string
+
+const (
+	journalPayout journalEntryType = "payout"
+	journalBatch  journalEntryType = "batch"
+)
+
+// every entry is a full snapshot of the payout or batch, last entry wins on replay
+type journalEntry struct {
+	Type   journalEntryType `json:"type"`
+	Payout *Payout          `json:"payout,omitempty"`
+	Batch  *Batch           `json:"batch,omitempty"`
+}
+
+// Journal is an append-only file of JSON lines synced to disk after each write
+type Journal struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func OpenJournal(path string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{file: file}, nil
+}
+
+func (j *Journal) append(entry journalEntry) (err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	_, err = j.file.Write(append(line, '\n'))
+	if err != nil {
+		return
+	}
+
+	return j.file.Sync()
+}
+
+func (j *Journal) replay() (entries []journalEntry, err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	_, err = j.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(j.file)
+	offset := int64(0)
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			err = nil
+			if len(line) > 0 {
+				// a partial last line means we crashed while writing, the entry was never acknowledged
+				err = j.file.Truncate(offset)
+			}
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		var entry journalEntry
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			return
+		}
+
+		entries = append(entries, entry)
+		offset += int64(len(line))
+	}
+}
+
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,631 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+var ErrInvalidPayout = errors.New("invalid payout")
+
+type BatchState string
+
+const (
+	// Batch has a nonce assigned but we don't know yet if it was broadcasted
+	BatchPending BatchState = "pending"
+	// Transaction was accepted by the wallet
+	BatchBroadcast BatchState = "broadcast"
+	// Transaction is in a block below the stable topoheight
+	BatchConfirmed BatchState = "confirmed"
+	// Transaction was refused before being sent or its nonce was used by another transaction,
+	// its payouts are back in the queue
+	BatchFailed BatchState = "failed"
+)
+
+type Payout struct {
+	// Unique id of the payout, a payout id is paid only once
+	ID          string `json:"id"`
+	Destination string `json:"destination"`
+	Asset       string `json:"asset"`
+	Amount      uint64 `json:"amount"`
+}
+
+type Batch struct {
+	ID    uint64 `json:"id"`
+	Nonce uint64 `json:"nonce"`
+	// Wallet topoheight when the nonce was assigned, the transaction can't be executed before
+	Topoheight uint64     `json:"topoheight,omitempty"`
+	PayoutIDs  []string   `json:"payout_ids"`
+	Hash       string     `json:"hash,omitempty"`
+	State      BatchState `json:"state"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Wallet is the subset of wallet.RPC and wallet.WebSocket used by the engine
+type Wallet interface {
+	GetNonce() (uint64, error)
+	BuildTransaction(params wallet.BuildTransactionParams) (wallet.TransactionResponse, error)
+	GetTransaction(params wallet.GetTransactionParams) (wallet.TransactionEntry, error)
+	GetPendingTransactions() ([]wallet.TransactionPending, error)
+	ListTransactions(params wallet.ListTransactionsParams) ([]wallet.TransactionEntry, error)
+	NetworkInfo() (wallet.NetworkInfoResult, error)
+}
+
+type Options struct {
+	// Transfers packed in a single transaction, wallet.MaxTransfers by default
+	MaxTransfersPerTx int
+	// Attempts to broadcast a transaction when the wallet can't be reached
+	MaxRetries int
+	RetryDelay time.Duration
+	// Interval used by Run()
+	PollInterval  time.Duration
+	FeeMultiplier float64
+}
+
+// Engine packs payouts in transactions, assigns nonces locally and journals every step.
+// A batch keeps its nonce across retries and restarts so the same payouts can never be executed twice.
+type Engine struct {
+	wallet  Wallet
+	journal *Journal
+	opts    Options
+	network config.Network
+
+	// serializes ProcessOnce, mutex is released while waiting between retries
+	processMutex sync.Mutex
+
+	mutex     sync.Mutex
+	payouts   map[string]*Payout
+	assigned  map[string]uint64 // payout id -> batch id
+	queue     []string
+	batches   map[uint64]*Batch
+	lastBatch uint64
+	nextNonce *uint64
+	// nonce of the wallet at the start of the current process
+	walletNonce uint64
+}
+
+func NewEngine(w Wallet, journalPath string, opts Options) (*Engine, error) {
+	if opts.MaxTransfersPerTx <= 0 || opts.MaxTransfersPerTx > wallet.MaxTransfers {
+		opts.MaxTransfersPerTx = wallet.MaxTransfers
+	}
+
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+
+	if opts.RetryDelay == 0 {
+		opts.RetryDelay = 2 * time.Second
+	}
+
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 10 * time.Second
+	}
+
+	info, err := w.NetworkInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	journal, err := OpenJournal(journalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{
+		wallet:   w,
+		journal:  journal,
+		opts:     opts,
+		network:  info.Network,
+		payouts:  make(map[string]*Payout),
+		assigned: make(map[string]uint64),
+		batches:  make(map[uint64]*Batch),
+	}
+
+	err = engine.restore()
+	if err != nil {
+		journal.Close()
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+func (e *Engine) restore() error {
+	entries, err := e.journal.replay()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		switch entry.Type {
+		case journalPayout:
+			if entry.Payout != nil {
+				e.payouts[entry.Payout.ID] = entry.Payout
+			}
+		case journalBatch:
+			if entry.Batch != nil {
+				e.batches[entry.Batch.ID] = entry.Batch
+				if entry.Batch.ID > e.lastBatch {
+					e.lastBatch = entry.Batch.ID
+				}
+			}
+		}
+	}
+
+	for _, batch := range e.sortedBatches() {
+		if batch.State == BatchFailed {
+			continue
+		}
+
+		for _, id := range batch.PayoutIDs {
+			e.assigned[id] = batch.ID
+		}
+
+		if e.nextNonce == nil || batch.Nonce >= *e.nextNonce {
+			nonce := batch.Nonce + 1
+			e.nextNonce = &nonce
+		}
+	}
+
+	for id := range e.payouts {
+		if _, ok := e.assigned[id]; !ok {
+			e.queue = append(e.queue, id)
+		}
+	}
+
+	sort.Strings(e.queue)
+	return nil
+}
+
+func (e *Engine) Close() error {
+	return e.journal.Close()
+}
+
+func (e *Engine) sortedBatches() (batches []*Batch) {
+	for _, batch := range e.batches {
+		batches = append(batches, batch)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].ID < batches[j].ID
+	})
+	return
+}
+
+// Submit queues payouts, a payout with an already known id is ignored.
+// The destinations must be on the network of the wallet.
+func (e *Engine) Submit(payouts []Payout) (err error) {
+	for _, payout := range payouts {
+		if payout.ID == "" || payout.Amount == 0 {
+			return fmt.Errorf("%w: %+v", ErrInvalidPayout, payout)
+		}
+
+		var addr *address.Address
+		addr, err = address.NewAddressFromString(payout.Destination)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidPayout, payout.ID, err)
+		}
+
+		if !addr.IsNetwork(e.network) {
+			return fmt.Errorf("%w: %s: destination is not on %s", ErrInvalidPayout, payout.ID, e.network)
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, payout := range payouts {
+		if _, ok := e.payouts[payout.ID]; ok {
+			continue
+		}
+
+		p := payout
+		if p.Asset == "" {
+			p.Asset = config.XELIS_ASSET
+		}
+
+		err = e.journal.append(journalEntry{Type: journalPayout, Payout: &p})
+		if err != nil {
+			return
+		}
+
+		e.payouts[p.ID] = &p
+		e.queue = append(e.queue, p.ID)
+	}
+
+	return
+}
+
+func (e *Engine) saveBatch(batch *Batch) error {
+	b := *batch
+	return e.journal.append(journalEntry{Type: journalBatch, Batch: &b})
+}
+
+func (e *Engine) Batches() (batches []Batch) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, batch := range e.sortedBatches() {
+		batches = append(batches, *batch)
+	}
+
+	return
+}
+
+// Status returns the batch paying the payout, nil if the payout is still queued
+func (e *Engine) Status(payoutID string) (batch *Batch, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.payouts[payoutID]; !ok {
+		err = fmt.Errorf("unknown payout %s", payoutID)
+		return
+	}
+
+	id, ok := e.assigned[payoutID]
+	if ok {
+		b := *e.batches[id]
+		batch = &b
+	}
+
+	return
+}
+
+func (e *Engine) Pending() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.queue)
+}
+
+func isRPCError(err error) bool {
+	var rpcErr *rpc.RPCError
+	return errors.As(err, &rpcErr)
+}
+
+// rejectedLocally reports if the wallet client refused the transaction before sending it.
+// Any other error may come after the node accepted the transaction: the wallet answers
+// with an error when its call to the node fails, and a lost connection gives no answer.
+func rejectedLocally(err error) bool {
+	return errors.Is(err, wallet.ErrNetworkMismatch) || errors.Is(err, wallet.ErrFeeConflict)
+}
+
+// ProcessOnce resolves pending batches, broadcasts queued payouts and confirms broadcasted batches.
+// Queued payouts wait while a batch is pending as their nonces would come after its nonce.
+func (e *Engine) ProcessOnce() (err error) {
+	e.processMutex.Lock()
+	defer e.processMutex.Unlock()
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	err = e.initNonce()
+	if err != nil {
+		return
+	}
+
+	info, err := e.wallet.NetworkInfo()
+	if err != nil {
+		return
+	}
+
+	var pendingErr error
+	for _, batch := range e.sortedBatches() {
+		if batch.State == BatchPending {
+			err = e.resolvePending(batch)
+			if err != nil && pendingErr == nil {
+				pendingErr = err
+			}
+		}
+	}
+
+	for pendingErr == nil && len(e.queue) > 0 {
+		var batch *Batch
+		batch, err = e.newBatch(info.Topoheight)
+		if err != nil {
+			return
+		}
+
+		pendingErr = e.broadcast(batch, false)
+	}
+
+	err = e.confirm(info)
+	if pendingErr != nil {
+		err = pendingErr
+	}
+
+	return
+}
+
+func (e *Engine) initNonce() (err error) {
+	nonce, err := e.wallet.GetNonce()
+	if err != nil {
+		return
+	}
+
+	e.walletNonce = nonce
+	if e.nextNonce == nil || nonce > *e.nextNonce {
+		e.nextNonce = &nonce
+	}
+
+	return
+}
+
+func (e *Engine) newBatch(topoheight uint64) (batch *Batch, err error) {
+	size := len(e.queue)
+	if size > e.opts.MaxTransfersPerTx {
+		size = e.opts.MaxTransfersPerTx
+	}
+
+	e.lastBatch++
+	batch = &Batch{
+		ID:         e.lastBatch,
+		Nonce:      *e.nextNonce,
+		Topoheight: topoheight,
+		PayoutIDs:  append([]string(nil), e.queue[:size]...),
+		State:      BatchPending,
+	}
+
+	// journal the nonce assignment before anything is sent
+	err = e.saveBatch(batch)
+	if err != nil {
+		e.lastBatch--
+		return
+	}
+
+	e.batches[batch.ID] = batch
+	for _, id := range batch.PayoutIDs {
+		e.assigned[id] = batch.ID
+	}
+
+	e.queue = e.queue[size:]
+	nonce := batch.Nonce + 1
+	e.nextNonce = &nonce
+	return
+}
+
+func (e *Engine) buildParams(batch *Batch) (params wallet.BuildTransactionParams, err error) {
+	builder := wallet.NewTx()
+	for _, id := range batch.PayoutIDs {
+		payout := e.payouts[id]
+		builder.Transfer(payout.Destination, payout.Asset, payout.Amount)
+	}
+
+	if e.opts.FeeMultiplier > 0 {
+		builder.FeeMultiplier(e.opts.FeeMultiplier)
+	}
+
+	return builder.Nonce(batch.Nonce).Broadcast(true).BuildTransactionParams()
+}
+
+// broadcast sends the batch until the wallet accepts it. Unless the wallet client refused it
+// before sending, the transaction may have been broadcasted despite the error (unknown is true),
+// so the batch stays pending with its nonce and resolvePending finds out what happened:
+// its payouts are never put back in the queue with another nonce.
+func (e *Engine) broadcast(batch *Batch, unknown bool) (err error) {
+	params, err := e.buildParams(batch)
+	if err != nil {
+		if unknown {
+			return e.keepPending(batch, err)
+		}
+
+		return e.fail(batch, err, true)
+	}
+
+	for attempt := 1; ; attempt++ {
+		var tx wallet.TransactionResponse
+		tx, err = e.wallet.BuildTransaction(params)
+		if err == nil {
+			batch.Hash = tx.Hash
+			batch.State = BatchBroadcast
+			batch.Error = ""
+			return e.saveBatch(batch)
+		}
+
+		if rejectedLocally(err) && !unknown {
+			return e.fail(batch, err, true)
+		}
+
+		// the history tells if a transaction refused by the wallet reached the node, a connection
+		// error is retried with the same nonce as only one transaction can use it
+		unknown = true
+		if rejectedLocally(err) || isRPCError(err) || attempt >= e.opts.MaxRetries {
+			return e.keepPending(batch, err)
+		}
+
+		// Submit, Status and Batches are not blocked while waiting,
+		// processMutex still prevents another ProcessOnce to use the batch
+		e.mutex.Unlock()
+		time.Sleep(e.opts.RetryDelay)
+		e.mutex.Lock()
+	}
+}
+
+// keepPending records the error of a batch which may have been broadcasted,
+// it is resolved again on next process
+func (e *Engine) keepPending(batch *Batch, cause error) (err error) {
+	batch.Error = cause.Error()
+	err = e.saveBatch(batch)
+	if err != nil {
+		return
+	}
+
+	return fmt.Errorf("batch %d is pending: %w", batch.ID, cause)
+}
+
+// put back the payouts in the queue, the nonce is reused for the next batch if it wasn't consumed
+func (e *Engine) fail(batch *Batch, cause error, reuseNonce bool) (err error) {
+	batch.State = BatchFailed
+	batch.Error = cause.Error()
+	err = e.saveBatch(batch)
+	if err != nil {
+		return
+	}
+
+	for _, id := range batch.PayoutIDs {
+		delete(e.assigned, id)
+	}
+
+	e.queue = append(append([]string(nil), batch.PayoutIDs...), e.queue...)
+	if reuseNonce && *e.nextNonce > batch.Nonce {
+		nonce := batch.Nonce
+		e.nextNonce = &nonce
+	}
+
+	return fmt.Errorf("batch %d failed: %w", batch.ID, cause)
+}
+
+func (e *Engine) matchOutgoing(batch *Batch, outgoing *wallet.Outgoing) bool {
+	if outgoing == nil || outgoing.Nonce != batch.Nonce || len(outgoing.Transfers) != len(batch.PayoutIDs) {
+		return false
+	}
+
+	for i, id := range batch.PayoutIDs {
+		payout := e.payouts[id]
+		transfer := outgoing.Transfers[i]
+		if transfer.Destination != payout.Destination || transfer.Amount != payout.Amount || transfer.Asset != payout.Asset {
+			return false
+		}
+	}
+
+	return true
+}
+
+// find out if a batch interrupted by a crash or an error was broadcasted, by its nonce in
+// the pending transactions and in the history since the nonce was assigned
+func (e *Engine) resolvePending(batch *Batch) (err error) {
+	pending, err := e.wallet.GetPendingTransactions()
+	if err != nil {
+		return
+	}
+
+	for _, tx := range pending {
+		if e.matchOutgoing(batch, tx.Outgoing) {
+			batch.Hash = tx.Hash
+			batch.State = BatchBroadcast
+			batch.Error = ""
+			return e.saveBatch(batch)
+		}
+	}
+
+	minTopoheight := batch.Topoheight
+	txs, err := e.wallet.ListTransactions(wallet.ListTransactionsParams{
+		MinTopoheight:  &minTopoheight,
+		AcceptOutgoing: true,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, tx := range txs {
+		if e.matchOutgoing(batch, tx.Outgoing) {
+			batch.Hash = tx.Hash
+			batch.State = BatchBroadcast
+			batch.Error = ""
+			return e.saveBatch(batch)
+		}
+
+		if tx.Outgoing != nil && tx.Outgoing.Nonce == batch.Nonce {
+			// the nonce was consumed by another transaction, the batch was never executed
+			return e.fail(batch, fmt.Errorf("nonce %d used by transaction %s", batch.Nonce, tx.Hash), false)
+		}
+	}
+
+	if e.walletNonce > batch.Nonce {
+		// the nonce is used but its transaction is not known by the wallet yet
+		return e.keepPending(batch, fmt.Errorf("nonce %d is used by an unknown transaction", batch.Nonce))
+	}
+
+	// not found anywhere, broadcast again with the same nonce
+	return e.broadcast(batch, true)
+}
+
+// confirm marks the stable batches as confirmed. A batch whose transaction is not known
+// by the wallet anymore was dropped or orphaned, it is pending again to be resolved by its nonce.
+func (e *Engine) confirm(info wallet.NetworkInfoResult) (err error) {
+	var pending map[string]bool
+	for _, batch := range e.sortedBatches() {
+		if batch.State != BatchBroadcast {
+			continue
+		}
+
+		tx, txErr := e.wallet.GetTransaction(wallet.GetTransactionParams{Hash: batch.Hash})
+		if txErr != nil {
+			if !isRPCError(txErr) {
+				return txErr
+			}
+
+			if pending == nil {
+				pending, err = e.pendingHashes()
+				if err != nil {
+					return
+				}
+			}
+
+			if pending[batch.Hash] {
+				// not executed in a block yet
+				continue
+			}
+
+			batch.State = BatchPending
+			batch.Error = fmt.Sprintf("transaction %s not found: %s", batch.Hash, txErr)
+			err = e.saveBatch(batch)
+			if err != nil {
+				return
+			}
+
+			continue
+		}
+
+		if tx.Topoheight <= info.StableTopoheight {
+			batch.State = BatchConfirmed
+			err = e.saveBatch(batch)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func (e *Engine) pendingHashes() (hashes map[string]bool, err error) {
+	pending, err := e.wallet.GetPendingTransactions()
+	if err != nil {
+		return
+	}
+
+	hashes = make(map[string]bool, len(pending))
+	for _, tx := range pending {
+		hashes[tx.Hash] = true
+	}
+
+	return
+}
+
+// Run calls ProcessOnce every PollInterval until the context is done, errors are passed to onError
+func (e *Engine) Run(ctx context.Context, onError func(error)) error {
+	ticker := time.NewTicker(e.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := e.ProcessOnce()
+		if err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package payout
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+const TESTING_ADDR = "xet:qf5u2p46jpgqmypqc2xwtq25yek2t7qhnqtdhw5kpfwcrlavs5asq0r83r7"
+const MAINNET_ADDR = "xel:as3mgjlevw5ve6k70evzz8lwmsa5p0lgws2d60fulxylnmeqrp9qqukwdfg"
+
+type fakeWallet struct {
+	nonce     uint64
+	built     []wallet.BuildTransactionParams
+	pending   []wallet.TransactionPending
+	executed  map[string]uint64
+	stable    uint64
+	nextError error
+	// broadcast the transaction but return the error, like a connection dropped before the response
+	errorAfterBroadcast bool
+}
+
+func (f *fakeWallet) GetNonce() (uint64, error) {
+	return f.nonce, nil
+}
+
+func (f *fakeWallet) BuildTransaction(params wallet.BuildTransactionParams) (tx wallet.TransactionResponse, err error) {
+	if f.nextError != nil && !f.errorAfterBroadcast {
+		err = f.nextError
+		f.nextError = nil
+		return
+	}
+
+	if *params.Nonce != f.nonce {
+		err = &rpc.RPCError{Message: fmt.Sprintf("invalid nonce %d, expected %d", *params.Nonce, f.nonce)}
+		return
+	}
+
+	f.built = append(f.built, params)
+	f.nonce++
+
+	outgoing := &wallet.Outgoing{Nonce: *params.Nonce}
+	for _, transfer := range params.Transfers {
+		outgoing.Transfers = append(outgoing.Transfers, wallet.TransferOut{
+			Amount:      transfer.Amount,
+			Asset:       transfer.Asset,
+			Destination: transfer.Destination,
+		})
+	}
+
+	tx.Hash = fmt.Sprintf("tx%d", *params.Nonce)
+	f.pending = append(f.pending, wallet.TransactionPending{Hash: tx.Hash, Outgoing: outgoing})
+
+	if f.nextError != nil {
+		err = f.nextError
+		f.nextError = nil
+		f.errorAfterBroadcast = false
+	}
+
+	return
+}
+
+func (f *fakeWallet) GetTransaction(params wallet.GetTransactionParams) (tx wallet.TransactionEntry, err error) {
+	topoheight, ok := f.executed[params.Hash]
+	if !ok {
+		err = &rpc.RPCError{Message: "transaction not found"}
+		return
+	}
+
+	tx.Hash = params.Hash
+	tx.Topoheight = topoheight
+	return
+}
+
+func (f *fakeWallet) GetPendingTransactions() ([]wallet.TransactionPending, error) {
+	return f.pending, nil
+}
+
+func (f *fakeWallet) ListTransactions(params wallet.ListTransactionsParams) ([]wallet.TransactionEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeWallet) NetworkInfo() (wallet.NetworkInfoResult, error) {
+	return wallet.NetworkInfoResult{StableTopoheight: f.stable, Network: config.Testnet}, nil
+}
+
+func newPayouts(count int) (payouts []Payout) {
+	for i := 0; i < count; i++ {
+		payouts = append(payouts, Payout{
+			ID:          fmt.Sprintf("round1_miner%d", i),
+			Destination: TESTING_ADDR,
+			Amount:      uint64(i + 1),
+		})
+	}
+
+	return
+}
+
+func TestPayoutPacking(t *testing.T) {
+	fake := &fakeWallet{nonce: 7, executed: make(map[string]uint64)}
+	engine, err := NewEngine(fake, filepath.Join(t.TempDir(), "journal"), Options{MaxTransfersPerTx: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	err = engine.Submit(newPayouts(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// submitting the same round twice must not pay twice
+	err = engine.Submit(newPayouts(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.built) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(fake.built))
+	}
+
+	for i, params := range fake.built {
+		if *params.Nonce != uint64(7+i) {
+			t.Fatalf("expected nonce %d, got %d", 7+i, *params.Nonce)
+		}
+	}
+
+	fake.executed["tx7"] = 10
+	fake.executed["tx8"] = 11
+	fake.stable = 10
+
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	states := map[BatchState]int{}
+	for _, batch := range engine.Batches() {
+		states[batch.State]++
+	}
+
+	if states[BatchConfirmed] != 1 || states[BatchBroadcast] != 2 {
+		t.Fatalf("unexpected batch states %+v", states)
+	}
+}
+
+func TestPayoutRecovery(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "journal")
+	fake := &fakeWallet{executed: make(map[string]uint64)}
+
+	engine, err := NewEngine(fake, journal, Options{MaxRetries: 1, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = engine.Submit(newPayouts(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// connection lost after the wallet broadcasted the transaction
+	fake.nextError = errors.New("connection reset by peer")
+	fake.errorAfterBroadcast = true
+	err = engine.ProcessOnce()
+	if err == nil {
+		t.Fatal("expected connection error")
+	}
+
+	engine.Close()
+
+	// restart from the journal
+	engine, err = NewEngine(fake, journal, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.built) != 1 {
+		t.Fatalf("expected a single transaction, got %d", len(fake.built))
+	}
+
+	batch, err := engine.Status("round1_miner0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if batch == nil || batch.State != BatchBroadcast || batch.Hash != "tx0" {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+}
+
+func TestPayoutRejected(t *testing.T) {
+	fake := &fakeWallet{executed: make(map[string]uint64)}
+	engine, err := NewEngine(fake, filepath.Join(t.TempDir(), "journal"), Options{RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	err = engine.Submit(newPayouts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the wallet may answer an error after the node accepted the transaction
+	fake.nextError = &rpc.RPCError{Message: "insufficient balance"}
+	err = engine.ProcessOnce()
+	if err == nil {
+		t.Fatal("expected insufficient balance error")
+	}
+
+	batch, _ := engine.Status("round1_miner0")
+	if engine.Pending() != 0 || batch == nil || batch.State != BatchPending {
+		t.Fatalf("expected the batch to stay pending, got %+v", batch)
+	}
+
+	// not found in the history, sent again with the same nonce
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.built) != 1 || *fake.built[0].Nonce != 0 {
+		t.Fatalf("unexpected transactions %+v", fake.built)
+	}
+}
+
+func TestPayoutRejectedLocally(t *testing.T) {
+	fake := &fakeWallet{executed: make(map[string]uint64)}
+	engine, err := NewEngine(fake, filepath.Join(t.TempDir(), "journal"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	err = engine.Submit([]Payout{{ID: "mainnet", Destination: MAINNET_ADDR, Amount: 1}})
+	if !errors.Is(err, ErrInvalidPayout) {
+		t.Fatalf("expected %s, got %v", ErrInvalidPayout, err)
+	}
+
+	err = engine.Submit(newPayouts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.nextError = wallet.ErrNetworkMismatch
+	err = engine.ProcessOnce()
+	if !errors.Is(err, wallet.ErrNetworkMismatch) {
+		t.Fatalf("expected %s, got %v", wallet.ErrNetworkMismatch, err)
+	}
+
+	if engine.Pending() != 2 {
+		t.Fatalf("expected payouts back in queue, got %d", engine.Pending())
+	}
+
+	// nonce of the failed batch is reused
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.built) != 1 || *fake.built[0].Nonce != 0 {
+		t.Fatalf("unexpected transactions %+v", fake.built)
+	}
+}
+
+func TestPayoutRPCErrorAfterBroadcast(t *testing.T) {
+	fake := &fakeWallet{executed: make(map[string]uint64)}
+	engine, err := NewEngine(fake, filepath.Join(t.TempDir(), "journal"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	err = engine.Submit(newPayouts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// broadcasted and the wallet nonce advanced, but the wallet answered an error
+	fake.nextError = &rpc.RPCError{Message: "error while submitting transaction"}
+	fake.errorAfterBroadcast = true
+	err = engine.ProcessOnce()
+	if err == nil {
+		t.Fatal("expected the wallet error")
+	}
+
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, _ := engine.Status("round1_miner0")
+	if len(fake.built) != 1 || batch.State != BatchBroadcast || batch.Hash != "tx0" {
+		t.Fatalf("expected a single transaction, got %d and %+v", len(fake.built), batch)
+	}
+}
+
+func TestPayoutDropped(t *testing.T) {
+	fake := &fakeWallet{executed: make(map[string]uint64)}
+	engine, err := NewEngine(fake, filepath.Join(t.TempDir(), "journal"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	err = engine.Submit(newPayouts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dropped from the mempool, the wallet is back to the previous nonce
+	fake.pending = nil
+	fake.nonce = 0
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, _ := engine.Status("round1_miner0")
+	if batch.State != BatchPending {
+		t.Fatalf("expected the dropped batch to be pending, got %+v", batch)
+	}
+
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, _ = engine.Status("round1_miner0")
+	if len(fake.built) != 2 || *fake.built[1].Nonce != 0 || batch.State != BatchBroadcast {
+		t.Fatalf("expected the batch sent again with its nonce, got %+v", batch)
+	}
+}
+
+func TestPayoutRetryAfterBroadcast(t *testing.T) {
+	fake := &fakeWallet{executed: make(map[string]uint64)}
+	engine, err := NewEngine(fake, filepath.Join(t.TempDir(), "journal"), Options{MaxRetries: 3, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	err = engine.Submit(newPayouts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first attempt is broadcasted but times out, the retry is rejected as the nonce is used
+	fake.nextError = errors.New("i/o timeout")
+	fake.errorAfterBroadcast = true
+	err = engine.ProcessOnce()
+	if err == nil {
+		t.Fatal("expected the rejected retry error")
+	}
+
+	if engine.Pending() != 0 {
+		t.Fatalf("expected payouts to stay in their batch, got %d queued", engine.Pending())
+	}
+
+	batch, err := engine.Status("round1_miner0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if batch == nil || batch.State != BatchPending {
+		t.Fatalf("expected a pending batch, got %+v", batch)
+	}
+
+	err = engine.ProcessOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.built) != 1 {
+		t.Fatalf("expected a single transaction, got %d", len(fake.built))
+	}
+
+	batch, _ = engine.Status("round1_miner0")
+	if batch.State != BatchBroadcast || batch.Hash != "tx0" {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+}
@@ -0,0 +1,76 @@
+// Package pow is a CPU reference implementation of xelis-hash to verify shares and test miners,
+// it favors readability over hashrate.
+package pow
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported pow algorithm")
+
+func ErrAlgorithm(algorithm daemon.AlgorithmVersion) error {
+	return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+}
+
+// Supported is true if the algorithm of the job can be hashed by this package,
+// xel/v3 is not implemented yet
+func Supported(algorithm daemon.AlgorithmVersion) bool {
+	switch algorithm {
+	case daemon.AlgorithmV1, daemon.AlgorithmV2:
+		return true
+	default:
+		return false
+	}
+}
+
+// Hasher keeps the scratch pads between hashes, it must not be used concurrently
+type Hasher struct {
+	v1 *v1ScratchPad
+	v2 *v2ScratchPad
+}
+
+func NewHasher() *Hasher {
+	return &Hasher{}
+}
+
+// Hash returns the pow hash of the miner work for the algorithm of the job
+func (h *Hasher) Hash(algorithm daemon.AlgorithmVersion, minerWork []byte) (hash [32]byte, err error) {
+	if len(minerWork) != work.Size {
+		err = fmt.Errorf("%w: %d", work.ErrInvalidSize, len(minerWork))
+		return
+	}
+
+	switch algorithm {
+	case daemon.AlgorithmV1:
+		if h.v1 == nil {
+			h.v1 = new(v1ScratchPad)
+		}
+
+		hash = hashV1(minerWork, h.v1)
+	case daemon.AlgorithmV2:
+		if h.v2 == nil {
+			h.v2 = new(v2ScratchPad)
+		}
+
+		hash = hashV2(minerWork, h.v2)
+	default:
+		err = ErrAlgorithm(algorithm)
+	}
+
+	return
+}
+
+var hashers = sync.Pool{New: func() interface{} { return NewHasher() }}
+
+// Hash is safe for concurrent use and can be used as the getwork server Hasher
+func Hash(algorithm daemon.AlgorithmVersion, minerWork []byte) (hash [32]byte, err error) {
+	h := hashers.Get().(*Hasher)
+	defer hashers.Put(h)
+
+	return h.Hash(algorithm, minerWork)
+}
@@ -0,0 +1,257 @@
+package pow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/getwork/work"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/sha3"
+)
+
+// keccak-p with all the rounds is keccak-f, checked with the legacy keccak256 of an empty input
+func TestKeccakP(t *testing.T) {
+	var state [keccakWords]uint64
+	state[0] ^= 0x01
+	state[16] ^= 0x80 << 56
+	keccakP(&state, 24)
+
+	var sum [32]byte
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(sum[i*8:], state[i])
+	}
+
+	expected := sha3.NewLegacyKeccak256().Sum(nil)
+	if !bytes.Equal(sum[:], expected) {
+		t.Fatalf("expected %x, got %x", expected, sum)
+	}
+}
+
+// first round of the cipher example of FIPS-197 appendix B
+func TestAESRound(t *testing.T) {
+	var block, key [16]byte
+	hex.Decode(block[:], []byte("193de3bea0f4e22b9ac68d2ae9f84808"))
+	hex.Decode(key[:], []byte("a0fafe1788542cb123a339392a6c7605"))
+
+	aesRound(&block, &key)
+	if hex.EncodeToString(block[:]) != "a49c7ff2689f352b6b5bea43026a5049" {
+		t.Fatalf("unexpected round output %x", block)
+	}
+}
+
+func TestChaCha(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	rand.Read(key[:])
+	rand.Read(nonce[:])
+
+	output := make([]byte, 64*5)
+	chachaKeyStream(20, &key, &nonce, output)
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := make([]byte, len(output))
+	cipher.XORKeyStream(expected, expected)
+	if !bytes.Equal(output, expected) {
+		t.Fatal("keystream doesn't match chacha20")
+	}
+}
+
+func TestU128(t *testing.T) {
+	toBig := func(v u128) *big.Int {
+		value := new(big.Int).SetUint64(v.hi)
+		value.Lsh(value, 64)
+		return value.Or(value, new(big.Int).SetUint64(v.lo))
+	}
+
+	mod := new(big.Int).Lsh(big.NewInt(1), 128)
+	random := func() (v u128) {
+		v = u128{rand.Uint64(), rand.Uint64()}
+		// small values take the other division paths
+		switch rand.Intn(4) {
+		case 0:
+			v.hi = 0
+		case 1:
+			v.hi >>= rand.Intn(64)
+		}
+
+		return
+	}
+
+	for i := 0; i < 100000; i++ {
+		a, b := random(), random()
+		if b == (u128{}) {
+			continue
+		}
+
+		product := new(big.Int).Mul(toBig(a), toBig(b))
+		if toBig(a.mul(b)).Cmp(product.Mod(product, mod)) != 0 {
+			t.Fatalf("%v * %v", a, b)
+		}
+
+		q, r := a.divMod(b)
+		expectedQ, expectedR := new(big.Int).QuoRem(toBig(a), toBig(b), new(big.Int))
+		if toBig(q).Cmp(expectedQ) != 0 || toBig(r).Cmp(expectedR) != 0 {
+			t.Fatalf("%v / %v: got %v %v", a, b, q, r)
+		}
+
+		if a.cmp(b) != toBig(a).Cmp(toBig(b)) {
+			t.Fatalf("cmp %v %v", a, b)
+		}
+	}
+}
+
+func TestIsqrt(t *testing.T) {
+	values := map[uint64]uint64{0: 0, 1: 1, 3: 1, 4: 2, 99: 9, 100: 10, math.MaxUint64: math.MaxUint32}
+	for n, expected := range values {
+		if isqrt(n) != expected {
+			t.Fatalf("isqrt(%d) = %d", n, isqrt(n))
+		}
+	}
+
+	for i := 0; i < 10000; i++ {
+		n := rand.Uint64() >> rand.Intn(64)
+		root := isqrt(n)
+		if root*root > n || (root+1 <= math.MaxUint32 && (root+1)*(root+1) <= n) {
+			t.Fatalf("isqrt(%d) = %d", n, root)
+		}
+	}
+}
+
+func testWork() []byte {
+	minerWork := make([]byte, work.Size)
+	for i := range minerWork {
+		minerWork[i] = byte(i)
+	}
+
+	return minerWork
+}
+
+// regression vectors produced by this package, they are not from the reference implementation
+var regressionVectors = []struct {
+	algorithm daemon.AlgorithmVersion
+	zero      string
+	counting  string
+}{
+	{daemon.AlgorithmV1, "0ebbbd8a31edadfe098f2d770d84b719588675ab88a0a17067d00a8f36182265", "06041f0195b572d8d64c83bea07b8871f56f4d003a437257b8722d79b584577a"},
+	{daemon.AlgorithmV2, "d8b0d4eb914181ae80b613822e5dad07e62bef48796983792d98782fcd4cb603", "b443905380002680e2f92cf3fa29b8ad56c0653e8dd023a87bccbc43578c2a03"},
+}
+
+func TestHash(t *testing.T) {
+	hasher := NewHasher()
+	for _, v := range regressionVectors {
+		zero, err := hasher.Hash(v.algorithm, make([]byte, work.Size))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counting, err := Hash(v.algorithm, testWork())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(zero[:]) != v.zero || hex.EncodeToString(counting[:]) != v.counting {
+			t.Fatalf("%s: got %x and %x", v.algorithm, zero, counting)
+		}
+
+		// the scratch pad of the previous hash must not change the next one
+		again, _ := hasher.Hash(v.algorithm, make([]byte, work.Size))
+		if again != zero {
+			t.Fatalf("%s: hash depends on the previous one", v.algorithm)
+		}
+	}
+
+	_, err := Hash(daemon.AlgorithmV3, testWork())
+	if !errors.Is(err, ErrUnsupportedAlgorithm) || Supported(daemon.AlgorithmV3) {
+		t.Fatalf("expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+
+	_, err = Hash(daemon.AlgorithmV2, testWork()[1:])
+	if !errors.Is(err, work.ErrInvalidSize) {
+		t.Fatalf("expected ErrInvalidSize, got %v", err)
+	}
+}
+
+type referenceVector struct {
+	Algorithm daemon.AlgorithmVersion `json:"algorithm"`
+	MinerWork string                  `json:"miner_work"`
+	Hash      string                  `json:"hash"`
+}
+
+// TestReferenceVectors checks hashes computed by the xelis-hash crate,
+// each vector is the hex miner work and its pow hash, every algorithm must have some
+func TestReferenceVectors(t *testing.T) {
+	b, err := os.ReadFile("testdata/reference.json")
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatal("missing testdata/reference.json, hashes must be checked against xelis-hash, see testdata/README.md")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors []referenceVector
+	err = json.Unmarshal(b, &vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, algorithm := range []daemon.AlgorithmVersion{daemon.AlgorithmV1, daemon.AlgorithmV2, daemon.AlgorithmV3} {
+		found := false
+		for _, v := range vectors {
+			found = found || v.Algorithm == algorithm
+		}
+
+		if !found {
+			t.Errorf("no reference vector for %s", algorithm)
+		}
+	}
+
+	for i, v := range vectors {
+		minerWork, err := hex.DecodeString(v.MinerWork)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := Hash(v.Algorithm, minerWork)
+		if err != nil {
+			t.Fatalf("vector %d: %s", i, err)
+		}
+
+		if hex.EncodeToString(hash[:]) != v.Hash {
+			t.Fatalf("vector %d: expected %s, got %x", i, v.Hash, hash)
+		}
+	}
+}
+
+func benchmarkHash(b *testing.B, algorithm daemon.AlgorithmVersion) {
+	hasher := NewHasher()
+	minerWork := testWork()
+	for i := 0; i < b.N; i++ {
+		binary.BigEndian.PutUint64(minerWork[work.NonceOffset:], uint64(i))
+		_, err := hasher.Hash(algorithm, minerWork)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHashV1(b *testing.B) {
+	benchmarkHash(b, daemon.AlgorithmV1)
+}
+
+func BenchmarkHashV2(b *testing.B) {
+	benchmarkHash(b, daemon.AlgorithmV2)
+}
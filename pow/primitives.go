@@ -0,0 +1,250 @@
+package pow
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const keccakWords = 25
+
+// round constants of keccak-f[1600], keccak-p[1600, 12] uses the last 12
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+var keccakRotations = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+var keccakLanes = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+
+func keccakP12(state *[keccakWords]uint64) {
+	keccakP(state, 12)
+}
+
+// keccakP applies the last rounds of keccak-f[1600]
+func keccakP(state *[keccakWords]uint64, rounds int) {
+	var bc [5]uint64
+	for round := 24 - rounds; round < 24; round++ {
+		// theta
+		for i := 0; i < 5; i++ {
+			bc[i] = state[i] ^ state[i+5] ^ state[i+10] ^ state[i+15] ^ state[i+20]
+		}
+
+		for i := 0; i < 5; i++ {
+			t := bc[(i+4)%5] ^ bits.RotateLeft64(bc[(i+1)%5], 1)
+			for j := 0; j < keccakWords; j += 5 {
+				state[j+i] ^= t
+			}
+		}
+
+		// rho and pi
+		t := state[1]
+		for i, lane := range keccakLanes {
+			t, state[lane] = state[lane], bits.RotateLeft64(t, keccakRotations[i])
+		}
+
+		// chi
+		for j := 0; j < keccakWords; j += 5 {
+			copy(bc[:], state[j:j+5])
+			for i := 0; i < 5; i++ {
+				state[j+i] ^= ^bc[(i+1)%5] & bc[(i+2)%5]
+			}
+		}
+
+		// iota
+		state[0] ^= keccakRoundConstants[round]
+	}
+}
+
+var aesSbox = func() (sbox [256]byte) {
+	for x := 0; x < 256; x++ {
+		// multiplicative inverse as x^254, 0 stays 0
+		inverse := byte(1)
+		for i := 0; i < 254; i++ {
+			inverse = gfMul(inverse, byte(x))
+		}
+
+		if x == 0 {
+			inverse = 0
+		}
+
+		s := inverse
+		for i := 1; i <= 4; i++ {
+			s ^= bits.RotateLeft8(inverse, i)
+		}
+
+		sbox[x] = s ^ 0x63
+	}
+
+	return
+}()
+
+func gfMul(a, b byte) (product byte) {
+	for b != 0 {
+		if b&1 != 0 {
+			product ^= a
+		}
+
+		a = xtime(a)
+		b >>= 1
+	}
+
+	return
+}
+
+func xtime(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+
+	return a << 1
+}
+
+// aesRound is one AES encryption round: SubBytes, ShiftRows, MixColumns and AddRoundKey
+func aesRound(block *[16]byte, key *[16]byte) {
+	var state [16]byte
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			state[r+4*c] = aesSbox[block[r+4*((c+r)%4)]]
+		}
+	}
+
+	for c := 0; c < 4; c++ {
+		a0, a1, a2, a3 := state[4*c], state[4*c+1], state[4*c+2], state[4*c+3]
+		all := a0 ^ a1 ^ a2 ^ a3
+		block[4*c] = a0 ^ all ^ xtime(a0^a1) ^ key[4*c]
+		block[4*c+1] = a1 ^ all ^ xtime(a1^a2) ^ key[4*c+1]
+		block[4*c+2] = a2 ^ all ^ xtime(a2^a3) ^ key[4*c+2]
+		block[4*c+3] = a3 ^ all ^ xtime(a3^a0) ^ key[4*c+3]
+	}
+}
+
+// chachaKeyStream writes the keystream of the IETF variant with a block counter starting at 0
+func chachaKeyStream(rounds int, key *[32]byte, nonce *[12]byte, output []byte) {
+	var initial [16]uint32
+	initial[0], initial[1], initial[2], initial[3] = 0x61707865, 0x3320646e, 0x79622d32, 0x6b206574
+	for i := 0; i < 8; i++ {
+		initial[4+i] = binary.LittleEndian.Uint32(key[i*4:])
+	}
+
+	for i := 0; i < 3; i++ {
+		initial[13+i] = binary.LittleEndian.Uint32(nonce[i*4:])
+	}
+
+	var block [64]byte
+	for offset := 0; offset < len(output); offset += 64 {
+		x := initial
+		for i := 0; i < rounds; i += 2 {
+			quarterRound(&x, 0, 4, 8, 12)
+			quarterRound(&x, 1, 5, 9, 13)
+			quarterRound(&x, 2, 6, 10, 14)
+			quarterRound(&x, 3, 7, 11, 15)
+			quarterRound(&x, 0, 5, 10, 15)
+			quarterRound(&x, 1, 6, 11, 12)
+			quarterRound(&x, 2, 7, 8, 13)
+			quarterRound(&x, 3, 4, 9, 14)
+		}
+
+		for i := range x {
+			binary.LittleEndian.PutUint32(block[i*4:], x[i]+initial[i])
+		}
+
+		copy(output[offset:], block[:])
+		initial[12]++
+	}
+}
+
+func quarterRound(x *[16]uint32, a, b, c, d int) {
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+}
+
+// isqrt is the integer square root rounded down
+func isqrt(n uint64) uint64 {
+	if n < 2 {
+		return n
+	}
+
+	x := n
+	y := x / 2
+	for y < x {
+		x = y
+		y = (x + n/x) / 2
+	}
+
+	return x
+}
+
+type u128 struct {
+	hi, lo uint64
+}
+
+func (a u128) cmp(b u128) int {
+	switch {
+	case a.hi > b.hi || (a.hi == b.hi && a.lo > b.lo):
+		return 1
+	case a == b:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// mul wraps around like the u128 multiplication of rust
+func (a u128) mul(b u128) u128 {
+	hi, lo := bits.Mul64(a.lo, b.lo)
+	hi += a.hi*b.lo + a.lo*b.hi
+	return u128{hi, lo}
+}
+
+func (a u128) sub(b u128) u128 {
+	lo, borrow := bits.Sub64(a.lo, b.lo, 0)
+	hi, _ := bits.Sub64(a.hi, b.hi, borrow)
+	return u128{hi, lo}
+}
+
+// divMod divides by a non zero divisor
+func (a u128) divMod(b u128) (q u128, r u128) {
+	if b.hi == 0 {
+		q.hi = a.hi / b.lo
+		q.lo, r.lo = bits.Div64(a.hi%b.lo, a.lo, b.lo)
+		return
+	}
+
+	// the quotient fits in 64 bits, estimated from the normalized divisor (Hacker's Delight divlu)
+	n := bits.LeadingZeros64(b.hi)
+	v1 := b.hi<<n | b.lo>>(64-n)
+	q1, _ := bits.Div64(a.hi>>1, a.hi<<63|a.lo>>1, v1)
+	q1 >>= 63 - n
+	if q1 != 0 {
+		q1--
+	}
+
+	r = a.sub(b.mul(u128{0, q1}))
+	if r.cmp(b) >= 0 {
+		q1++
+		r = r.sub(b)
+	}
+
+	q.lo = q1
+	return
+}
+
+func (a u128) div(b u128) u128 {
+	q, _ := a.divMod(b)
+	return q
+}
+
+func (a u128) mod(b u128) u128 {
+	_, r := a.divMod(b)
+	return r
+}
@@ -0,0 +1,198 @@
+package pow
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// xel/v1 parameters
+const (
+	v1MemorySize      = 32768
+	v1ScratchPadIters = 5000
+	v1BufferSize      = 42
+	v1SlotLength      = 256
+	v1InputSize       = keccakWords * 8
+	v1Stage1Max       = v1MemorySize / keccakWords
+)
+
+type v1ScratchPad struct {
+	pad   [v1MemorySize]uint64
+	small [v1MemorySize * 2]uint32
+}
+
+// hashV1 pads the miner work with zeros to the 200 bytes of the keccak state
+func hashV1(minerWork []byte, scratchPad *v1ScratchPad) (hash [32]byte) {
+	var input [v1InputSize]byte
+	copy(input[:], minerWork)
+
+	var state [keccakWords]uint64
+	for i := range state {
+		state[i] = binary.LittleEndian.Uint64(input[i*8:])
+	}
+
+	pad := &scratchPad.pad
+
+	// stage 1: fill the scratch pad from the keccak state, the last row is partial
+	for row := 0; row < v1Stage1Max; row++ {
+		v1Stage1Row(&state, pad, row, keccakWords)
+	}
+	v1Stage1Row(&state, pad, v1Stage1Max, v1MemorySize-v1Stage1Max*keccakWords)
+
+	// stage 2: the scratch pad read as u32 slots
+	small := &scratchPad.small
+	for i, value := range pad {
+		small[i*2] = uint32(value)
+		small[i*2+1] = uint32(value >> 32)
+	}
+
+	var slots [v1SlotLength]uint32
+	copy(slots[:], small[len(small)-v1SlotLength:])
+
+	var indices [v1SlotLength]uint16
+	for j := 0; j < len(small)/v1SlotLength; j++ {
+		row := small[j*v1SlotLength : (j+1)*v1SlotLength]
+
+		// sum of the pad values added or subtracted depending on the sign of their slot
+		var total uint32
+		for k := range indices {
+			indices[k] = uint16(k)
+			if slots[k]>>31 == 0 {
+				total += row[k]
+			} else {
+				total -= row[k]
+			}
+		}
+
+		for slotIdx := v1SlotLength - 1; slotIdx >= 0; slotIdx-- {
+			indexInIndices := row[slotIdx] % uint32(slotIdx+1)
+			index := indices[indexInIndices]
+			indices[indexInIndices] = indices[slotIdx]
+
+			// the sum without the slot itself
+			sum := total
+			s1 := slots[index] >> 31
+			if s1 == 0 {
+				sum -= row[index]
+			} else {
+				sum += row[index]
+			}
+
+			slots[index] += sum
+
+			s2 := slots[index] >> 31
+			total -= 2 * row[index] * (s2 - s1)
+		}
+	}
+
+	copy(small[len(small)-v1SlotLength:], slots[:])
+	for i := range pad {
+		pad[i] = uint64(small[i*2]) | uint64(small[i*2+1])<<32
+	}
+
+	// stage 3
+	var key [16]byte
+	var block [16]byte
+
+	addrA := (pad[v1MemorySize-1] >> 15) & 0x7FFF
+	addrB := pad[v1MemorySize-1] & 0x7FFF
+
+	var memA, memB [v1BufferSize]uint64
+	for i := uint64(0); i < v1BufferSize; i++ {
+		memA[i] = pad[(addrA+i)%v1MemorySize]
+		memB[i] = pad[(addrB+i)%v1MemorySize]
+	}
+
+	for i := 0; i < v1ScratchPadIters; i++ {
+		a := memA[i%v1BufferSize]
+		b := memB[i%v1BufferSize]
+
+		binary.LittleEndian.PutUint64(block[:8], b)
+		binary.LittleEndian.PutUint64(block[8:], a)
+		aesRound(&block, &key)
+
+		hash1 := binary.LittleEndian.Uint64(block[:8])
+		hash2 := a ^ b
+		result := ^(hash1 ^ hash2)
+
+		for j := 0; j < 32; j++ {
+			a := memA[(j+i)%v1BufferSize]
+			b := memB[(j+i)%v1BufferSize]
+
+			switch (result >> (j * 2)) & 0xf {
+			case 0:
+				result = bits.RotateLeft64(result, j) ^ b
+			case 1:
+				result = ^(bits.RotateLeft64(result, j) ^ a)
+			case 2:
+				result = ^(result ^ a)
+			case 3:
+				result = result ^ b
+			case 4:
+				result = result ^ (a + b)
+			case 5:
+				result = result ^ (a - b)
+			case 6:
+				result = result ^ (b - a)
+			case 7:
+				result = result ^ (a * b)
+			case 8:
+				result = result ^ (a & b)
+			case 9:
+				result = result ^ (a | b)
+			case 10:
+				result = result ^ (a ^ b)
+			case 11:
+				result = result ^ (a - result)
+			case 12:
+				result = result ^ (b - result)
+			case 13:
+				result = result ^ (a + result)
+			case 14:
+				result = result ^ (result - a)
+			case 15:
+				result = result ^ (result - b)
+			}
+		}
+
+		addrB = result & 0x7FFF
+		memA[i%v1BufferSize] = result
+		memB[i%v1BufferSize] = pad[addrB]
+
+		addrA = (result >> 15) & 0x7FFF
+		pad[addrA] = result
+
+		// the last 4 results are the hash, the very last one first
+		index := v1ScratchPadIters - i - 1
+		if index < 4 {
+			binary.BigEndian.PutUint64(hash[index*8:], result)
+		}
+	}
+
+	return
+}
+
+func v1Stage1Row(state *[keccakWords]uint64, pad *[v1MemorySize]uint64, row int, words int) {
+	keccakP12(state)
+
+	var randInt uint64
+	for j := 0; j < words; j++ {
+		left := state[(j+1)%keccakWords]
+		right := state[(j+2)%keccakWords]
+		xor := left ^ right
+
+		var v uint64
+		switch xor & 0x3 {
+		case 0:
+			v = left & right
+		case 1:
+			v = ^(left & right)
+		case 2:
+			v = ^xor
+		case 3:
+			v = xor
+		}
+
+		randInt = state[j] ^ randInt ^ v
+		pad[row*keccakWords+j] = randInt
+	}
+}
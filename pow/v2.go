@@ -0,0 +1,176 @@
+package pow
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/zeebo/blake3"
+)
+
+// xel/v2 parameters
+const (
+	v2MemorySize      = 429 * 128
+	v2ScratchPadIters = 3
+	v2BufferSize      = v2MemorySize / 2
+	v2ChunkSize       = 32
+	v2NonceSize       = 12
+	v2OutputSize      = v2MemorySize * 8
+)
+
+var v2Key = [16]byte{'x', 'e', 'l', 'i', 's', 'h', 'a', 's', 'h', '-', 'p', 'o', 'w', '-', 'v', '2'}
+
+type v2ScratchPad struct {
+	bytes [v2OutputSize]byte
+	pad   [v2MemorySize]uint64
+}
+
+// hashV2 is the blake3 hash of the scratch pad filled by stage 1 and mixed by stage 3
+func hashV2(minerWork []byte, scratchPad *v2ScratchPad) [32]byte {
+	v2Stage1(minerWork, &scratchPad.bytes)
+
+	pad := &scratchPad.pad
+	for i := range pad {
+		pad[i] = binary.LittleEndian.Uint64(scratchPad.bytes[i*8:])
+	}
+
+	v2Stage3(pad)
+
+	for i, value := range pad {
+		binary.LittleEndian.PutUint64(scratchPad.bytes[i*8:], value)
+	}
+
+	return blake3.Sum256(scratchPad.bytes[:])
+}
+
+// v2Stage1 fills the output with a chacha8 keystream per input chunk,
+// each key is chained from the previous one and each nonce is the end of the previous keystream
+func v2Stage1(input []byte, output *[v2OutputSize]byte) {
+	inputHash := blake3.Sum256(input)
+
+	var nonce [v2NonceSize]byte
+	copy(nonce[:], inputHash[:])
+
+	chunks := (len(input) + v2ChunkSize - 1) / v2ChunkSize
+	offset := 0
+	for chunkIndex := 0; chunkIndex < chunks; chunkIndex++ {
+		chunk := input[chunkIndex*v2ChunkSize:]
+		if len(chunk) > v2ChunkSize {
+			chunk = chunk[:v2ChunkSize]
+		}
+
+		var tmp [64]byte
+		copy(tmp[:32], inputHash[:])
+		copy(tmp[32:], chunk)
+		inputHash = blake3.Sum256(tmp[:])
+
+		remaining := v2OutputSize - offset
+		size := remaining / (chunks - chunkIndex)
+
+		part := output[offset : offset+size]
+		chachaKeyStream(8, &inputHash, &nonce, part)
+		offset += size
+
+		copy(nonce[:], part[len(part)-v2NonceSize:])
+	}
+}
+
+func v2Stage3(pad *[v2MemorySize]uint64) {
+	memA := pad[:v2BufferSize]
+	memB := pad[v2BufferSize:]
+
+	addrA := memB[v2BufferSize-1]
+	addrB := memA[v2BufferSize-1] >> 32
+	r := 0
+
+	var block [16]byte
+	for i := 0; i < v2ScratchPadIters; i++ {
+		a := memA[addrA%v2BufferSize]
+		b := memB[addrB%v2BufferSize]
+
+		binary.LittleEndian.PutUint64(block[:8], b)
+		binary.LittleEndian.PutUint64(block[8:], a)
+		aesRound(&block, &v2Key)
+
+		hash1 := binary.LittleEndian.Uint64(block[:8])
+		hash2 := binary.LittleEndian.Uint64(block[8:])
+		result := ^(hash1 ^ hash2)
+
+		for j := 0; j < v2BufferSize; j++ {
+			a := memA[result%v2BufferSize]
+			b := memB[^bits.RotateLeft64(result, -r)%v2BufferSize]
+
+			var c uint64
+			if r < v2BufferSize {
+				c = memA[r]
+			} else {
+				c = memB[r-v2BufferSize]
+			}
+
+			if r < v2MemorySize-1 {
+				r++
+			} else {
+				r = 0
+			}
+
+			result ^= v2Branch(result, a, b, c, r, i*j)
+			result = bits.RotateLeft64(result, 1)
+
+			t := memA[v2BufferSize-j-1] ^ result
+			memA[v2BufferSize-j-1] = t
+			memB[j] ^= bits.RotateLeft64(t, -int(result&63))
+		}
+
+		addrA = result
+		addrB = isqrt(result)
+	}
+}
+
+// v2Branch is the operation picked by the result, r is the next scratch pad index
+func v2Branch(result, a, b, c uint64, r int, ij int) uint64 {
+	switch bits.RotateLeft64(result, int(c&63)) & 0xf {
+	case 0:
+		return bits.RotateLeft64(c, ij) ^ b
+	case 1:
+		return bits.RotateLeft64(c, -ij) ^ a
+	case 2:
+		return a ^ b ^ c
+	case 3:
+		return (a + b) * c
+	case 4:
+		return (b - c) * a
+	case 5:
+		return c - a + b
+	case 6:
+		return a - b + c
+	case 7:
+		return b*c + a
+	case 8:
+		return c*a + b
+	case 9:
+		return a * b * c
+	case 10:
+		return u128{a, b}.mod(u128{0, c | 1}).lo
+	case 11:
+		t1 := u128{b, c}
+		t2 := u128{bits.RotateLeft64(result, r), a | 2}
+		if t2.cmp(t1) > 0 {
+			return c
+		}
+
+		return t1.mod(t2).lo
+	case 12:
+		return u128{c, a}.div(u128{0, b | 4}).lo
+	case 13:
+		t1 := u128{bits.RotateLeft64(result, r), b}
+		t2 := u128{a, c | 8}
+		if t1.cmp(t2) > 0 {
+			return t1.div(t2).lo
+		}
+
+		return a ^ b
+	case 14:
+		return u128{b, a}.mul(u128{0, c}).hi
+	default:
+		return u128{a, c}.mul(u128{bits.RotateLeft64(result, -r), b}).hi
+	}
+}
@@ -3,7 +3,6 @@ package rpc
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -142,7 +141,7 @@ func (h *Http) Request(method string, params interface{}, result interface{}) (r
 	}
 
 	if rpcResponse.Error != nil {
-		err = errors.New(rpcResponse.Error.Message)
+		err = rpcResponse.Error
 		return
 	}
 
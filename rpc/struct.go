@@ -19,3 +19,7 @@ type RPCError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
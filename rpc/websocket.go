@@ -314,7 +314,7 @@ func (w *WebSocket) RawCall(id int64, data []byte) (res interface{}, err error)
 
 func ParseResponseResult(res RPCResponse, result any) (err error) {
 	if res.Error != nil {
-		err = fmt.Errorf(res.Error.Message)
+		err = res.Error
 		return
 	}
 
@@ -0,0 +1,89 @@
+package signature
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"github.com/gtank/ristretto255"
+)
+
+var ErrInvalidPrivateKey = errors.New("invalid private key")
+
+// PrivateKey signs like a xelis wallet, its public key is private^-1 * H
+type PrivateKey struct {
+	scalar *ristretto255.Scalar
+}
+
+func randomScalar() (scalar *ristretto255.Scalar, err error) {
+	b := make([]byte, 64)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+
+	scalar = &ristretto255.Scalar{}
+	scalar.FromUniformBytes(b)
+	return
+}
+
+func blinding() *ristretto255.Element {
+	h := &ristretto255.Element{}
+	h.FromUniformBytes(createBlinding())
+	return h
+}
+
+func NewPrivateKey() (key *PrivateKey, err error) {
+	scalar, err := randomScalar()
+	if err != nil {
+		return
+	}
+
+	key = &PrivateKey{scalar: scalar}
+	return
+}
+
+// PrivateKeyFromBytes decodes a canonical non zero scalar of 32 bytes
+func PrivateKeyFromBytes(b []byte) (key *PrivateKey, err error) {
+	scalar := &ristretto255.Scalar{}
+	if len(b) != 32 || scalar.Decode(b) != nil || scalar.Equal(ristretto255.NewScalar()) == 1 {
+		err = ErrInvalidPrivateKey
+		return
+	}
+
+	key = &PrivateKey{scalar: scalar}
+	return
+}
+
+func (k *PrivateKey) Bytes() []byte {
+	return k.scalar.Encode(nil)
+}
+
+func (k *PrivateKey) PublicKey() (publicKey [32]byte) {
+	inverted := (&ristretto255.Scalar{}).Invert(k.scalar)
+	point := (&ristretto255.Element{}).ScalarMult(inverted, blinding())
+	copy(publicKey[:], point.Encode(nil))
+	return
+}
+
+// Sign returns the hex signature s || e verified by Verify with the public key
+func (k *PrivateKey) Sign(data []byte) (signature string, err error) {
+	nonce, err := randomScalar()
+	if err != nil {
+		return
+	}
+
+	r := (&ristretto255.Element{}).ScalarMult(nonce, blinding())
+	e, err := hashAndPointToScalar(k.PublicKey(), data, r)
+	if err != nil {
+		return
+	}
+
+	// s = private^-1 * e + nonce
+	s := (&ristretto255.Scalar{}).Invert(k.scalar)
+	s.Multiply(s, e)
+	s.Add(s, nonce)
+
+	signature = hex.EncodeToString(append(s.Encode(nil), e.Encode(nil)...))
+	return
+}
@@ -0,0 +1,65 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	key, err := NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("hello xelis")
+	sig, err := key.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := Verify(key.PublicKey(), sig, data)
+	if err != nil || !valid {
+		t.Fatalf("signature not valid: %v", err)
+	}
+
+	publicKey := key.PublicKey()
+	valid, err = Verify2(hex.EncodeToString(publicKey[:]), sig, []byte("hello xelis!"))
+	if err != nil || valid {
+		t.Fatalf("signature valid for other data: %v", err)
+	}
+
+	other, err := NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, _ = Verify(other.PublicKey(), sig, data)
+	if valid {
+		t.Fatal("signature valid for other key")
+	}
+}
+
+func TestPrivateKeyFromBytes(t *testing.T) {
+	key, err := NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := PrivateKeyFromBytes(key.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decoded.Bytes(), key.Bytes()) || decoded.PublicKey() != key.PublicKey() {
+		t.Fatal("decoded key differs")
+	}
+
+	invalid := [][]byte{make([]byte, 32), make([]byte, 31), bytes.Repeat([]byte{0xff}, 32)}
+	for _, b := range invalid {
+		_, err = PrivateKeyFromBytes(b)
+		if err != ErrInvalidPrivateKey {
+			t.Fatalf("%x: %v", b, err)
+		}
+	}
+}
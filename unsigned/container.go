@@ -0,0 +1,357 @@
+package unsigned
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/multisig"
+	"github.com/xelis-project/xelis-go-sdk/transaction"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+var ErrMissingUnsigned = multisig.ErrMissingUnsigned
+var ErrHashMismatch = errors.New("params are for a different unsigned transaction")
+var ErrDuplicateSignature = multisig.ErrDuplicateSignature
+var ErrThresholdNotReached = multisig.ErrThresholdNotReached
+var ErrSummaryMismatch = errors.New("summary does not match the unsigned transaction")
+var ErrUnsortedSignatures = errors.New("signatures are not sorted by signer id")
+
+type PayloadKind uint8
+
+const (
+	KindUnknown PayloadKind = iota
+	KindTransfers
+	KindBurn
+	KindMultiSig
+	KindInvokeContract
+	KindDeployContract
+	KindBlob
+)
+
+func (k PayloadKind) String() string {
+	switch k {
+	case KindTransfers:
+		return "transfers"
+	case KindBurn:
+		return "burn"
+	case KindMultiSig:
+		return "multisig"
+	case KindInvokeContract:
+		return "invoke contract"
+	case KindDeployContract:
+		return "deploy contract"
+	case KindBlob:
+		return "blob"
+	default:
+		return "unknown"
+	}
+}
+
+type TransferSummary struct {
+	Destination  string `json:"destination"`
+	Asset        string `json:"asset"`
+	Amount       uint64 `json:"amount"`
+	HasExtraData bool   `json:"has_extra_data"`
+}
+
+// Summary is the plaintext metadata shown to the signer, amounts are encrypted in the transaction itself
+type Summary struct {
+	TxVersion uint8                 `json:"tx_version"`
+	Nonce     uint64                `json:"nonce"`
+	Fee       uint64                `json:"fee"`
+	FeeLimit  uint64                `json:"fee_limit"`
+	Reference transaction.Reference `json:"reference"`
+	// Zero if the source account is not a multisig
+	Threshold uint8             `json:"threshold"`
+	Kind      PayloadKind       `json:"kind"`
+	Transfers []TransferSummary `json:"transfers,omitempty"`
+	Burn      *transaction.Burn `json:"burn,omitempty"`
+}
+
+// Container moves an unsigned transaction between an online watch wallet and an offline signer
+type Container struct {
+	// Hash to sign, hex encoded
+	Hash string
+	// Public key of the source account
+	Source   [32]byte
+	Unsigned []byte
+	Summary  Summary
+	// Sorted by signer id
+	Signatures []wallet.SignatureId
+}
+
+// New creates a container from the wallet response and the params used to build it
+func New(res wallet.UnsignedTransactionResponse, params wallet.BuildUnsignedTransactionParams) (container *Container, err error) {
+	if res.TxAsHex == nil || *res.TxAsHex == "" {
+		err = ErrMissingUnsigned
+		return
+	}
+
+	unsignedTx, err := hex.DecodeString(*res.TxAsHex)
+	if err != nil {
+		return
+	}
+
+	source, err := uintsToBytes(res.Source)
+	if err != nil {
+		return
+	}
+
+	if len(source) != 32 {
+		err = fmt.Errorf("invalid source public key length %d", len(source))
+		return
+	}
+
+	summary := Summary{
+		TxVersion: res.Version,
+		Nonce:     res.Nonce,
+		Fee:       res.Fee,
+		FeeLimit:  res.FeeLimit,
+		Reference: res.Reference,
+		Kind:      payloadKind(params),
+		Burn:      params.Burn,
+	}
+
+	if res.Threshold != nil {
+		summary.Threshold = *res.Threshold
+	}
+
+	for _, transfer := range params.Transfers {
+		summary.Transfers = append(summary.Transfers, TransferSummary{
+			Destination:  transfer.Destination,
+			Asset:        transfer.Asset,
+			Amount:       transfer.Amount,
+			HasExtraData: transfer.ExtraData != nil,
+		})
+	}
+
+	container = &Container{
+		Hash:     res.Hash,
+		Unsigned: unsignedTx,
+		Summary:  summary,
+	}
+	copy(container.Source[:], source)
+
+	err = container.validate()
+	if err != nil {
+		container = nil
+	}
+
+	return
+}
+
+func payloadKind(params wallet.BuildUnsignedTransactionParams) PayloadKind {
+	switch {
+	case len(params.Transfers) > 0:
+		return KindTransfers
+	case params.Burn != nil:
+		return KindBurn
+	case params.MultiSig != nil:
+		return KindMultiSig
+	case params.InvokeContract != nil:
+		return KindInvokeContract
+	case params.DeployContract != nil:
+		return KindDeployContract
+	case params.Blob != nil:
+		return KindBlob
+	default:
+		return KindUnknown
+	}
+}
+
+func uintsToBytes(values []uint) (b []byte, err error) {
+	b = make([]byte, len(values))
+	for i, v := range values {
+		if v > 255 {
+			err = fmt.Errorf("invalid byte value %d at index %d", v, i)
+			return
+		}
+
+		b[i] = byte(v)
+	}
+
+	return
+}
+
+func (c *Container) validate() (err error) {
+	if len(c.Unsigned) == 0 {
+		return ErrMissingUnsigned
+	}
+
+	err = multisig.VerifyHash(c.Hash, c.Unsigned)
+	if err != nil {
+		return
+	}
+
+	err = c.verifySummary()
+	if err != nil {
+		return
+	}
+
+	if len(c.Summary.Transfers) > wallet.MaxTransfers {
+		return wallet.ErrMaxTransfers
+	}
+
+	for i, sig := range c.Signatures {
+		_, err = hex.DecodeString(sig.Signature)
+		if err != nil || len(sig.Signature) != 128 || int(sig.Id) >= wallet.MaxMultiSigParticipants {
+			return fmt.Errorf("invalid signature for signer %d", sig.Id)
+		}
+
+		if i > 0 && sig.Id == c.Signatures[i-1].Id {
+			return ErrDuplicateSignature
+		}
+
+		if i > 0 && sig.Id < c.Signatures[i-1].Id {
+			return ErrUnsortedSignatures
+		}
+	}
+
+	return
+}
+
+// verifySummary checks the summary fields found at fixed positions of the unsigned transaction:
+// version and source at the start, reference at the end.
+// Nonce, fees and transfers follow the payload and can't be checked without decoding it.
+func (c *Container) verifySummary() (err error) {
+	data, err := multisig.UnsignedData(c.Unsigned)
+	if err != nil {
+		return
+	}
+
+	// version, source and reference hash and topoheight
+	if len(data) < 1+32+32+8 {
+		return fmt.Errorf("%w: %d bytes", multisig.ErrInvalidUnsigned, len(data))
+	}
+
+	if data[0] != c.Summary.TxVersion || !bytes.Equal(data[1:33], c.Source[:]) {
+		return ErrSummaryMismatch
+	}
+
+	reference := data[len(data)-40:]
+	if hex.EncodeToString(reference[:32]) != c.Summary.Reference.Hash ||
+		binary.BigEndian.Uint64(reference[32:]) != c.Summary.Reference.Topoheight {
+		return ErrSummaryMismatch
+	}
+
+	return
+}
+
+// SignParams returns the params for SignUnsignedTransaction of the given signer
+func (c *Container) SignParams(signerId uint8) wallet.SignUnsignedTransactionParams {
+	return wallet.SignUnsignedTransactionParams{
+		Hash:     c.Hash,
+		SignerId: signerId,
+	}
+}
+
+// AddSignature stores the result of SignUnsignedTransaction, adding the same signature twice is a no-op
+func (c *Container) AddSignature(sig wallet.SignatureId) (err error) {
+	_, err = hex.DecodeString(sig.Signature)
+	if err != nil {
+		return
+	}
+
+	if len(sig.Signature) != 128 {
+		return fmt.Errorf("invalid signature length %d", len(sig.Signature))
+	}
+
+	// signer ids are indexes of the participants, the encoding counts signatures on a byte
+	if int(sig.Id) >= wallet.MaxMultiSigParticipants {
+		return fmt.Errorf("invalid signer id %d", sig.Id)
+	}
+
+	c.Signatures, err = multisig.AddSignature(c.Signatures, sig)
+	return
+}
+
+// FinalizeParams returns the params for FinalizeUnsignedTransaction
+func (c *Container) FinalizeParams(broadcast bool, txAsHex bool) (params wallet.FinalizeUnsignedTransactionParams, err error) {
+	if len(c.Signatures) < int(c.Summary.Threshold) {
+		err = ErrThresholdNotReached
+		return
+	}
+
+	params = wallet.FinalizeUnsignedTransactionParams{
+		Unsigned:  hex.EncodeToString(c.Unsigned),
+		Broadcast: broadcast,
+		TxAsHex:   txAsHex,
+	}
+
+	if c.Summary.Threshold > 0 {
+		params.Signatures = c.Signatures[:c.Summary.Threshold]
+	}
+
+	return
+}
+
+// MergeFinalizeParams adds the signatures of finalize params built for the same transaction
+func (c *Container) MergeFinalizeParams(params wallet.FinalizeUnsignedTransactionParams) (err error) {
+	if params.Unsigned != hex.EncodeToString(c.Unsigned) {
+		return ErrHashMismatch
+	}
+
+	for _, sig := range params.Signatures {
+		err = c.AddSignature(sig)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func formatAmount(asset string, amount uint64) string {
+	if asset == config.XELIS_ASSET {
+		divisor := uint64(1)
+		for i := 0; i < config.XELIS_DECIMALS; i++ {
+			divisor *= 10
+		}
+
+		return fmt.Sprintf("%d.%0*d XEL", amount/divisor, config.XELIS_DECIMALS, amount%divisor)
+	}
+
+	return fmt.Sprintf("%d atomic units of asset %s", amount, asset)
+}
+
+// Describe returns a human readable summary for the signer to review before signing.
+// Only the source, version and reference are checked against the unsigned transaction,
+// the other fields come from the params given to New and are shown as not checked.
+func (c *Container) Describe() string {
+	var b strings.Builder
+	s := c.Summary
+
+	fmt.Fprintf(&b, "Unsigned transaction %s\n", c.Hash)
+	fmt.Fprintf(&b, "Checked against the unsigned transaction:\n")
+	fmt.Fprintf(&b, "  Source: %s\n", hex.EncodeToString(c.Source[:]))
+	fmt.Fprintf(&b, "  Version: %d\n", s.TxVersion)
+	fmt.Fprintf(&b, "  Reference: %s at topoheight %d\n", s.Reference.Hash, s.Reference.Topoheight)
+	fmt.Fprintf(&b, "Not checked, as given by the wallet that built it:\n")
+	fmt.Fprintf(&b, "  Nonce: %d\n", s.Nonce)
+	fmt.Fprintf(&b, "  Fee: %s (limit %s)\n", formatAmount(config.XELIS_ASSET, s.Fee), formatAmount(config.XELIS_ASSET, s.FeeLimit))
+	fmt.Fprintf(&b, "  Payload: %s\n", s.Kind)
+
+	for i, transfer := range s.Transfers {
+		extra := ""
+		if transfer.HasExtraData {
+			extra = " with extra data"
+		}
+
+		fmt.Fprintf(&b, "    Transfer %d: %s to %s%s\n", i+1, formatAmount(transfer.Asset, transfer.Amount), transfer.Destination, extra)
+	}
+
+	if s.Burn != nil {
+		fmt.Fprintf(&b, "    Burn: %s\n", formatAmount(s.Burn.Asset, s.Burn.Amount))
+	}
+
+	if s.Threshold > 0 {
+		fmt.Fprintf(&b, "Signatures: %d/%d\n", len(c.Signatures), s.Threshold)
+	}
+
+	return b.String()
+}
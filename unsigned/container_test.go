@@ -0,0 +1,241 @@
+package unsigned
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/multisig"
+	"github.com/xelis-project/xelis-go-sdk/transaction"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	"github.com/zeebo/blake3"
+)
+
+const TESTING_ADDR = "xet:qf5u2p46jpgqmypqc2xwtq25yek2t7qhnqtdhw5kpfwcrlavs5asq0r83r7"
+
+// testUnsigned returns a version 1 unsigned transaction: version, source, payload,
+// reference and empty multisig, with the hash to sign
+func testUnsigned(source []byte, reference transaction.Reference) (unsigned []byte, hash [32]byte) {
+	unsigned = append([]byte{1}, source...)
+	unsigned = append(unsigned, []byte("payload, fee, nonce and proofs")...)
+
+	referenceHash, _ := hex.DecodeString(reference.Hash)
+	unsigned = append(unsigned, referenceHash...)
+	unsigned = binary.BigEndian.AppendUint64(unsigned, reference.Topoheight)
+
+	hash = blake3.Sum256(unsigned)
+	unsigned = append(unsigned, 0)
+	return
+}
+
+func newTestResponse() wallet.UnsignedTransactionResponse {
+	source := make([]uint, 32)
+	sourceBytes := make([]byte, 32)
+	for i := range source {
+		source[i] = uint(i)
+		sourceBytes[i] = byte(i)
+	}
+
+	reference := transaction.Reference{Hash: strings.Repeat("ab", 32), Topoheight: 1000}
+	unsigned, hash := testUnsigned(sourceBytes, reference)
+	txAsHex := hex.EncodeToString(unsigned)
+	threshold := uint8(2)
+	return wallet.UnsignedTransactionResponse{
+		Version:   1,
+		Source:    source,
+		Fee:       25000,
+		FeeLimit:  50000,
+		Nonce:     12,
+		Reference: reference,
+		Hash:      hex.EncodeToString(hash[:]),
+		Threshold: &threshold,
+		TxAsHex:   &txAsHex,
+	}
+}
+
+func newTestContainer(t *testing.T) *Container {
+	res := newTestResponse()
+
+	params, err := wallet.NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 150000000).BuildUnsignedTransactionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container, err := New(res, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return container
+}
+
+func TestContainerRoundTrip(t *testing.T) {
+	container := newTestContainer(t)
+
+	err := container.AddSignature(wallet.SignatureId{Id: 1, Signature: strings.Repeat("11", 64)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encode := range []func() (string, error){container.EncodeBase64, container.EncodeHex} {
+		encoded, err := encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := DecodeString(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.Describe() != container.Describe() || !bytes.Equal(decoded.Unsigned, container.Unsigned) {
+			t.Fatalf("unexpected container %+v", decoded)
+		}
+	}
+
+	description := container.Describe()
+	if !strings.Contains(description, "Not checked") || !strings.Contains(description, "1.50000000 XEL to "+TESTING_ADDR) || !strings.Contains(description, "Signatures: 1/2") {
+		t.Fatalf("unexpected description %s", description)
+	}
+
+	t.Log(description)
+}
+
+func TestContainerChecksum(t *testing.T) {
+	data, err := newTestContainer(t).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data[20] ^= 0xff
+	_, err = Decode(data)
+	if err != ErrInvalidChecksum {
+		t.Fatalf("expected %s, got %v", ErrInvalidChecksum, err)
+	}
+
+	_, err = Decode(data[:len(data)-10])
+	if err != ErrInvalidChecksum {
+		t.Fatalf("expected %s, got %v", ErrInvalidChecksum, err)
+	}
+
+	_, err = Decode([]byte("nothing"))
+	if err != ErrInvalidMagic {
+		t.Fatalf("expected %s, got %v", ErrInvalidMagic, err)
+	}
+}
+
+func TestContainerFinalize(t *testing.T) {
+	container := newTestContainer(t)
+
+	_, err := container.FinalizeParams(true, false)
+	if err != ErrThresholdNotReached {
+		t.Fatalf("expected %s, got %v", ErrThresholdNotReached, err)
+	}
+
+	other := newTestContainer(t)
+	for _, id := range []uint8{2, 0} {
+		err = other.AddSignature(wallet.SignatureId{Id: id, Signature: strings.Repeat("22", 64)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = other.AddSignature(wallet.SignatureId{Id: 0, Signature: strings.Repeat("33", 64)})
+	if err != ErrDuplicateSignature {
+		t.Fatalf("expected %s, got %v", ErrDuplicateSignature, err)
+	}
+
+	// signatures gathered on another machine come back as finalize params
+	otherParams, err := other.FinalizeParams(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = container.MergeFinalizeParams(otherParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := container.FinalizeParams(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params.Unsigned != hex.EncodeToString(container.Unsigned) || len(params.Signatures) != 2 || params.Signatures[0].Id != 0 {
+		t.Fatalf("unexpected params %+v", params)
+	}
+
+	sign := container.SignParams(1)
+	if sign.Hash != container.Hash || sign.SignerId != 1 {
+		t.Fatalf("unexpected sign params %+v", sign)
+	}
+}
+
+func TestContainerMismatch(t *testing.T) {
+	params, err := wallet.NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).BuildUnsignedTransactionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := newTestResponse()
+	res.Hash = strings.Repeat("cd", 32)
+	_, err = New(res, params)
+	if !errors.Is(err, multisig.ErrInvalidHash) {
+		t.Fatalf("expected %s, got %v", multisig.ErrInvalidHash, err)
+	}
+
+	res = newTestResponse()
+	res.Reference.Topoheight++
+	_, err = New(res, params)
+	if err != ErrSummaryMismatch {
+		t.Fatalf("expected %s, got %v", ErrSummaryMismatch, err)
+	}
+
+	res = newTestResponse()
+	res.Source[0]++
+	_, err = New(res, params)
+	if err != ErrSummaryMismatch {
+		t.Fatalf("expected %s, got %v", ErrSummaryMismatch, err)
+	}
+}
+
+func TestContainerSignatureOrder(t *testing.T) {
+	container := newTestContainer(t)
+	for _, id := range []uint8{1, 2} {
+		err := container.AddSignature(wallet.SignatureId{Id: id, Signature: strings.Repeat("11", 64)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := container.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each signature is its id and 64 bytes, before the checksum
+	first := len(data) - checksumSize - 2*65
+	second := first + 65
+	tests := []struct {
+		ids [2]byte
+		err error
+	}{
+		{[2]byte{2, 1}, ErrUnsortedSignatures},
+		{[2]byte{1, 1}, ErrDuplicateSignature},
+	}
+
+	for _, test := range tests {
+		tampered := append([]byte(nil), data...)
+		tampered[first], tampered[second] = test.ids[0], test.ids[1]
+		copy(tampered[len(tampered)-checksumSize:], checksum(tampered[:len(tampered)-checksumSize]))
+
+		_, err = Decode(tampered)
+		if err != test.err {
+			t.Fatalf("ids %v: expected %s, got %v", test.ids, test.err, err)
+		}
+	}
+}
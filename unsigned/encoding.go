@@ -0,0 +1,347 @@
+package unsigned
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/xelis-project/xelis-go-sdk/transaction"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	"golang.org/x/crypto/sha3"
+)
+
+// Magic bytes at the start of every encoded container
+var Magic = []byte("XUTX")
+
+// FormatVersion is bumped when the binary layout changes
+const FormatVersion uint8 = 1
+
+const checksumSize = 4
+
+var ErrInvalidMagic = errors.New("not an unsigned transaction container")
+var ErrUnsupportedVersion = errors.New("unsupported container version")
+var ErrInvalidChecksum = errors.New("invalid container checksum")
+
+type encoder struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (e *encoder) write(data interface{}) {
+	if e.err == nil {
+		e.err = binary.Write(&e.buf, binary.BigEndian, data)
+	}
+}
+
+func (e *encoder) writeHash(value string) {
+	b, err := hex.DecodeString(value)
+	if err == nil && len(b) != 32 {
+		err = fmt.Errorf("invalid hash %s", value)
+	}
+
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+
+	var hash [32]byte
+	copy(hash[:], b)
+	e.write(hash)
+}
+
+func (e *encoder) writeHash64(value string) {
+	b, err := hex.DecodeString(value)
+	if err == nil && len(b) != 64 {
+		err = fmt.Errorf("invalid signature %s", value)
+	}
+
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+
+	var sig [64]byte
+	copy(sig[:], b)
+	e.write(sig)
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	if len(b) > math.MaxUint32 {
+		e.err = fmt.Errorf("too many bytes to encode")
+		return
+	}
+
+	e.write(uint32(len(b)))
+	e.write(b)
+}
+
+func (e *encoder) writeString(value string) {
+	if len(value) > math.MaxUint16 {
+		e.err = fmt.Errorf("string too long to encode")
+		return
+	}
+
+	e.write(uint16(len(value)))
+	e.write([]byte(value))
+}
+
+func (e *encoder) writeBool(value bool) {
+	if value {
+		e.write(uint8(1))
+	} else {
+		e.write(uint8(0))
+	}
+}
+
+type decoder struct {
+	reader *bytes.Reader
+	err    error
+}
+
+func (d *decoder) read(data interface{}) {
+	if d.err == nil {
+		d.err = binary.Read(d.reader, binary.BigEndian, data)
+		if errors.Is(d.err, io.EOF) {
+			d.err = io.ErrUnexpectedEOF
+		}
+	}
+}
+
+func (d *decoder) readU8() (value uint8) {
+	d.read(&value)
+	return
+}
+
+func (d *decoder) readU64() (value uint64) {
+	d.read(&value)
+	return
+}
+
+func (d *decoder) readHash() string {
+	var hash [32]byte
+	d.read(&hash)
+	return hex.EncodeToString(hash[:])
+}
+
+func (d *decoder) readBytes() []byte {
+	var size uint32
+	d.read(&size)
+	if d.err != nil {
+		return nil
+	}
+
+	if int64(size) > int64(d.reader.Len()) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	b := make([]byte, size)
+	d.read(b)
+	return b
+}
+
+func (d *decoder) readString() string {
+	var size uint16
+	d.read(&size)
+	if d.err != nil {
+		return ""
+	}
+
+	b := make([]byte, size)
+	d.read(b)
+	return string(b)
+}
+
+func (d *decoder) readBool() bool {
+	value := d.readU8()
+	if value > 1 && d.err == nil {
+		d.err = fmt.Errorf("invalid bool value %d", value)
+	}
+
+	return value == 1
+}
+
+func checksum(data []byte) []byte {
+	hash := sha3.Sum256(data)
+	return hash[:checksumSize]
+}
+
+// Encode returns the binary form of the container
+func (c *Container) Encode() (data []byte, err error) {
+	err = c.validate()
+	if err != nil {
+		return
+	}
+
+	s := c.Summary
+	if len(s.Transfers) > math.MaxUint8 || len(c.Signatures) > math.MaxUint8 {
+		err = fmt.Errorf("too many transfers or signatures to encode")
+		return
+	}
+
+	e := &encoder{}
+	e.write(Magic)
+	e.write(FormatVersion)
+	e.writeHash(c.Hash)
+	e.write(c.Source)
+	e.write(s.TxVersion)
+	e.write(s.Nonce)
+	e.write(s.Fee)
+	e.write(s.FeeLimit)
+	e.writeHash(s.Reference.Hash)
+	e.write(s.Reference.Topoheight)
+	e.write(s.Threshold)
+	e.write(uint8(s.Kind))
+
+	e.write(uint8(len(s.Transfers)))
+	for _, transfer := range s.Transfers {
+		e.writeString(transfer.Destination)
+		e.writeHash(transfer.Asset)
+		e.write(transfer.Amount)
+		e.writeBool(transfer.HasExtraData)
+	}
+
+	e.writeBool(s.Burn != nil)
+	if s.Burn != nil {
+		e.writeHash(s.Burn.Asset)
+		e.write(s.Burn.Amount)
+	}
+
+	e.writeBytes(c.Unsigned)
+
+	e.write(uint8(len(c.Signatures)))
+	for _, sig := range c.Signatures {
+		e.write(sig.Id)
+		e.writeHash64(sig.Signature)
+	}
+
+	if e.err != nil {
+		err = e.err
+		return
+	}
+
+	data = e.buf.Bytes()
+	data = append(data, checksum(data)...)
+	return
+}
+
+// Decode parses the binary form of the container and verifies its checksum
+func Decode(data []byte) (container *Container, err error) {
+	if len(data) < len(Magic)+1+checksumSize || !bytes.Equal(data[:len(Magic)], Magic) {
+		err = ErrInvalidMagic
+		return
+	}
+
+	if data[len(Magic)] != FormatVersion {
+		err = ErrUnsupportedVersion
+		return
+	}
+
+	body := data[:len(data)-checksumSize]
+	if !bytes.Equal(checksum(body), data[len(body):]) {
+		err = ErrInvalidChecksum
+		return
+	}
+
+	d := &decoder{reader: bytes.NewReader(body[len(Magic)+1:])}
+	c := &Container{}
+	c.Hash = d.readHash()
+	d.read(&c.Source)
+
+	s := &c.Summary
+	s.TxVersion = d.readU8()
+	s.Nonce = d.readU64()
+	s.Fee = d.readU64()
+	s.FeeLimit = d.readU64()
+	s.Reference.Hash = d.readHash()
+	s.Reference.Topoheight = d.readU64()
+	s.Threshold = d.readU8()
+	s.Kind = PayloadKind(d.readU8())
+
+	transfers := int(d.readU8())
+	for i := 0; i < transfers && d.err == nil; i++ {
+		s.Transfers = append(s.Transfers, TransferSummary{
+			Destination:  d.readString(),
+			Asset:        d.readHash(),
+			Amount:       d.readU64(),
+			HasExtraData: d.readBool(),
+		})
+	}
+
+	if d.readBool() {
+		s.Burn = &transaction.Burn{
+			Asset:  d.readHash(),
+			Amount: d.readU64(),
+		}
+	}
+
+	c.Unsigned = d.readBytes()
+
+	signatures := int(d.readU8())
+	for i := 0; i < signatures && d.err == nil; i++ {
+		id := d.readU8()
+		var sig [64]byte
+		d.read(&sig)
+		c.Signatures = append(c.Signatures, wallet.SignatureId{Id: id, Signature: hex.EncodeToString(sig[:])})
+	}
+
+	if d.err != nil {
+		err = d.err
+		return
+	}
+
+	if d.reader.Len() > 0 {
+		err = fmt.Errorf("%d trailing bytes in container", d.reader.Len())
+		return
+	}
+
+	err = c.validate()
+	if err != nil {
+		return
+	}
+
+	container = c
+	return
+}
+
+// EncodeBase64 returns the container as standard base64
+func (c *Container) EncodeBase64() (encoded string, err error) {
+	data, err := c.Encode()
+	if err != nil {
+		return
+	}
+
+	encoded = base64.StdEncoding.EncodeToString(data)
+	return
+}
+
+// EncodeHex returns the container as hex
+func (c *Container) EncodeHex() (encoded string, err error) {
+	data, err := c.Encode()
+	if err != nil {
+		return
+	}
+
+	encoded = hex.EncodeToString(data)
+	return
+}
+
+// DecodeString parses a container from EncodeBase64 or EncodeHex
+func DecodeString(encoded string) (container *Container, err error) {
+	encoded = strings.TrimSpace(encoded)
+
+	// base64 of the magic bytes is never valid hex so we can try hex first
+	data, err := hex.DecodeString(encoded)
+	if err != nil || !bytes.HasPrefix(data, Magic) {
+		data, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return
+		}
+	}
+
+	return Decode(data)
+}
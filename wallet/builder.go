@@ -0,0 +1,571 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/xelis-project/xelis-go-sdk/address"
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	"github.com/xelis-project/xelis-go-sdk/data"
+	"github.com/xelis-project/xelis-go-sdk/transaction"
+)
+
+// limits enforced by the blockchain, checked locally by TxBuilder
+const (
+	MaxTransfers            = 255
+	MaxMultiSigParticipants = 255
+	ExtraDataLimit          = 1024
+	ExtraDataLimitSum       = ExtraDataLimit * 32
+)
+
+var ErrPayloadConflict = errors.New("only one payload (transfers, burn, multisig, invoke contract, deploy contract or blob) can be set")
+var ErrEmptyPayload = errors.New("transaction has no payload")
+var ErrMaxTransfers = fmt.Errorf("transaction can't have more than %d transfers", MaxTransfers)
+var ErrExtraDataNoTransfer = errors.New("extra data must be set after a transfer")
+var ErrExtraDataLimit = fmt.Errorf("extra data max size is %d bytes", ExtraDataLimit)
+var ErrExtraDataLimitSum = fmt.Errorf("total extra data max size is %d bytes", ExtraDataLimitSum)
+var ErrFeeConflict = errors.New("only one fee mode (fixed, multiplier or tip) can be set")
+var ErrMixedNetworks = errors.New("destinations must be on the same network")
+var ErrNetworkMismatch = errors.New("destination is not on the network of the node")
+var ErrInvalidThreshold = errors.New("multisig threshold must be between 1 and the number of participants")
+
+type payloadType int
+
+const (
+	noPayload payloadType = iota
+	transfersPayload
+	burnPayload
+	multiSigPayload
+	invokeContractPayload
+	deployContractPayload
+	blobPayload
+)
+
+// TxBuilder validates a transaction locally and produces the params of
+// BuildTransaction, BuildTransactionOffline, BuildUnsignedTransaction and EstimateFees.
+// The first error is kept and returned when building the params.
+//
+//	params, err := wallet.NewTx().
+//		Transfer(addr, config.XELIS_ASSET, 100).
+//		WithExtraData(data.Element{Value: "hello"}).
+//		FeeMultiplier(1.2).
+//		Broadcast(true).
+//		BuildTransactionParams()
+type TxBuilder struct {
+	payload        payloadType
+	transfers      []TransferBuilder
+	extraData      []*data.Element
+	burn           *transaction.Burn
+	multiSig       *MutliSigBuilder
+	invokeContract *InvokeContractBuilder
+	deployContract *DeployContractBuilder
+	blob           *BlobPayloadBuilder
+
+	fee       *FeeBuilder
+	baseFee   *BaseFeeMode
+	feeLimit  *uint64
+	nonce     *uint64
+	txVersion *uint8
+	signers   *[]SignerId
+	broadcast bool
+	txAsHex   bool
+
+	// address prefix of the destinations
+	prefix  string
+	network *config.Network
+	err     error
+}
+
+func NewTx() *TxBuilder {
+	return &TxBuilder{}
+}
+
+func (b *TxBuilder) setErr(err error) *TxBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+
+	return b
+}
+
+func (b *TxBuilder) setPayload(payload payloadType) bool {
+	if b.payload != noPayload && b.payload != payload {
+		b.setErr(ErrPayloadConflict)
+		return false
+	}
+
+	b.payload = payload
+	return true
+}
+
+func (b *TxBuilder) checkAddress(addr string) bool {
+	a, err := address.NewAddressFromString(addr)
+	if err != nil {
+		b.setErr(fmt.Errorf("invalid address %s: %w", addr, err))
+		return false
+	}
+
+	if b.prefix != "" && b.prefix != a.Prefix() {
+		b.setErr(ErrMixedNetworks)
+		return false
+	}
+
+	if b.network != nil {
+		err = checkAddressNetwork(a, addr, *b.network)
+		if err != nil {
+			b.setErr(err)
+			return false
+		}
+	}
+
+	b.prefix = a.Prefix()
+	return true
+}
+
+// ForNetwork refuses destinations that are not on the network, before or after this call
+func (b *TxBuilder) ForNetwork(network config.Network) *TxBuilder {
+	prefix := network.AddressPrefix()
+	if prefix == "" {
+		return b.setErr(fmt.Errorf("%w: %s", config.ErrUnknownNetwork, network))
+	}
+
+	if b.prefix != "" && b.prefix != prefix {
+		return b.setErr(fmt.Errorf("%w: destinations are not on %s", ErrNetworkMismatch, network))
+	}
+
+	b.network = &network
+	return b
+}
+
+// NodeInfo is implemented by daemon.RPC and daemon.WebSocket
+type NodeInfo interface {
+	GetInfo() (daemon.GetInfoResult, error)
+}
+
+// VerifyNetwork checks the destinations against the network of the connected node
+// and returns the validation error, if any
+func (b *TxBuilder) VerifyNetwork(node NodeInfo) (err error) {
+	info, err := node.GetInfo()
+	if err != nil {
+		return
+	}
+
+	return b.ForNetwork(info.Network).Err()
+}
+
+func checkAddressNetwork(a *address.Address, addr string, network config.Network) error {
+	if !a.IsNetwork(network) {
+		return fmt.Errorf("%w: %s is not on %s", ErrNetworkMismatch, addr, network)
+	}
+
+	return nil
+}
+
+// networkCache keeps the network of the wallet, it doesn't change for a connection
+// so BuildTransaction only asks for it once
+type networkCache struct {
+	mutex   sync.Mutex
+	network config.Network
+}
+
+func (c *networkCache) get(getNetwork func() (string, error)) (network config.Network, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.network == "" {
+		var n string
+		n, err = getNetwork()
+		if err != nil {
+			return
+		}
+
+		c.network = config.Network(n)
+	}
+
+	network = c.network
+	return
+}
+
+// checkNetwork refuses destinations that are not on the network of the wallet,
+// which is the network of its node. The network is only fetched if there are destinations.
+func checkNetwork(getNetwork func() (config.Network, error), destinations []string) (err error) {
+	if len(destinations) == 0 {
+		return
+	}
+
+	network, err := getNetwork()
+	if err != nil {
+		return
+	}
+
+	for _, destination := range destinations {
+		var a *address.Address
+		a, err = address.NewAddressFromString(destination)
+		if err != nil {
+			return fmt.Errorf("invalid address %s: %w", destination, err)
+		}
+
+		err = checkAddressNetwork(a, destination, network)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// destinations of the transfers, multisig participants and blob
+func payloadDestinations(transfers []string, multiSig *MutliSigBuilder, blob *BlobPayloadBuilder) (destinations []string) {
+	destinations = transfers
+	if multiSig != nil {
+		destinations = append(destinations, multiSig.Participants...)
+	}
+
+	if blob != nil {
+		destinations = append(destinations, blob.Destinations...)
+	}
+
+	return
+}
+
+func (p BuildTransactionParams) destinations() []string {
+	var transfers []string
+	for _, transfer := range p.Transfers {
+		transfers = append(transfers, transfer.Destination)
+	}
+
+	return payloadDestinations(transfers, p.MultiSig, p.Blob)
+}
+
+func (p BuildUnsignedTransactionParams) destinations() []string {
+	var transfers []string
+	for _, transfer := range p.Transfers {
+		transfers = append(transfers, transfer.Destination)
+	}
+
+	return payloadDestinations(transfers, p.MultiSig, p.Blob)
+}
+
+func (b *TxBuilder) Transfer(destination string, asset string, amount uint64) *TxBuilder {
+	if !b.setPayload(transfersPayload) || !b.checkAddress(destination) {
+		return b
+	}
+
+	if len(b.transfers) >= MaxTransfers {
+		return b.setErr(ErrMaxTransfers)
+	}
+
+	b.transfers = append(b.transfers, TransferBuilder{
+		Destination: destination,
+		Asset:       asset,
+		Amount:      amount,
+	})
+	b.extraData = append(b.extraData, nil)
+	return b
+}
+
+// WithExtraData attaches extra data to the last transfer
+func (b *TxBuilder) WithExtraData(extraData data.Element) *TxBuilder {
+	if len(b.transfers) == 0 {
+		return b.setErr(ErrExtraDataNoTransfer)
+	}
+
+	size, err := data.EncodedSize(extraData)
+	if err != nil {
+		return b.setErr(err)
+	}
+
+	if size > ExtraDataLimit {
+		return b.setErr(ErrExtraDataLimit)
+	}
+
+	index := len(b.transfers) - 1
+	var value interface{} = extraData
+	b.transfers[index].ExtraData = &value
+	b.extraData[index] = &extraData
+	return b
+}
+
+// EncryptExtraData sets the encryption of the last transfer extra data (encrypted by default)
+func (b *TxBuilder) EncryptExtraData(encrypt bool) *TxBuilder {
+	if len(b.transfers) == 0 {
+		return b.setErr(ErrExtraDataNoTransfer)
+	}
+
+	b.transfers[len(b.transfers)-1].EncryptExtraData = &encrypt
+	return b
+}
+
+func (b *TxBuilder) Burn(asset string, amount uint64) *TxBuilder {
+	if !b.setPayload(burnPayload) {
+		return b
+	}
+
+	b.burn = &transaction.Burn{Asset: asset, Amount: amount}
+	return b
+}
+
+func (b *TxBuilder) MultiSig(participants []string, threshold uint8) *TxBuilder {
+	if !b.setPayload(multiSigPayload) {
+		return b
+	}
+
+	if len(participants) > MaxMultiSigParticipants {
+		return b.setErr(fmt.Errorf("multisig can't have more than %d participants", MaxMultiSigParticipants))
+	}
+
+	// threshold of 0 with no participants deletes the multisig
+	if threshold > uint8(len(participants)) || (threshold == 0 && len(participants) > 0) {
+		return b.setErr(ErrInvalidThreshold)
+	}
+
+	for _, participant := range participants {
+		if !b.checkAddress(participant) {
+			return b
+		}
+	}
+
+	b.multiSig = &MutliSigBuilder{Participants: participants, Threshold: threshold}
+	return b
+}
+
+func (b *TxBuilder) InvokeContract(invoke InvokeContractBuilder) *TxBuilder {
+	if !b.setPayload(invokeContractPayload) {
+		return b
+	}
+
+	b.invokeContract = &invoke
+	return b
+}
+
+func (b *TxBuilder) DeployContract(deploy DeployContractBuilder) *TxBuilder {
+	if !b.setPayload(deployContractPayload) {
+		return b
+	}
+
+	b.deployContract = &deploy
+	return b
+}
+
+func (b *TxBuilder) Blob(blob BlobPayloadBuilder) *TxBuilder {
+	if !b.setPayload(blobPayload) {
+		return b
+	}
+
+	for _, destination := range blob.Destinations {
+		if !b.checkAddress(destination) {
+			return b
+		}
+	}
+
+	b.blob = &blob
+	return b
+}
+
+func (b *TxBuilder) setFee(fee FeeBuilder) *TxBuilder {
+	if b.fee != nil {
+		return b.setErr(ErrFeeConflict)
+	}
+
+	b.fee = &fee
+	return b
+}
+
+func (b *TxBuilder) FixedFee(fee uint64) *TxBuilder {
+	return b.setFee(FeeBuilder{Fixed: &fee})
+}
+
+func (b *TxBuilder) FeeMultiplier(multiplier float64) *TxBuilder {
+	return b.setFee(FeeBuilder{Extra: &ExtraFeeMode{Multiplier: &multiplier}})
+}
+
+func (b *TxBuilder) FeeTip(tip uint64) *TxBuilder {
+	return b.setFee(FeeBuilder{Extra: &ExtraFeeMode{Tip: &tip}})
+}
+
+func (b *TxBuilder) BaseFee(baseFee BaseFeeMode) *TxBuilder {
+	b.baseFee = &baseFee
+	return b
+}
+
+func (b *TxBuilder) FeeLimit(feeLimit uint64) *TxBuilder {
+	b.feeLimit = &feeLimit
+	return b
+}
+
+func (b *TxBuilder) Nonce(nonce uint64) *TxBuilder {
+	b.nonce = &nonce
+	return b
+}
+
+func (b *TxBuilder) TxVersion(version uint8) *TxBuilder {
+	b.txVersion = &version
+	return b
+}
+
+func (b *TxBuilder) Signers(signers []SignerId) *TxBuilder {
+	b.signers = &signers
+	return b
+}
+
+func (b *TxBuilder) Broadcast(broadcast bool) *TxBuilder {
+	b.broadcast = broadcast
+	return b
+}
+
+func (b *TxBuilder) TxAsHex(txAsHex bool) *TxBuilder {
+	b.txAsHex = txAsHex
+	return b
+}
+
+// Err returns the first validation error
+func (b *TxBuilder) Err() error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if b.payload == noPayload {
+		return ErrEmptyPayload
+	}
+
+	total := 0
+	for _, extraData := range b.extraData {
+		if extraData == nil {
+			continue
+		}
+
+		size, err := data.EncodedSize(*extraData)
+		if err != nil {
+			return err
+		}
+
+		total += size
+	}
+
+	if total > ExtraDataLimitSum {
+		return ErrExtraDataLimitSum
+	}
+
+	return checkFeeBuilder(b.fee)
+}
+
+func (b *TxBuilder) deployContractParam() interface{} {
+	if b.deployContract == nil {
+		return nil
+	}
+
+	return b.deployContract
+}
+
+// transfers are copied so the params are not changed by the next calls to the builder
+func (b *TxBuilder) copyTransfers() []TransferBuilder {
+	transfers := make([]TransferBuilder, len(b.transfers))
+	copy(transfers, b.transfers)
+	return transfers
+}
+
+func (b *TxBuilder) BuildTransactionParams() (params BuildTransactionParams, err error) {
+	err = b.Err()
+	if err != nil {
+		return
+	}
+
+	params = BuildTransactionParams{
+		Transfers:      b.copyTransfers(),
+		Burn:           b.burn,
+		MultiSig:       b.multiSig,
+		InvokeContract: b.invokeContract,
+		DeployContract: b.deployContractParam(),
+		Blob:           b.blob,
+		Fee:            b.fee,
+		BaseFee:        b.baseFee,
+		FeeLimit:       b.feeLimit,
+		Nonce:          b.nonce,
+		TxVersion:      b.txVersion,
+		Broadcast:      b.broadcast,
+		TxAsHex:        b.txAsHex,
+		Signers:        b.signers,
+	}
+	return
+}
+
+// BuildTransactionOfflineParams requires the nonce to be set with Nonce()
+func (b *TxBuilder) BuildTransactionOfflineParams(reference transaction.Reference, balances map[string]interface{}) (params BuildTransactionOfflineParams, err error) {
+	err = b.Err()
+	if err != nil {
+		return
+	}
+
+	if b.nonce == nil {
+		err = errors.New("nonce is required to build a transaction offline")
+		return
+	}
+
+	var baseFee *uint64
+	if b.baseFee != nil {
+		baseFee = b.baseFee.Fixed
+	}
+
+	params = BuildTransactionOfflineParams{
+		Transfers:      b.copyTransfers(),
+		Burn:           b.burn,
+		MultiSig:       b.multiSig,
+		InvokeContract: b.invokeContract,
+		DeployContract: b.deployContractParam(),
+		Blob:           b.blob,
+		Fee:            b.fee,
+		BaseFee:        baseFee,
+		FeeLimit:       b.feeLimit,
+		TxVersion:      b.txVersion,
+		TxAsHex:        b.txAsHex,
+		Balances:       balances,
+		Reference:      reference,
+		Nonce:          *b.nonce,
+		Signers:        b.signers,
+	}
+	return
+}
+
+func (b *TxBuilder) BuildUnsignedTransactionParams() (params BuildUnsignedTransactionParams, err error) {
+	err = b.Err()
+	if err != nil {
+		return
+	}
+
+	params = BuildUnsignedTransactionParams{
+		Transfers:      b.copyTransfers(),
+		Burn:           b.burn,
+		MultiSig:       b.multiSig,
+		InvokeContract: b.invokeContract,
+		DeployContract: b.deployContractParam(),
+		Blob:           b.blob,
+		Nonce:          b.nonce,
+		Fee:            b.fee,
+		BaseFee:        b.baseFee,
+		FeeLimit:       b.feeLimit,
+		TxVersion:      b.txVersion,
+		TxAsHex:        b.txAsHex,
+	}
+	return
+}
+
+func (b *TxBuilder) EstimateFeesParams() (params EstimateFeesParams, err error) {
+	err = b.Err()
+	if err != nil {
+		return
+	}
+
+	params = EstimateFeesParams{
+		Transfers:      b.copyTransfers(),
+		Burn:           b.burn,
+		MultiSig:       b.multiSig,
+		InvokeContract: b.invokeContract,
+		DeployContract: b.deployContractParam(),
+		Blob:           b.blob,
+		Fee:            b.fee,
+		BaseFee:        b.baseFee,
+	}
+	return
+}
@@ -0,0 +1,232 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/config"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	d "github.com/xelis-project/xelis-go-sdk/data"
+	"github.com/xelis-project/xelis-go-sdk/transaction"
+)
+
+func TestTxBuilderTransfers(t *testing.T) {
+	params, err := NewTx().
+		Transfer(TESTING_ADDR, config.XELIS_ASSET, 100).
+		WithExtraData(d.Element{Value: "hello"}).
+		Transfer(TESTING_ADDR, config.XELIS_ASSET, 200).
+		FeeMultiplier(1.2).
+		Nonce(5).
+		Broadcast(true).
+		BuildTransactionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(params.Transfers) != 2 || params.Transfers[0].ExtraData == nil || params.Transfers[1].ExtraData != nil {
+		t.Fatalf("unexpected transfers %+v", params.Transfers)
+	}
+
+	if *params.Nonce != 5 || !params.Broadcast {
+		t.Fatalf("unexpected params %+v", params)
+	}
+
+	b, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), `"fee":{"extra":{"multiplier":1.2}}`) {
+		t.Fatalf("unexpected json %s", b)
+	}
+
+	t.Log(string(b))
+}
+
+func TestTxBuilderValidation(t *testing.T) {
+	_, err := NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).Burn(config.XELIS_ASSET, 1).BuildTransactionParams()
+	if err != ErrPayloadConflict {
+		t.Fatalf("expected %s, got %v", ErrPayloadConflict, err)
+	}
+
+	_, err = NewTx().BuildTransactionParams()
+	if err != ErrEmptyPayload {
+		t.Fatalf("expected %s, got %v", ErrEmptyPayload, err)
+	}
+
+	_, err = NewTx().Transfer("xel:invalid", config.XELIS_ASSET, 1).BuildTransactionParams()
+	if err == nil {
+		t.Fatal("expected invalid address error")
+	}
+
+	_, err = NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).Transfer(MAINNET_ADDR, config.XELIS_ASSET, 1).BuildTransactionParams()
+	if err != ErrMixedNetworks {
+		t.Fatalf("expected %s, got %v", ErrMixedNetworks, err)
+	}
+
+	_, err = NewTx().WithExtraData(d.Element{Value: "hello"}).BuildTransactionParams()
+	if err != ErrExtraDataNoTransfer {
+		t.Fatalf("expected %s, got %v", ErrExtraDataNoTransfer, err)
+	}
+
+	_, err = NewTx().Burn(config.XELIS_ASSET, 1).FixedFee(10).FeeTip(1).BuildTransactionParams()
+	if err != ErrFeeConflict {
+		t.Fatalf("expected %s, got %v", ErrFeeConflict, err)
+	}
+
+	var array []d.Element
+	for i := 0; i < 10; i++ {
+		array = append(array, d.Element{Value: strings.Repeat("a", 200)})
+	}
+
+	_, err = NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).WithExtraData(d.Element{Array: array}).BuildTransactionParams()
+	if err != ErrExtraDataLimit {
+		t.Fatalf("expected %s, got %v", ErrExtraDataLimit, err)
+	}
+
+	builder := NewTx()
+	for i := 0; i <= MaxTransfers; i++ {
+		builder.Transfer(TESTING_ADDR, config.XELIS_ASSET, 1)
+	}
+
+	_, err = builder.BuildTransactionParams()
+	if err != ErrMaxTransfers {
+		t.Fatalf("expected %s, got %v", ErrMaxTransfers, err)
+	}
+}
+
+type fakeNode struct {
+	network config.Network
+}
+
+func (f fakeNode) GetInfo() (daemon.GetInfoResult, error) {
+	return daemon.GetInfoResult{Network: f.network}, nil
+}
+
+func TestTxBuilderNetwork(t *testing.T) {
+	err := NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).VerifyNetwork(fakeNode{config.Testnet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// testnet, stagenet and devnet share the same addresses
+	err = NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).VerifyNetwork(fakeNode{config.Devnet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).VerifyNetwork(fakeNode{config.Mainnet})
+	if !errors.Is(err, ErrNetworkMismatch) {
+		t.Fatalf("expected %s, got %v", ErrNetworkMismatch, err)
+	}
+
+	_, err = NewTx().ForNetwork(config.Mainnet).Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).BuildTransactionParams()
+	if !errors.Is(err, ErrNetworkMismatch) {
+		t.Fatalf("expected %s, got %v", ErrNetworkMismatch, err)
+	}
+
+	_, err = NewTx().ForNetwork("Unknown").Transfer(MAINNET_ADDR, config.XELIS_ASSET, 1).BuildTransactionParams()
+	if !errors.Is(err, config.ErrUnknownNetwork) {
+		t.Fatalf("expected %s, got %v", config.ErrUnknownNetwork, err)
+	}
+}
+
+func TestBuildTransactionNetwork(t *testing.T) {
+	var built bool
+	var networkCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int64  `json:"id"`
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{} = config.Mainnet
+		if req.Method == "get_network" {
+			networkCalls++
+		} else {
+			built = true
+			result = TransactionResponse{}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer server.Close()
+
+	wallet, err := NewRPC(server.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 1).BuildTransactionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wallet.BuildTransaction(params)
+	if !errors.Is(err, ErrNetworkMismatch) || built {
+		t.Fatalf("expected %s before building, got %v", ErrNetworkMismatch, err)
+	}
+
+	params, err = NewTx().Transfer(MAINNET_ADDR, config.XELIS_ASSET, 1).BuildTransactionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wallet.BuildTransaction(params)
+	if err != nil || !built {
+		t.Fatalf("expected the transaction to be built, got %v", err)
+	}
+
+	// the network is fetched once per client
+	if networkCalls != 1 {
+		t.Fatalf("expected one get_network call, got %d", networkCalls)
+	}
+}
+
+func TestTxBuilderOffline(t *testing.T) {
+	builder := NewTx().Transfer(TESTING_ADDR, config.XELIS_ASSET, 100).WithExtraData(d.Element{Value: uint64(1)}).EncryptExtraData(false)
+
+	_, err := builder.BuildTransactionOfflineParams(transaction.Reference{}, nil)
+	if err == nil {
+		t.Fatal("expected missing nonce error")
+	}
+
+	params, err := builder.Nonce(3).BuildTransactionOfflineParams(transaction.Reference{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params.Nonce != 3 || len(params.Transfers) != 1 || params.Transfers[0].ExtraData == nil {
+		t.Fatalf("unexpected params %+v", params)
+	}
+
+	encrypt := params.Transfers[0].EncryptExtraData
+	if encrypt == nil || *encrypt {
+		t.Fatal("expected the extra data encryption to be kept")
+	}
+
+	unsigned, err := builder.BuildUnsignedTransactionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unsigned.Transfers) != 1 || unsigned.Transfers[0].EncryptExtraData == nil {
+		t.Fatalf("unexpected params %+v", unsigned)
+	}
+
+	built, err := builder.BuildTransactionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// params already returned are not changed by the builder
+	builder.EncryptExtraData(true).Transfer(TESTING_ADDR, config.XELIS_ASSET, 1)
+	if len(built.Transfers) != 1 || *built.Transfers[0].EncryptExtraData || *params.Transfers[0].EncryptExtraData {
+		t.Fatal("returned params were changed by the builder")
+	}
+}
@@ -0,0 +1,118 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/xelis-project/xelis-go-sdk/data"
+)
+
+// Codec converts Go values to data.Element before storing them
+// and back from the raw JSON values returned by the wallet
+type Codec[T any] interface {
+	Encode(value T) (data.Element, error)
+	Decode(value json.RawMessage) (T, error)
+}
+
+// JSONCodec goes through the JSON form of the value, struct fields become element fields.
+// Only unsigned integers, strings, bools, arrays and objects can be stored.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(value T) (element data.Element, err error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var v interface{}
+	err = decoder.Decode(&v)
+	if err != nil {
+		return
+	}
+
+	return toElement(v)
+}
+
+// Decode reads the raw JSON so numbers above 2^53 keep their precision,
+// interface{} fields get a json.Number
+func (JSONCodec[T]) Decode(value json.RawMessage) (result T, err error) {
+	err = unmarshal(value, &result)
+	if err != nil {
+		// keys of query results are always strings, retry with the raw content for numbers and bools
+		var s string
+		if json.Unmarshal(value, &s) != nil {
+			return
+		}
+
+		if unmarshal([]byte(s), &result) == nil {
+			err = nil
+		}
+	}
+
+	return
+}
+
+func unmarshal(b []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func toElement(value interface{}) (element data.Element, err error) {
+	switch v := value.(type) {
+	case nil:
+		err = fmt.Errorf("null values are not supported")
+	case bool, string:
+		element.Value = v
+	case json.Number:
+		number, ok := new(big.Int).SetString(v.String(), 10)
+		if !ok || number.Sign() < 0 {
+			err = fmt.Errorf("only unsigned integers are supported, got %s", v)
+			return
+		}
+
+		if number.IsUint64() {
+			element.Value = number.Uint64()
+		} else if number.BitLen() <= 128 {
+			element.Value = *number
+		} else {
+			err = fmt.Errorf("number %s is too large", v)
+		}
+	case []interface{}:
+		element.Array = []data.Element{}
+		for _, item := range v {
+			var e data.Element
+			e, err = toElement(item)
+			if err != nil {
+				return
+			}
+
+			element.Array = append(element.Array, e)
+		}
+	case map[string]interface{}:
+		element.Fields = make(map[data.Value]data.Element)
+		for key, item := range v {
+			// nil fields are omitted and decoded back as zero values
+			if item == nil {
+				continue
+			}
+
+			var e data.Element
+			e, err = toElement(item)
+			if err != nil {
+				return
+			}
+
+			element.Fields[key] = e
+		}
+	default:
+		err = fmt.Errorf("unsupported value %T", value)
+	}
+
+	return
+}
@@ -0,0 +1,267 @@
+// Package kv is a typed layer over the encrypted database of the wallet.
+//
+//	type Order struct {
+//		Product string `json:"product"`
+//		Amount  uint64 `json:"amount"`
+//	}
+//
+//	orders := kv.NewTree[string, Order](w, "orders")
+//	err := orders.Put("order_1", Order{Product: "coffee", Amount: 2})
+//	order, found, err := orders.Get("order_1")
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	"github.com/xelis-project/xelis-go-sdk/wallet/query"
+)
+
+// Client is the subset of wallet.RPC and wallet.WebSocket used by a tree
+type Client interface {
+	Store(params wallet.StoreParams) (bool, error)
+	GetValueFromKeyRaw(params wallet.GetValueFromKeyParams) (json.RawMessage, error)
+	HasKey(params wallet.HasKeyParams) (bool, error)
+	Delete(params wallet.DeleteParams) (bool, error)
+	DeleteTreeEntries(params wallet.DeleteTreeEntriesParams) (bool, error)
+	GetMatchingKeysRaw(params wallet.GetMatchingKeysParams) ([]json.RawMessage, error)
+	CountMatchingEntries(params wallet.CountMatchingEntriesParams) (uint64, error)
+	QueryDBRaw(params wallet.QueryDBParams) (wallet.RawQueryResult, error)
+}
+
+type Entry[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// ScanOptions filters entries on the wallet side, nil queries match everything
+type ScanOptions struct {
+	Key   *query.Query
+	Value *query.Query
+	Limit *uint64
+	Skip  *uint64
+}
+
+type Tree[K any, V any] struct {
+	client Client
+	name   string
+	keys   Codec[K]
+	values Codec[V]
+}
+
+// NewTree uses JSONCodec for keys and values
+func NewTree[K any, V any](client Client, name string) *Tree[K, V] {
+	return NewTreeWithCodecs[K, V](client, name, JSONCodec[K]{}, JSONCodec[V]{})
+}
+
+func NewTreeWithCodecs[K any, V any](client Client, name string, keys Codec[K], values Codec[V]) *Tree[K, V] {
+	return &Tree[K, V]{client: client, name: name, keys: keys, values: values}
+}
+
+func (t *Tree[K, V]) Name() string {
+	return t.name
+}
+
+func (t *Tree[K, V]) Put(key K, value V) (err error) {
+	k, err := t.keys.Encode(key)
+	if err != nil {
+		return
+	}
+
+	v, err := t.values.Encode(value)
+	if err != nil {
+		return
+	}
+
+	_, err = t.client.Store(wallet.StoreParams{Tree: t.name, Key: k, Value: v})
+	return
+}
+
+// Get returns found false if the key doesn't exist
+func (t *Tree[K, V]) Get(key K) (value V, found bool, err error) {
+	k, err := t.keys.Encode(key)
+	if err != nil {
+		return
+	}
+
+	result, err := t.client.GetValueFromKeyRaw(wallet.GetValueFromKeyParams{Tree: t.name, Key: k})
+	if err != nil {
+		// the wallet answers with an error for missing keys
+		var rpcErr *rpc.RPCError
+		if !errors.As(err, &rpcErr) {
+			return
+		}
+
+		has, hasErr := t.client.HasKey(wallet.HasKeyParams{Tree: t.name, Key: k})
+		if hasErr == nil && !has {
+			err = nil
+		}
+
+		return
+	}
+
+	value, err = t.values.Decode(result)
+	if err != nil {
+		return
+	}
+
+	found = true
+	return
+}
+
+func (t *Tree[K, V]) Has(key K) (has bool, err error) {
+	k, err := t.keys.Encode(key)
+	if err != nil {
+		return
+	}
+
+	return t.client.HasKey(wallet.HasKeyParams{Tree: t.name, Key: k})
+}
+
+func (t *Tree[K, V]) Delete(key K) (err error) {
+	k, err := t.keys.Encode(key)
+	if err != nil {
+		return
+	}
+
+	_, err = t.client.Delete(wallet.DeleteParams{Tree: t.name, Key: k})
+	return
+}
+
+// Clear deletes all the entries of the tree
+func (t *Tree[K, V]) Clear() (err error) {
+	_, err = t.client.DeleteTreeEntries(wallet.DeleteTreeEntriesParams{Tree: t.name})
+	return
+}
+
+func build(q *query.Query) (*wallet.Query, error) {
+	if q == nil {
+		return nil, nil
+	}
+
+	return q.Build()
+}
+
+// Scan returns the matching entries sorted by key and the skip value of the next page if any
+func (t *Tree[K, V]) Scan(opts ScanOptions) (entries []Entry[K, V], next *uint64, err error) {
+	params := wallet.QueryDBParams{Tree: t.name, Limit: opts.Limit, Skip: opts.Skip}
+	params.Key, err = build(opts.Key)
+	if err != nil {
+		return
+	}
+
+	params.Value, err = build(opts.Value)
+	if err != nil {
+		return
+	}
+
+	result, err := t.client.QueryDBRaw(params)
+	if err != nil {
+		return
+	}
+
+	rawKeys := make([]string, 0, len(result.Entries))
+	for rawKey := range result.Entries {
+		rawKeys = append(rawKeys, rawKey)
+	}
+	sort.Strings(rawKeys)
+
+	for _, rawKey := range rawKeys {
+		var entry Entry[K, V]
+		var key json.RawMessage
+		key, err = json.Marshal(rawKey)
+		if err != nil {
+			return
+		}
+
+		entry.Key, err = t.keys.Decode(key)
+		if err != nil {
+			return
+		}
+
+		entry.Value, err = t.values.Decode(result.Entries[rawKey])
+		if err != nil {
+			return
+		}
+
+		entries = append(entries, entry)
+	}
+
+	next = result.Next
+	return
+}
+
+// ScanPrefix returns the entries with a string key starting with prefix
+func (t *Tree[K, V]) ScanPrefix(prefix string, limit *uint64) (entries []Entry[K, V], next *uint64, err error) {
+	q := query.Value().StartsWith(prefix)
+	return t.Scan(ScanOptions{Key: &q, Limit: limit})
+}
+
+// ForEach pages through all the matching entries until fn returns false
+func (t *Tree[K, V]) ForEach(opts ScanOptions, fn func(entry Entry[K, V]) bool) (err error) {
+	for {
+		var entries []Entry[K, V]
+		var next *uint64
+		entries, next, err = t.Scan(opts)
+		if err != nil {
+			return
+		}
+
+		for _, entry := range entries {
+			if !fn(entry) {
+				return
+			}
+		}
+
+		if next == nil || len(entries) == 0 {
+			return
+		}
+
+		opts.Skip = next
+	}
+}
+
+// Keys returns the keys matching the query, all keys if nil
+func (t *Tree[K, V]) Keys(q *query.Query, limit *uint64, skip *uint64) (keys []K, err error) {
+	params := wallet.GetMatchingKeysParams{Tree: t.name, Limit: limit, Skip: skip}
+	params.Query, err = build(q)
+	if err != nil {
+		return
+	}
+
+	result, err := t.client.GetMatchingKeysRaw(params)
+	if err != nil {
+		return
+	}
+
+	for _, rawKey := range result {
+		var key K
+		key, err = t.keys.Decode(rawKey)
+		if err != nil {
+			return
+		}
+
+		keys = append(keys, key)
+	}
+
+	return
+}
+
+// Count returns the number of entries matching the key and value queries of opts
+func (t *Tree[K, V]) Count(opts ScanOptions) (count uint64, err error) {
+	params := wallet.CountMatchingEntriesParams{Tree: t.name}
+	params.Key, err = build(opts.Key)
+	if err != nil {
+		return
+	}
+
+	params.Value, err = build(opts.Value)
+	if err != nil {
+		return
+	}
+
+	return t.client.CountMatchingEntries(params)
+}
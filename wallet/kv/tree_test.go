@@ -0,0 +1,243 @@
+package kv
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	"github.com/xelis-project/xelis-go-sdk/wallet/query"
+)
+
+// fakeClient keeps the JSON sent over the wire like the wallet would
+type fakeClient struct {
+	entries map[string]string
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{entries: make(map[string]string)}
+}
+
+func encode(t interface{}) string {
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+func decode(s string) (v interface{}) {
+	json.Unmarshal([]byte(s), &v)
+	return
+}
+
+func (f *fakeClient) Store(params wallet.StoreParams) (bool, error) {
+	f.entries[encode(params.Key)] = encode(params.Value)
+	return true, nil
+}
+
+func (f *fakeClient) GetValueFromKeyRaw(params wallet.GetValueFromKeyParams) (json.RawMessage, error) {
+	value, ok := f.entries[encode(params.Key)]
+	if !ok {
+		return nil, &rpc.RPCError{Message: "key not found"}
+	}
+
+	return json.RawMessage(value), nil
+}
+
+func (f *fakeClient) HasKey(params wallet.HasKeyParams) (bool, error) {
+	_, ok := f.entries[encode(params.Key)]
+	return ok, nil
+}
+
+func (f *fakeClient) Delete(params wallet.DeleteParams) (bool, error) {
+	delete(f.entries, encode(params.Key))
+	return true, nil
+}
+
+func (f *fakeClient) DeleteTreeEntries(params wallet.DeleteTreeEntriesParams) (bool, error) {
+	f.entries = make(map[string]string)
+	return true, nil
+}
+
+// only starts_with key queries are supported
+func (f *fakeClient) matchingKeys(q *wallet.Query) (keys []string) {
+	prefix := ""
+	if q != nil {
+		var raw struct {
+			StartsWith string `json:"starts_with"`
+		}
+		json.Unmarshal(q.Raw, &raw)
+		prefix = raw.StartsWith
+	}
+
+	for key := range f.entries {
+		k, _ := decode(key).(string)
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return
+}
+
+func (f *fakeClient) GetMatchingKeysRaw(params wallet.GetMatchingKeysParams) (keys []json.RawMessage, err error) {
+	for _, key := range f.matchingKeys(params.Query) {
+		keys = append(keys, json.RawMessage(key))
+	}
+
+	return
+}
+
+func (f *fakeClient) CountMatchingEntries(params wallet.CountMatchingEntriesParams) (uint64, error) {
+	return uint64(len(f.matchingKeys(params.Key))), nil
+}
+
+func (f *fakeClient) QueryDBRaw(params wallet.QueryDBParams) (result wallet.RawQueryResult, err error) {
+	keys := f.matchingKeys(params.Key)
+	if params.Skip != nil {
+		keys = keys[*params.Skip:]
+	}
+
+	if params.Limit != nil && uint64(len(keys)) > *params.Limit {
+		next := *params.Limit
+		if params.Skip != nil {
+			next += *params.Skip
+		}
+
+		result.Next = &next
+		keys = keys[:*params.Limit]
+	}
+
+	result.Entries = make(map[string]json.RawMessage)
+	for _, key := range keys {
+		result.Entries[decode(key).(string)] = json.RawMessage(f.entries[key])
+	}
+
+	return
+}
+
+type order struct {
+	Product  string   `json:"product"`
+	Quantity uint64   `json:"quantity"`
+	Tags     []string `json:"tags"`
+	Paid     bool     `json:"paid"`
+}
+
+func TestTreeCRUD(t *testing.T) {
+	orders := NewTree[string, order](newFakeClient(), "orders")
+
+	_, found, err := orders.Get("order_1")
+	if err != nil || found {
+		t.Fatalf("expected missing key, got %t %v", found, err)
+	}
+
+	expected := order{Product: "coffee", Quantity: 2, Tags: []string{"hot"}, Paid: true}
+	err = orders.Put("order_1", expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, found, err := orders.Get("order_1")
+	if err != nil || !found {
+		t.Fatalf("expected key, got %t %v", found, err)
+	}
+
+	if encode(value) != encode(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, value)
+	}
+
+	err = orders.Delete("order_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	has, err := orders.Has("order_1")
+	if err != nil || has {
+		t.Fatalf("expected deleted key, got %t %v", has, err)
+	}
+}
+
+func TestTreeScan(t *testing.T) {
+	client := newFakeClient()
+	orders := NewTree[string, order](client, "orders")
+	for _, key := range []string{"a_1", "b_1", "a_2", "a_3"} {
+		err := orders.Put(key, order{Product: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	limit := uint64(2)
+	entries, next, err := orders.ScanPrefix("a_", &limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Key != "a_1" || entries[1].Value.Product != "a_2" || next == nil {
+		t.Fatalf("unexpected entries %+v next %v", entries, next)
+	}
+
+	prefix := query.Value().StartsWith("a_")
+	var keys []string
+	err = orders.ForEach(ScanOptions{Key: &prefix, Limit: &limit}, func(entry Entry[string, order]) bool {
+		keys = append(keys, entry.Key)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(keys, ",") != "a_1,a_2,a_3" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	count, err := orders.Count(ScanOptions{Key: &prefix})
+	if err != nil || count != 3 {
+		t.Fatalf("expected 3 entries, got %d %v", count, err)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	_, err := JSONCodec[int]{}.Encode(-1)
+	if err == nil {
+		t.Fatal("expected negative number error")
+	}
+
+	_, err = JSONCodec[float64]{}.Encode(1.5)
+	if err == nil {
+		t.Fatal("expected float error")
+	}
+
+	// keys in query results are strings
+	number, err := JSONCodec[uint64]{}.Decode(json.RawMessage(`"42"`))
+	if err != nil || number != 42 {
+		t.Fatalf("expected 42, got %d %v", number, err)
+	}
+}
+
+func TestTreeLargeNumbers(t *testing.T) {
+	balances := NewTree[uint64, order](newFakeClient(), "balances")
+	// above 2^53, a float64 would round it to 2^60
+	large := uint64(1<<60 + 1)
+	expected := order{Product: "coffee", Quantity: large}
+	err := balances.Put(large, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, found, err := balances.Get(large)
+	if err != nil || !found || value.Quantity != large {
+		t.Fatalf("expected quantity %d, got %+v %t %v", large, value, found, err)
+	}
+
+	keys, err := balances.Keys(nil, nil, nil)
+	if err != nil || len(keys) != 1 || keys[0] != large {
+		t.Fatalf("expected key %d, got %v %v", large, keys, err)
+	}
+
+	fields, err := JSONCodec[map[string]interface{}]{}.Decode(json.RawMessage(`{"amount":1152921504606846977}`))
+	if err != nil || fields["amount"] != json.Number("1152921504606846977") {
+		t.Fatalf("expected the exact number, got %v %v", fields, err)
+	}
+}
@@ -1,6 +1,10 @@
 package wallet
 
-import "github.com/xelis-project/xelis-go-sdk/data"
+import (
+	"encoding/json"
+
+	"github.com/xelis-project/xelis-go-sdk/data"
+)
 
 type QueryNumber struct {
 	Greater        uint `json:"greater,omitempty"`
@@ -49,4 +53,15 @@ type Query struct {
 	Or  []Query `json:"or,omitempty"`
 	*QueryElement
 	*QueryValue
+	// Raw JSON sent as is instead of the fields above, set by the wallet/query builder
+	Raw json.RawMessage `json:"-"`
+}
+
+func (q Query) MarshalJSON() ([]byte, error) {
+	if q.Raw != nil {
+		return q.Raw, nil
+	}
+
+	type query Query
+	return json.Marshal(query(q))
 }
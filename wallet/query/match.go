@@ -0,0 +1,434 @@
+package query
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/xelis-project/xelis-go-sdk/data"
+)
+
+var ErrEmptyQuery = errors.New("empty query")
+
+type logicNode struct {
+	op      string
+	queries []Query
+}
+
+func (n *logicNode) toJSON() interface{} {
+	return map[string]interface{}{n.op: n.queries}
+}
+
+func (n *logicNode) match(element data.Element) (bool, error) {
+	for _, query := range n.queries {
+		ok, err := query.Match(element)
+		if err != nil {
+			return false, err
+		}
+
+		if n.op == "or" && ok {
+			return true, nil
+		}
+
+		if n.op == "and" && !ok {
+			return false, nil
+		}
+	}
+
+	return n.op == "and", nil
+}
+
+func (n *logicNode) validate() error {
+	for _, query := range n.queries {
+		err := query.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type notNode struct {
+	query Query
+}
+
+func (n *notNode) toJSON() interface{} {
+	return map[string]interface{}{"not": n.query}
+}
+
+func (n *notNode) match(element data.Element) (bool, error) {
+	ok, err := n.query.Match(element)
+	return !ok, err
+}
+
+func (n *notNode) validate() error {
+	return n.query.validate()
+}
+
+type valueNode struct {
+	op    string
+	value interface{}
+}
+
+func (n *valueNode) toJSON() interface{} {
+	return map[string]interface{}{n.op: jsonValue(n.value)}
+}
+
+func (n *valueNode) match(element data.Element) (bool, error) {
+	if element.Value == nil {
+		return false, nil
+	}
+
+	if n.op == "equal" {
+		return valuesEqual(element.Value, n.value), nil
+	}
+
+	value, ok := element.Value.(string)
+	if !ok {
+		return false, nil
+	}
+
+	pattern, _ := n.value.(string)
+	switch n.op {
+	case "starts_with":
+		return strings.HasPrefix(value, pattern), nil
+	case "ends_with":
+		return strings.HasSuffix(value, pattern), nil
+	case "contains_value":
+		return strings.Contains(value, pattern), nil
+	case "matches":
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+
+		return regex.MatchString(value), nil
+	}
+
+	return false, nil
+}
+
+func (n *valueNode) validate() error {
+	if n.op != "matches" {
+		return nil
+	}
+
+	pattern, _ := n.value.(string)
+	_, err := regexp.Compile(pattern)
+	return err
+}
+
+type valueTypeNode struct {
+	valueType data.ValueType
+}
+
+func (n *valueTypeNode) toJSON() interface{} {
+	return map[string]interface{}{"is_of_type": n.valueType}
+}
+
+func (n *valueTypeNode) match(element data.Element) (bool, error) {
+	valueType, ok := typeOfValue(element.Value)
+	return ok && valueType == n.valueType, nil
+}
+
+func (n *valueTypeNode) validate() error {
+	return nil
+}
+
+type numberNode struct {
+	op    string
+	value uint64
+}
+
+func (n *numberNode) toJSON() interface{} {
+	return map[string]interface{}{n.op: n.value}
+}
+
+func (n *numberNode) compare(value *big.Int) bool {
+	cmp := value.Cmp(new(big.Int).SetUint64(n.value))
+	switch n.op {
+	case "greater":
+		return cmp > 0
+	case "greater_or_equal":
+		return cmp >= 0
+	case "lesser":
+		return cmp < 0
+	case "lesser_or_equal":
+		return cmp <= 0
+	}
+
+	return false
+}
+
+func (n *numberNode) match(element data.Element) (bool, error) {
+	value, ok := toBig(element.Value)
+	return ok && n.compare(value), nil
+}
+
+func (n *numberNode) validate() error {
+	return nil
+}
+
+type lenNode struct {
+	number numberNode
+}
+
+func (n *lenNode) toJSON() interface{} {
+	return map[string]interface{}{"len": n.number.toJSON()}
+}
+
+func (n *lenNode) match(element data.Element) (bool, error) {
+	var size int
+	switch {
+	case element.Array != nil:
+		size = len(element.Array)
+	case element.Fields != nil:
+		size = len(element.Fields)
+	default:
+		switch value := element.Value.(type) {
+		case string:
+			size = len(value)
+		case data.Blob:
+			size = len(value)
+		default:
+			return false, nil
+		}
+	}
+
+	return n.number.compare(big.NewInt(int64(size))), nil
+}
+
+func (n *lenNode) validate() error {
+	return nil
+}
+
+type atKeyNode struct {
+	key   interface{}
+	query Query
+}
+
+func (n *atKeyNode) toJSON() interface{} {
+	return map[string]interface{}{"at_key": map[string]interface{}{"key": jsonValue(n.key), "query": n.query}}
+}
+
+func (n *atKeyNode) match(element data.Element) (bool, error) {
+	value, ok := lookup(element, n.key)
+	if !ok {
+		return false, nil
+	}
+
+	return n.query.Match(value)
+}
+
+func (n *atKeyNode) validate() error {
+	return n.query.validate()
+}
+
+type hasKeyNode struct {
+	key   interface{}
+	query *Query
+}
+
+func (n *hasKeyNode) toJSON() interface{} {
+	return map[string]interface{}{"has_key": map[string]interface{}{"key": jsonValue(n.key), "query": n.query}}
+}
+
+func (n *hasKeyNode) match(element data.Element) (bool, error) {
+	value, ok := lookup(element, n.key)
+	if !ok || n.query == nil {
+		return ok, nil
+	}
+
+	return n.query.Match(value)
+}
+
+func (n *hasKeyNode) validate() error {
+	if n.query == nil {
+		return nil
+	}
+
+	return n.query.validate()
+}
+
+type atPositionNode struct {
+	position uint
+	query    Query
+}
+
+func (n *atPositionNode) toJSON() interface{} {
+	return map[string]interface{}{"at_position": map[string]interface{}{"position": n.position, "query": n.query}}
+}
+
+func (n *atPositionNode) match(element data.Element) (bool, error) {
+	if n.position >= uint(len(element.Array)) {
+		return false, nil
+	}
+
+	return n.query.Match(element.Array[n.position])
+}
+
+func (n *atPositionNode) validate() error {
+	return n.query.validate()
+}
+
+type containsElementNode struct {
+	element data.Element
+}
+
+func (n *containsElementNode) toJSON() interface{} {
+	return map[string]interface{}{"contains_element": n.element}
+}
+
+func (n *containsElementNode) match(element data.Element) (bool, error) {
+	for _, item := range element.Array {
+		if elementsEqual(item, n.element) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (n *containsElementNode) validate() error {
+	return nil
+}
+
+type elementTypeNode struct {
+	elementType data.ElementType
+}
+
+func (n *elementTypeNode) toJSON() interface{} {
+	return map[string]interface{}{"type": n.elementType}
+}
+
+func (n *elementTypeNode) match(element data.Element) (bool, error) {
+	var elementType data.ElementType
+	switch {
+	case element.Array != nil:
+		elementType = data.ElementArrayType
+	case element.Fields != nil:
+		elementType = data.ElementFieldsType
+	default:
+		elementType = data.ElementValueType
+	}
+
+	return elementType == n.elementType, nil
+}
+
+func (n *elementTypeNode) validate() error {
+	return nil
+}
+
+func lookup(element data.Element, key interface{}) (data.Element, bool) {
+	for k, v := range element.Fields {
+		if valuesEqual(k, key) {
+			return v, true
+		}
+	}
+
+	return data.Element{}, false
+}
+
+func toBig(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case uint8:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint16:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case int:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case big.Int:
+		return &v, true
+	case *big.Int:
+		return v, v != nil
+	}
+
+	return nil, false
+}
+
+func typeOfValue(value interface{}) (data.ValueType, bool) {
+	switch value.(type) {
+	case bool:
+		return data.BoolType, true
+	case string:
+		return data.StringType, true
+	case uint8:
+		return data.U8Type, true
+	case uint16:
+		return data.U16Type, true
+	case uint32:
+		return data.U32Type, true
+	case uint64:
+		return data.U64Type, true
+	case big.Int, *big.Int:
+		return data.U128Type, true
+	case data.Hash:
+		return data.HashType, true
+	case data.Blob:
+		return data.BlobType, true
+	}
+
+	return 0, false
+}
+
+// numbers are compared by value so a query built with an int matches a stored u64
+func valuesEqual(a interface{}, b interface{}) bool {
+	aNumber, aOk := toBig(a)
+	bNumber, bOk := toBig(b)
+	if aOk || bOk {
+		return aOk && bOk && aNumber.Cmp(bNumber) == 0
+	}
+
+	switch av := a.(type) {
+	case data.Blob:
+		bv, ok := b.(data.Blob)
+		return ok && bytes.Equal(av, bv)
+	case data.Hash, string, bool:
+		return a == b
+	}
+
+	return false
+}
+
+func elementsEqual(a data.Element, b data.Element) bool {
+	switch {
+	case a.Array != nil || b.Array != nil:
+		if len(a.Array) != len(b.Array) || a.Array == nil || b.Array == nil {
+			return false
+		}
+
+		for i := range a.Array {
+			if !elementsEqual(a.Array[i], b.Array[i]) {
+				return false
+			}
+		}
+
+		return true
+	case a.Fields != nil || b.Fields != nil:
+		if len(a.Fields) != len(b.Fields) || a.Fields == nil || b.Fields == nil {
+			return false
+		}
+
+		for key, value := range a.Fields {
+			other, ok := lookup(b, key)
+			if !ok || !elementsEqual(value, other) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return valuesEqual(a.Value, b.Value)
+	}
+}
@@ -0,0 +1,250 @@
+// Package query builds queries for the wallet encrypted database.
+//
+//	q.Key("amount").Gt(100).And(q.Value().StartsWith("inv_"))
+//
+// The result can be used in QueryDB, GetMatchingKeys, CountMatchingEntries and ListTransactions
+// and evaluated locally against data.Element values with Match.
+package query
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/xelis-project/xelis-go-sdk/data"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+type node interface {
+	toJSON() interface{}
+	match(element data.Element) (bool, error)
+	// validate checks the values that the wallet would reject, like an invalid regex
+	validate() error
+}
+
+// Query is immutable, every method returns a new query
+type Query struct {
+	node node
+}
+
+func And(queries ...Query) Query {
+	return Query{node: &logicNode{op: "and", queries: queries}}
+}
+
+func Or(queries ...Query) Query {
+	return Query{node: &logicNode{op: "or", queries: queries}}
+}
+
+func Not(query Query) Query {
+	return Query{node: &notNode{query: query}}
+}
+
+func (q Query) And(others ...Query) Query {
+	return And(append([]Query{q}, others...)...)
+}
+
+func (q Query) Or(others ...Query) Query {
+	return Or(append([]Query{q}, others...)...)
+}
+
+func (q Query) Not() Query {
+	return Not(q)
+}
+
+func (q Query) MarshalJSON() ([]byte, error) {
+	if q.node == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(q.node.toJSON())
+}
+
+func (q Query) validate() error {
+	if q.node == nil {
+		return nil
+	}
+
+	return q.node.validate()
+}
+
+// Build validates the query and returns it for the wallet params
+func (q Query) Build() (query *wallet.Query, err error) {
+	err = q.validate()
+	if err != nil {
+		return
+	}
+
+	raw, err := q.MarshalJSON()
+	if err != nil {
+		return
+	}
+
+	query = &wallet.Query{Raw: raw}
+	return
+}
+
+// MustBuild is the same as Build but panics on an invalid query
+func (q Query) MustBuild() *wallet.Query {
+	query, err := q.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	return query
+}
+
+// Match evaluates the query against an element like the wallet does
+func (q Query) Match(element data.Element) (bool, error) {
+	if q.node == nil {
+		return false, ErrEmptyQuery
+	}
+
+	return q.node.match(element)
+}
+
+// Target selects what the next condition applies to
+type Target struct {
+	wrap func(Query) Query
+}
+
+// Value targets the element itself
+func Value() Target {
+	return Target{wrap: func(q Query) Query { return q }}
+}
+
+// Key targets the value at key of a fields element
+func Key(key interface{}) Target {
+	return Value().Key(key)
+}
+
+// Position targets the value at position of an array element
+func Position(position uint) Target {
+	return Value().Position(position)
+}
+
+func (t Target) Key(key interface{}) Target {
+	return Target{wrap: func(q Query) Query {
+		return t.wrap(Query{node: &atKeyNode{key: key, query: q}})
+	}}
+}
+
+func (t Target) Position(position uint) Target {
+	return Target{wrap: func(q Query) Query {
+		return t.wrap(Query{node: &atPositionNode{position: position, query: q}})
+	}}
+}
+
+func (t Target) Where(query Query) Query {
+	return t.wrap(query)
+}
+
+func (t Target) value(op string, value interface{}) Query {
+	return t.wrap(Query{node: &valueNode{op: op, value: value}})
+}
+
+func (t Target) Eq(value interface{}) Query {
+	return t.value("equal", value)
+}
+
+func (t Target) StartsWith(value interface{}) Query {
+	return t.value("starts_with", value)
+}
+
+func (t Target) EndsWith(value interface{}) Query {
+	return t.value("ends_with", value)
+}
+
+func (t Target) Contains(value interface{}) Query {
+	return t.value("contains_value", value)
+}
+
+// Matches checks a string value against a regex
+func (t Target) Matches(regex string) Query {
+	return t.value("matches", regex)
+}
+
+func (t Target) IsOfType(valueType data.ValueType) Query {
+	return t.wrap(Query{node: &valueTypeNode{valueType: valueType}})
+}
+
+func (t Target) number(op string, value uint64) Query {
+	return t.wrap(Query{node: &numberNode{op: op, value: value}})
+}
+
+func (t Target) Gt(value uint64) Query {
+	return t.number("greater", value)
+}
+
+func (t Target) Gte(value uint64) Query {
+	return t.number("greater_or_equal", value)
+}
+
+func (t Target) Lt(value uint64) Query {
+	return t.number("lesser", value)
+}
+
+func (t Target) Lte(value uint64) Query {
+	return t.number("lesser_or_equal", value)
+}
+
+func (t Target) HasKey(key interface{}) Query {
+	return t.wrap(Query{node: &hasKeyNode{key: key}})
+}
+
+// HasKeyWhere checks the key exists and its value matches the query
+func (t Target) HasKeyWhere(key interface{}, query Query) Query {
+	return t.wrap(Query{node: &hasKeyNode{key: key, query: &query}})
+}
+
+func (t Target) ContainsElement(element data.Element) Query {
+	return t.wrap(Query{node: &containsElementNode{element: element}})
+}
+
+func (t Target) IsElementType(elementType data.ElementType) Query {
+	return t.wrap(Query{node: &elementTypeNode{elementType: elementType}})
+}
+
+// Len targets the length of an array, fields or string
+func (t Target) Len() LenTarget {
+	return LenTarget{target: t}
+}
+
+type LenTarget struct {
+	target Target
+}
+
+func (l LenTarget) number(op string, value uint64) Query {
+	return l.target.wrap(Query{node: &lenNode{number: numberNode{op: op, value: value}}})
+}
+
+func (l LenTarget) Gt(value uint64) Query {
+	return l.number("greater", value)
+}
+
+func (l LenTarget) Gte(value uint64) Query {
+	return l.number("greater_or_equal", value)
+}
+
+func (l LenTarget) Lt(value uint64) Query {
+	return l.number("lesser", value)
+}
+
+func (l LenTarget) Lte(value uint64) Query {
+	return l.number("lesser_or_equal", value)
+}
+
+// Eq is a shortcut for Gte(value).And(Lte(value))
+func (l LenTarget) Eq(value uint64) Query {
+	return And(l.Gte(value), l.Lte(value))
+}
+
+// values are sent as their plain JSON, big.Int as a string like data.Element does
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case big.Int:
+		return v.String()
+	case *big.Int:
+		return v.String()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,103 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xelis-project/xelis-go-sdk/data"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+)
+
+func TestQueryJSON(t *testing.T) {
+	tests := []struct {
+		query    Query
+		expected string
+	}{
+		{Value().Eq(100), `{"equal":100}`},
+		{Value().Lt(0), `{"lesser":0}`},
+		{Value().IsElementType(data.ElementValueType), `{"type":0}`},
+		{Value().IsOfType(data.BoolType), `{"is_of_type":0}`},
+		{Key("amount").Gt(100).And(Value().StartsWith("inv_")), `{"and":[{"at_key":{"key":"amount","query":{"greater":100}}},{"starts_with":"inv_"}]}`},
+		{Key("user").Key("name").Eq("alice").Not(), `{"not":{"at_key":{"key":"user","query":{"at_key":{"key":"name","query":{"equal":"alice"}}}}}}`},
+		{Position(1).Len().Gte(2), `{"at_position":{"position":1,"query":{"len":{"greater_or_equal":2}}}}`},
+		{Value().HasKey("id"), `{"has_key":{"key":"id","query":null}}`},
+	}
+
+	for _, test := range tests {
+		b, err := json.Marshal(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != test.expected {
+			t.Fatalf("expected %s, got %s", test.expected, b)
+		}
+
+		// the same JSON must be sent inside wallet params
+		params, err := json.Marshal(wallet.QueryDBParams{Tree: "test", Value: test.query.MustBuild()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `{"tree":"test","value":` + test.expected + `}`
+		if string(params) != expected {
+			t.Fatalf("expected %s, got %s", expected, params)
+		}
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	invoice := data.Element{Fields: map[data.Value]data.Element{
+		"id":     {Value: "inv_42"},
+		"amount": {Value: uint64(150)},
+		"items":  {Array: []data.Element{{Value: "a"}, {Value: "b"}}},
+	}}
+
+	tests := []struct {
+		query    Query
+		expected bool
+	}{
+		{Key("amount").Gt(100), true},
+		{Key("amount").Lte(100), false},
+		{Key("amount").Eq(150), true},
+		{Key("id").StartsWith("inv_").And(Key("amount").Gte(150)), true},
+		{Key("id").Matches(`^inv_\d+$`), true},
+		{Key("id").EndsWith("43").Or(Key("missing").Eq(1)), false},
+		{Key("items").Len().Eq(2), true},
+		{Key("items").Position(1).Eq("b"), true},
+		{Key("items").ContainsElement(data.Element{Value: "c"}), false},
+		{Value().HasKeyWhere("amount", Value().IsOfType(data.U64Type)), true},
+		{Value().IsElementType(data.ElementFieldsType), true},
+		{Key("amount").StartsWith("1").Not(), true},
+	}
+
+	for i, test := range tests {
+		ok, err := test.query.Match(invoice)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok != test.expected {
+			b, _ := json.Marshal(test.query)
+			t.Fatalf("query %d %s: expected %t, got %t", i, b, test.expected, ok)
+		}
+	}
+
+	_, err := Key("id").Matches("(").Match(invoice)
+	if err == nil {
+		t.Fatal("expected invalid regex error")
+	}
+
+	// the regex nested in a logic node is rejected before reaching the wallet
+	_, err = Key("amount").Gt(100).And(Not(Key("id").Matches("("))).Build()
+	if err == nil {
+		t.Fatal("expected Build to reject the invalid regex")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected MustBuild to panic")
+		}
+	}()
+	Key("id").Matches("(").MustBuild()
+}
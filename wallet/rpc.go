@@ -3,16 +3,19 @@ package wallet
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/http"
 
+	"github.com/xelis-project/xelis-go-sdk/config"
 	"github.com/xelis-project/xelis-go-sdk/data"
 	"github.com/xelis-project/xelis-go-sdk/rpc"
 	"github.com/xelis-project/xelis-go-sdk/wallet/methods"
 )
 
 type RPC struct {
-	http *rpc.Http
+	http    *rpc.Http
+	network networkCache
 }
 
 func setAuthHeader(header http.Header, username string, password string) {
@@ -34,7 +37,7 @@ func NewRPC(url string, username string, password string) (*RPC, error) {
 	}
 
 	daemon := &RPC{
-		http,
+		http: http,
 	}
 
 	return daemon, nil
@@ -58,6 +61,11 @@ func (d *RPC) GetNetwork() (network string, err error) {
 	return
 }
 
+// cachedNetwork is GetNetwork fetched once per client, for the destinations checks
+func (d *RPC) cachedNetwork() (config.Network, error) {
+	return d.network.get(d.GetNetwork)
+}
+
 func (d *RPC) GetNonce() (nonce uint64, err error) {
 	_, err = d.Request(methods.GetNonce, nil, &nonce)
 	return
@@ -148,6 +156,10 @@ func (d *RPC) BuildTransaction(params BuildTransactionParams) (result Transactio
 		return
 	}
 
+	if err = checkNetwork(d.cachedNetwork, params.destinations()); err != nil {
+		return
+	}
+
 	_, err = d.Request(methods.BuildTransaction, params, &result)
 	return
 }
@@ -158,6 +170,10 @@ func (d *RPC) BuildTransactionOffline(params BuildTransactionOfflineParams) (res
 }
 
 func (d *RPC) BuildUnsignedTransaction(params BuildUnsignedTransactionParams) (result UnsignedTransactionResponse, err error) {
+	if err = checkNetwork(d.cachedNetwork, params.destinations()); err != nil {
+		return
+	}
+
 	_, err = d.Request(methods.BuildUnsignedTransaction, params, &result)
 	return
 }
@@ -258,6 +274,12 @@ func (d *RPC) GetMatchingKeys(params GetMatchingKeysParams) (result []interface{
 	return
 }
 
+// GetMatchingKeysRaw keeps the JSON of the keys, numbers above 2^53 lose precision in interface{}
+func (d *RPC) GetMatchingKeysRaw(params GetMatchingKeysParams) (result []json.RawMessage, err error) {
+	_, err = d.Request(methods.GetMatchingKeys, params, &result)
+	return
+}
+
 func (d *RPC) CountMatchingEntries(params CountMatchingEntriesParams) (result uint64, err error) {
 	_, err = d.Request(methods.CountMatchingEntries, params, &result)
 	return
@@ -268,12 +290,20 @@ func (d *RPC) GetValueFromKey(params GetValueFromKeyParams) (result interface{},
 	return
 }
 
+// GetValueFromKeyRaw keeps the JSON of the value, numbers above 2^53 lose precision in interface{}
+func (d *RPC) GetValueFromKeyRaw(params GetValueFromKeyParams) (result json.RawMessage, err error) {
+	_, err = d.Request(methods.GetValueFromKey, params, &result)
+	return
+}
+
 func (d *RPC) Store(params StoreParams) (result bool, err error) {
 	_, err = d.Request(methods.Store, params, &result)
 	return
 }
 
-func (d *RPC) Delete(params interface{}) (result bool, err error) {
+// Delete takes DeleteParams like WebSocket.Delete so both satisfy the same interfaces,
+// callers passing another params type must switch to DeleteParams
+func (d *RPC) Delete(params DeleteParams) (result bool, err error) {
 	_, err = d.Request(methods.Delete, params, &result)
 	return
 }
@@ -293,6 +323,12 @@ func (d *RPC) QueryDB(params QueryDBParams) (result QueryResult, err error) {
 	return
 }
 
+// QueryDBRaw keeps the JSON of the entries, numbers above 2^53 lose precision in interface{}
+func (d *RPC) QueryDBRaw(params QueryDBParams) (result RawQueryResult, err error) {
+	_, err = d.Request(methods.QueryDB, params, &result)
+	return
+}
+
 func checkFeeBuilder(fee *FeeBuilder) error {
 	if fee != nil {
 		fixedModes := 0
@@ -308,10 +344,10 @@ func checkFeeBuilder(fee *FeeBuilder) error {
 			}
 		}
 		if fixedModes > 1 || extraModes > 1 || (fixedModes > 0 && extraModes > 0) {
-			return fmt.Errorf("you cannot set multiple fee modes in FeeBuilder")
+			return fmt.Errorf("%w: you cannot set multiple fee modes in FeeBuilder", ErrFeeConflict)
 		}
 		if fee.Extra != nil && fee.Extra.Tip != nil && fee.Extra.Multiplier != nil {
-			return fmt.Errorf("you cannot set both Tip and Multiplier in ExtraFeeMode")
+			return fmt.Errorf("%w: you cannot set both Tip and Multiplier in ExtraFeeMode", ErrFeeConflict)
 		}
 	}
 
@@ -627,7 +627,7 @@ func TestRPCWalletDBKeyStore(t *testing.T) {
 
 	t.Logf("%+v", result4)
 
-	result5, err := wallet.Delete(StoreParams{
+	result5, err := wallet.Delete(DeleteParams{
 		Tree: tree,
 		Key:  "test",
 	})
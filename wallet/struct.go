@@ -3,6 +3,7 @@ package wallet
 import (
 	"encoding/json"
 
+	"github.com/xelis-project/xelis-go-sdk/config"
 	"github.com/xelis-project/xelis-go-sdk/data"
 	"github.com/xelis-project/xelis-go-sdk/transaction"
 	"github.com/xelis-project/xelis-go-sdk/xvm"
@@ -65,6 +66,21 @@ type PlaintextExtraData struct {
 	Data      interface{} `json:"data"`
 }
 
+// Element decodes Data received from the wallet
+func (p PlaintextExtraData) Element() (element data.Element, err error) {
+	if e, ok := p.Data.(data.Element); ok {
+		return e, nil
+	}
+
+	b, err := json.Marshal(p.Data)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(b, &element)
+	return
+}
+
 type TransferOut struct {
 	Amount      uint64              `json:"amount"`
 	Asset       string              `json:"asset"`
@@ -356,7 +372,7 @@ type BalanceChangedResult struct {
 }
 
 type BuildTransactionOfflineParams struct {
-	Transfers      []TransferOut          `json:"transfers"`
+	Transfers      []TransferBuilder      `json:"transfers"`
 	Burn           *transaction.Burn      `json:"burn,omitempty"`
 	MultiSig       *MutliSigBuilder       `json:"multi_sig,omitempty"`
 	InvokeContract *InvokeContractBuilder `json:"invoke_contract,omitempty"`
@@ -374,7 +390,7 @@ type BuildTransactionOfflineParams struct {
 }
 
 type BuildUnsignedTransactionParams struct {
-	Transfers      []TransferOut          `json:"transfers"`
+	Transfers      []TransferBuilder      `json:"transfers"`
 	Burn           *transaction.Burn      `json:"burn,omitempty"`
 	MultiSig       *MutliSigBuilder       `json:"multi_sig,omitempty"`
 	InvokeContract *InvokeContractBuilder `json:"invoke_contract,omitempty"`
@@ -444,27 +460,27 @@ type EstimateExtraDataSizeResult struct {
 }
 
 type NetworkInfoResult struct {
-	Height            uint64  `json:"height"`
-	Topoheight        uint64  `json:"topoheight"`
-	Stableheight      uint64  `json:"stableheight"`
-	StableTopoheight  uint64  `json:"stable_topoheight"`
-	PrunedTopoheight  *uint64 `json:"pruned_topoheight"`
-	TopBlockHash      string  `json:"top_block_hash"`
-	CirculatingSupply uint64  `json:"circulating_supply"`
-	BurnedSupply      uint64  `json:"burned_supply"`
-	EmittedSupply     uint64  `json:"emitted_supply"`
-	MaximumSupply     uint64  `json:"maximum_supply"`
-	Difficulty        string  `json:"difficulty"`
-	BlockTimeTarget   uint64  `json:"block_time_target"`
-	AverageBlockTime  uint64  `json:"average_block_time"`
-	BlockReward       uint64  `json:"block_reward"`
-	DevReward         uint64  `json:"dev_reward"`
-	MinerReward       uint64  `json:"miner_reward"`
-	MempoolSize       uint64  `json:"mempool_size"`
-	Version           string  `json:"version"`
-	Network           string  `json:"network"`
-	BlockVersion      uint8   `json:"block_version"`
-	ConnectedTo       string  `json:"connected_to"`
+	Height            uint64         `json:"height"`
+	Topoheight        uint64         `json:"topoheight"`
+	Stableheight      uint64         `json:"stableheight"`
+	StableTopoheight  uint64         `json:"stable_topoheight"`
+	PrunedTopoheight  *uint64        `json:"pruned_topoheight"`
+	TopBlockHash      string         `json:"top_block_hash"`
+	CirculatingSupply uint64         `json:"circulating_supply"`
+	BurnedSupply      uint64         `json:"burned_supply"`
+	EmittedSupply     uint64         `json:"emitted_supply"`
+	MaximumSupply     uint64         `json:"maximum_supply"`
+	Difficulty        string         `json:"difficulty"`
+	BlockTimeTarget   uint64         `json:"block_time_target"`
+	AverageBlockTime  uint64         `json:"average_block_time"`
+	BlockReward       uint64         `json:"block_reward"`
+	DevReward         uint64         `json:"dev_reward"`
+	MinerReward       uint64         `json:"miner_reward"`
+	MempoolSize       uint64         `json:"mempool_size"`
+	Version           string         `json:"version"`
+	Network           config.Network `json:"network"`
+	BlockVersion      uint8          `json:"block_version"`
+	ConnectedTo       string         `json:"connected_to"`
 }
 
 type CompressedCiphertext struct {
@@ -539,6 +555,11 @@ type QueryResult struct {
 	Next    *uint64                `json:"next"`
 }
 
+type RawQueryResult struct {
+	Entries map[string]json.RawMessage `json:"entries"`
+	Next    *uint64                    `json:"next"`
+}
+
 type Asset struct {
 	Decimals  int         `json:"decimals"`
 	Name      string      `json:"name"`
@@ -1,8 +1,10 @@
 package wallet
 
 import (
+	"encoding/json"
 	"net/http"
 
+	"github.com/xelis-project/xelis-go-sdk/config"
 	"github.com/xelis-project/xelis-go-sdk/daemon"
 	"github.com/xelis-project/xelis-go-sdk/data"
 	"github.com/xelis-project/xelis-go-sdk/rpc"
@@ -11,8 +13,9 @@ import (
 )
 
 type WebSocket struct {
-	Prefix string
-	WS     *rpc.WebSocket
+	Prefix  string
+	WS      *rpc.WebSocket
+	network networkCache
 }
 
 func NewWebSocket(endpoint string, username string, password string) (*WebSocket, error) {
@@ -243,6 +246,11 @@ func (w *WebSocket) GetNetwork() (network string, err error) {
 	return
 }
 
+// cachedNetwork is GetNetwork fetched once per client, for the destinations checks
+func (w *WebSocket) cachedNetwork() (config.Network, error) {
+	return w.network.get(w.GetNetwork)
+}
+
 func (w *WebSocket) GetNonce() (nonce uint64, err error) {
 	_, err = w.WS.Call(w.Prefix+methods.GetNonce, nil, &nonce)
 	return
@@ -333,6 +341,10 @@ func (w *WebSocket) BuildTransaction(params BuildTransactionParams) (result Tran
 		return
 	}
 
+	if err = checkNetwork(w.cachedNetwork, params.destinations()); err != nil {
+		return
+	}
+
 	_, err = w.WS.Call(w.Prefix+methods.BuildTransaction, params, &result)
 	return
 }
@@ -351,6 +363,10 @@ func (w *WebSocket) BuildUnsignedTransaction(params BuildUnsignedTransactionPara
 		return
 	}
 
+	if err = checkNetwork(w.cachedNetwork, params.destinations()); err != nil {
+		return
+	}
+
 	_, err = w.WS.Call(w.Prefix+methods.BuildUnsignedTransaction, params, &result)
 	return
 }
@@ -450,6 +466,12 @@ func (w *WebSocket) GetMatchingKeys(params GetMatchingKeysParams) (result []inte
 	return
 }
 
+// GetMatchingKeysRaw keeps the JSON of the keys, numbers above 2^53 lose precision in interface{}
+func (w *WebSocket) GetMatchingKeysRaw(params GetMatchingKeysParams) (result []json.RawMessage, err error) {
+	_, err = w.WS.Call(w.Prefix+methods.GetMatchingKeys, params, &result)
+	return
+}
+
 func (w *WebSocket) CountMatchingEntries(params CountMatchingEntriesParams) (result uint64, err error) {
 	_, err = w.WS.Call(w.Prefix+methods.CountMatchingEntries, params, &result)
 	return
@@ -460,6 +482,12 @@ func (w *WebSocket) GetValueFromKey(params GetValueFromKeyParams) (result interf
 	return
 }
 
+// GetValueFromKeyRaw keeps the JSON of the value, numbers above 2^53 lose precision in interface{}
+func (w *WebSocket) GetValueFromKeyRaw(params GetValueFromKeyParams) (result json.RawMessage, err error) {
+	_, err = w.WS.Call(w.Prefix+methods.GetValueFromKey, params, &result)
+	return
+}
+
 func (w *WebSocket) Store(params StoreParams) (result bool, err error) {
 	_, err = w.WS.Call(w.Prefix+methods.Store, params, &result)
 	return
@@ -484,3 +512,9 @@ func (w *WebSocket) QueryDB(params QueryDBParams) (result QueryResult, err error
 	_, err = w.WS.Call(w.Prefix+methods.QueryDB, params, &result)
 	return
 }
+
+// QueryDBRaw keeps the JSON of the entries, numbers above 2^53 lose precision in interface{}
+func (w *WebSocket) QueryDBRaw(params QueryDBParams) (result RawQueryResult, err error) {
+	_, err = w.WS.Call(w.Prefix+methods.QueryDB, params, &result)
+	return
+}
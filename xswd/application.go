@@ -0,0 +1,83 @@
+package xswd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// prefixes of the methods and events routed to the daemon and the wallet
+const (
+	NodePrefix   = "node."
+	WalletPrefix = "wallet."
+)
+
+// limits of the application data accepted by the wallet
+const (
+	ApplicationIDSize         = 32
+	MaxNameLength             = 32
+	MaxDescriptionLength      = 255
+	MaxUrlLength              = 255
+	MaxPermissions            = 255
+	MaxPermissionMethodLength = 64
+)
+
+// the messages are the ones of the wallet so both servers answer the same errors
+var ErrInvalidApplicationID = errors.New("Invalid application ID")
+var ErrInvalidApplicationName = errors.New("Invalid application name")
+var ErrApplicationDescriptionTooLong = errors.New("Application description is too long")
+var ErrInvalidApplicationUrl = errors.New("Invalid application URL")
+var ErrTooManyPermissions = errors.New("Too many permissions")
+var ErrInvalidPermission = errors.New("Invalid permission")
+
+func (p Permission) Valid() bool {
+	return p == Ask || p == AcceptAlways || p == DenyAlways
+}
+
+// Validate checks the application data the way the wallet does before asking the user
+func (a ApplicationData) Validate() (err error) {
+	id, err := hex.DecodeString(a.ID)
+	if err != nil || len(id) != ApplicationIDSize {
+		return ErrInvalidApplicationID
+	}
+
+	if strings.TrimSpace(a.Name) == "" || len(a.Name) > MaxNameLength {
+		return ErrInvalidApplicationName
+	}
+
+	if len(a.Description) > MaxDescriptionLength {
+		return ErrApplicationDescriptionTooLong
+	}
+
+	if a.Url != "" {
+		u, err := url.Parse(a.Url)
+		if err != nil || len(a.Url) > MaxUrlLength || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidApplicationUrl
+		}
+	}
+
+	if len(a.Permissions) > MaxPermissions {
+		return ErrTooManyPermissions
+	}
+
+	for method, permission := range a.Permissions {
+		if !ValidMethod(method) || len(method) > MaxPermissionMethodLength || !permission.Valid() {
+			return fmt.Errorf("%w: %s", ErrInvalidPermission, method)
+		}
+	}
+
+	return
+}
+
+// ValidMethod reports if the method is prefixed by node. or wallet. and has a name
+func ValidMethod(method string) bool {
+	for _, prefix := range []string{NodePrefix, WalletPrefix} {
+		if strings.HasPrefix(method, prefix) && len(method) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package xswd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/xelis-project/xelis-go-sdk/signature"
+)
+
+var ErrMissingSignature = errors.New("Application signature is missing")
+var ErrInvalidSignature = errors.New("Invalid application signature")
+
+// NewApplicationID returns a random id, an application keeps it to be recognized by the wallet
+func NewApplicationID() (id string, err error) {
+	b := make([]byte, ApplicationIDSize)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+
+	id = hex.EncodeToString(b)
+	return
+}
+
+// SigningData is the canonical json of the application without its signature:
+// fields in declaration order, sorted permissions and no whitespace
+func (a ApplicationData) SigningData() ([]byte, error) {
+	a.Signature = ""
+	if a.Permissions == nil {
+		a.Permissions = make(map[string]Permission)
+	}
+
+	return json.Marshal(a)
+}
+
+// Sign sets the id to the public key of the key and signs the application data,
+// so the signature is verified from the application data alone
+func (a *ApplicationData) Sign(key *signature.PrivateKey) (err error) {
+	publicKey := key.PublicKey()
+	a.ID = hex.EncodeToString(publicKey[:])
+
+	data, err := a.SigningData()
+	if err != nil {
+		return
+	}
+
+	a.Signature, err = key.Sign(data)
+	return
+}
+
+// VerifySignature checks the signature of Sign with the public key of the id
+func (a ApplicationData) VerifySignature() (err error) {
+	if a.Signature == "" {
+		return ErrMissingSignature
+	}
+
+	publicKey, err := hex.DecodeString(a.ID)
+	if err != nil || len(publicKey) != ApplicationIDSize {
+		return ErrInvalidApplicationID
+	}
+
+	// s || e of 32 bytes each
+	if len(a.Signature) != 128 {
+		return ErrInvalidSignature
+	}
+
+	data, err := a.SigningData()
+	if err != nil {
+		return
+	}
+
+	valid, err := signature.Verify2(a.ID, a.Signature, data)
+	if err != nil || !valid {
+		return ErrInvalidSignature
+	}
+
+	return
+}
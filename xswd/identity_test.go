@@ -0,0 +1,213 @@
+package xswd
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	daemonMethods "github.com/xelis-project/xelis-go-sdk/daemon/methods"
+	"github.com/xelis-project/xelis-go-sdk/signature"
+	walletMethods "github.com/xelis-project/xelis-go-sdk/wallet/methods"
+)
+
+func testApplication(t *testing.T) ApplicationData {
+	id, err := NewApplicationID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ApplicationData{
+		ID:          id,
+		Name:        "Test App",
+		Description: "This is a test app.",
+		Url:         "https://example.com",
+		Permissions: map[string]Permission{"wallet.get_balance": Ask, "node.get_info": AcceptAlways},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		edit func(app *ApplicationData)
+		err  error
+	}{
+		{func(app *ApplicationData) {}, nil},
+		{func(app *ApplicationData) { app.Url = "" }, nil},
+		{func(app *ApplicationData) { app.ID = "ertherth" }, ErrInvalidApplicationID},
+		{func(app *ApplicationData) { app.ID = app.ID[:62] }, ErrInvalidApplicationID},
+		{func(app *ApplicationData) { app.Name = " " }, ErrInvalidApplicationName},
+		{func(app *ApplicationData) { app.Name = strings.Repeat("a", MaxNameLength+1) }, ErrInvalidApplicationName},
+		{func(app *ApplicationData) { app.Description = strings.Repeat("a", MaxDescriptionLength+1) }, ErrApplicationDescriptionTooLong},
+		{func(app *ApplicationData) { app.Url = "ftp://example.com" }, ErrInvalidApplicationUrl},
+		{func(app *ApplicationData) { app.Url = "https://" }, ErrInvalidApplicationUrl},
+		{func(app *ApplicationData) { app.Permissions["get_balance"] = Ask }, ErrInvalidPermission},
+		{func(app *ApplicationData) { app.Permissions["wallet."] = Ask }, ErrInvalidPermission},
+		{func(app *ApplicationData) { app.Permissions["wallet.get_balance"] = 3 }, ErrInvalidPermission},
+	}
+
+	for i, test := range tests {
+		app := testApplication(t)
+		test.edit(&app)
+
+		err := app.Validate()
+		if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+			t.Fatalf("test %d: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestNewApplicationID(t *testing.T) {
+	id, err := NewApplicationID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewApplicationID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(id) != 2*ApplicationIDSize || id == other {
+		t.Fatalf("ids %s %s", id, other)
+	}
+}
+
+func TestSigningData(t *testing.T) {
+	app := testApplication(t)
+	app.ID = strings.Repeat("ab", ApplicationIDSize)
+	app.Signature = "ignored"
+
+	data, err := app.SigningData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"id":"` + app.ID + `","name":"Test App","description":"This is a test app.","url":"https://example.com",` +
+		`"permissions":{"node.get_info":1,"wallet.get_balance":0}}`
+	if string(data) != expected {
+		t.Fatalf("signing data %s", data)
+	}
+
+	app.Permissions = nil
+	data, err = app.SigningData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(string(data), `"permissions":{}}`) {
+		t.Fatalf("nil permissions %s", data)
+	}
+}
+
+func TestSignApplication(t *testing.T) {
+	key, err := signature.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := testApplication(t)
+	err = app.VerifySignature()
+	if err != ErrMissingSignature {
+		t.Fatalf("unsigned: %v", err)
+	}
+
+	err = app.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey := key.PublicKey()
+	if app.ID != hex.EncodeToString(publicKey[:]) {
+		t.Fatalf("id %s is not the public key", app.ID)
+	}
+
+	err = app.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.VerifySignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := app
+	tampered.Name = "Other App"
+	if tampered.VerifySignature() != ErrInvalidSignature {
+		t.Fatal("tampered name verified")
+	}
+
+	tampered = app
+	tampered.Permissions = map[string]Permission{"wallet.get_balance": AcceptAlways}
+	if tampered.VerifySignature() != ErrInvalidSignature {
+		t.Fatal("tampered permissions verified")
+	}
+
+	tampered = app
+	tampered.Signature = app.Signature[:64]
+	if tampered.VerifySignature() != ErrInvalidSignature {
+		t.Fatal("short signature verified")
+	}
+
+	tampered = app
+	tampered.ID, _ = NewApplicationID()
+	if tampered.VerifySignature() != ErrInvalidSignature {
+		t.Fatal("other id verified")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	permissions, err := NewManifest().
+		Node(Ask, daemonMethods.GetInfo).
+		Wallet(Ask, walletMethods.GetBalance, walletMethods.BuildTransaction).
+		Wallet(AcceptAlways, walletMethods.GetAddress).
+		DenyAlways("wallet.clear_tx_cache").
+		Permissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]Permission{
+		"node." + daemonMethods.GetInfo:            Ask,
+		"wallet." + walletMethods.GetBalance:       Ask,
+		"wallet." + walletMethods.BuildTransaction: Ask,
+		"wallet." + walletMethods.GetAddress:       AcceptAlways,
+		"wallet.clear_tx_cache":                    DenyAlways,
+	}
+
+	if len(permissions) != len(expected) {
+		t.Fatalf("permissions %v", permissions)
+	}
+
+	for method, permission := range expected {
+		if permissions[method] != permission {
+			t.Fatalf("%s: %d", method, permissions[method])
+		}
+	}
+
+	_, err = NewManifest().Wallet(Ask, walletMethods.GetBalance).Wallet(DenyAlways, walletMethods.GetBalance).Permissions()
+	if !errors.Is(err, ErrPermissionConflict) {
+		t.Fatalf("conflict: %v", err)
+	}
+
+	_, err = NewManifest().Ask("get_balance").Permissions()
+	if !errors.Is(err, ErrInvalidPermission) {
+		t.Fatalf("unprefixed method: %v", err)
+	}
+
+	_, err = NewManifest().Wallet(Permission(5), walletMethods.GetBalance).Permissions()
+	if !errors.Is(err, ErrInvalidPermission) {
+		t.Fatalf("invalid permission: %v", err)
+	}
+
+	app := testApplication(t)
+	err = NewManifest().Ask("node.").Apply(&app)
+	if err == nil || len(app.Permissions) != 2 {
+		t.Fatalf("failed manifest applied: %v %v", err, app.Permissions)
+	}
+
+	err = NewManifest().AcceptAlways("wallet.get_address").Apply(&app)
+	if err != nil || len(app.Permissions) != 1 || app.Permissions["wallet.get_address"] != AcceptAlways {
+		t.Fatalf("applied: %v %v", err, app.Permissions)
+	}
+}
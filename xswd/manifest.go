@@ -0,0 +1,106 @@
+package xswd
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrPermissionConflict = errors.New("method requested with different permissions")
+
+// Manifest builds the permissions requested by an application, the first error is kept
+// and returned by Permissions
+type Manifest struct {
+	permissions map[string]Permission
+	err         error
+}
+
+func NewManifest() *Manifest {
+	return &Manifest{permissions: make(map[string]Permission)}
+}
+
+func (m *Manifest) setErr(err error) *Manifest {
+	if m.err == nil {
+		m.err = err
+	}
+
+	return m
+}
+
+func (m *Manifest) request(prefix string, permission Permission, methods []string) *Manifest {
+	if !permission.Valid() {
+		return m.setErr(fmt.Errorf("%w: %d", ErrInvalidPermission, permission))
+	}
+
+	for _, method := range methods {
+		method = prefix + method
+		if !ValidMethod(method) || len(method) > MaxPermissionMethodLength {
+			return m.setErr(fmt.Errorf("%w: %s", ErrInvalidPermission, method))
+		}
+
+		current, ok := m.permissions[method]
+		if ok && current != permission {
+			return m.setErr(fmt.Errorf("%w: %s", ErrPermissionConflict, method))
+		}
+
+		m.permissions[method] = permission
+	}
+
+	if len(m.permissions) > MaxPermissions {
+		m.setErr(ErrTooManyPermissions)
+	}
+
+	return m
+}
+
+// Node requests daemon methods, without the node. prefix
+func (m *Manifest) Node(permission Permission, methods ...string) *Manifest {
+	return m.request(NodePrefix, permission, methods)
+}
+
+// Wallet requests wallet methods, without the wallet. prefix
+func (m *Manifest) Wallet(permission Permission, methods ...string) *Manifest {
+	return m.request(WalletPrefix, permission, methods)
+}
+
+// Ask, AcceptAlways and DenyAlways request prefixed methods like wallet.get_balance
+func (m *Manifest) Ask(methods ...string) *Manifest {
+	return m.request("", Ask, methods)
+}
+
+func (m *Manifest) AcceptAlways(methods ...string) *Manifest {
+	return m.request("", AcceptAlways, methods)
+}
+
+func (m *Manifest) DenyAlways(methods ...string) *Manifest {
+	return m.request("", DenyAlways, methods)
+}
+
+func (m *Manifest) Err() error {
+	return m.err
+}
+
+// Permissions returns a copy of the requested permissions
+func (m *Manifest) Permissions() (permissions map[string]Permission, err error) {
+	if m.err != nil {
+		err = m.err
+		return
+	}
+
+	permissions = make(map[string]Permission, len(m.permissions))
+	for method, permission := range m.permissions {
+		permissions[method] = permission
+	}
+
+	return
+}
+
+// Apply sets the permissions of the application, it must be signed after
+func (m *Manifest) Apply(app *ApplicationData) (err error) {
+	permissions, err := m.Permissions()
+	if err != nil {
+		return
+	}
+
+	app.Permissions = permissions
+	return
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+)
+
+var ErrMethodNotFound = errors.New("Method not found")
+var ErrInvalidParams = errors.New("Invalid params")
+
+// Backend executes the calls routed to it, the method has no node. or wallet. prefix.
+// Returning an *rpc.RPCError sends its code and message to the application.
+type Backend interface {
+	Call(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error)
+}
+
+// Subscriber is implemented by backends pushing events, onData is called for each event
+// until the backend connection is closed
+type Subscriber interface {
+	Subscribe(event string, onData func(json.RawMessage)) error
+}
+
+// Requester is implemented by rpc.Http, daemon.RPC and wallet.RPC
+type Requester interface {
+	Request(method string, params interface{}, result interface{}) (*http.Response, error)
+}
+
+// params are omitted from the forwarded request if the application sent none
+func requestParams(params json.RawMessage) interface{} {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+
+	return params
+}
+
+type httpBackend struct {
+	requester Requester
+}
+
+// NewHttpBackend forwards the calls over http, the context is not passed to the request
+// so it ends with the requester timeout
+func NewHttpBackend(requester Requester) Backend {
+	return httpBackend{requester: requester}
+}
+
+func (b httpBackend) Call(ctx context.Context, method string, params json.RawMessage) (result json.RawMessage, err error) {
+	_, err = b.requester.Request(method, requestParams(params), &result)
+	return
+}
+
+// WebSocketBackend forwards the calls and events of a websocket connection.
+// rpc.WebSocket is not safe for concurrent calls so they are sent one at a time.
+type WebSocketBackend struct {
+	ws    *rpc.WebSocket
+	mutex sync.Mutex
+}
+
+func NewWebSocketBackend(ws *rpc.WebSocket) *WebSocketBackend {
+	return &WebSocketBackend{ws: ws}
+}
+
+func (b *WebSocketBackend) Call(ctx context.Context, method string, params json.RawMessage) (result json.RawMessage, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	_, err = b.ws.Call(method, requestParams(params), &result)
+	return
+}
+
+func (b *WebSocketBackend) Subscribe(event string, onData func(json.RawMessage)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.ws.ListenEventFunc(event, func(res rpc.RPCResponse) {
+		onData(res.Result)
+	})
+}
+
+// Handler implements a method in Go, the result is sent as json
+type Handler func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// Handlers is a Backend calling the handler of each method,
+// events of such backends are sent with Server.Notify
+type Handlers map[string]Handler
+
+func (h Handlers) Call(ctx context.Context, method string, params json.RawMessage) (result json.RawMessage, err error) {
+	handler, ok := h[method]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrMethodNotFound, method)
+		return
+	}
+
+	value, err := handler(ctx, params)
+	if err != nil {
+		return
+	}
+
+	return json.Marshal(value)
+}
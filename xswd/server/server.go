@@ -0,0 +1,752 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+	"github.com/xelis-project/xelis-go-sdk/xswd"
+)
+
+var ErrNoApprover = errors.New("application and request approval callbacks are required")
+
+// errors sent to the applications, the messages are the ones of the wallet when it has them
+var ErrParse = errors.New("Parse error")
+var ErrInvalidRequest = errors.New("Invalid request")
+var ErrPermissionDenied = errors.New("Permission denied")
+var ErrApplicationIDUsed = errors.New("Application ID is already used")
+var ErrApplicationRejected = errors.New("Application has been rejected")
+var ErrRegistrationTimeout = errors.New("Application data was not received in time")
+var ErrSignatureRequired = errors.New("Application signature is required")
+var ErrInvalidOrigin = errors.New("Application URL does not match its origin")
+var ErrInvalidSignature = xswd.ErrInvalidSignature
+
+// JSON-RPC error codes of the responses
+const (
+	CodeParseError         = -32700
+	CodeInvalidRequest     = -32600
+	CodeMethodNotFound     = -32601
+	CodeInvalidParams      = -32602
+	CodeInternalError      = -32603
+	CodePermissionDenied   = -32000
+	CodeInvalidApplication = -32001
+)
+
+const (
+	SubscribeMethod   = "subscribe"
+	UnsubscribeMethod = "unsubscribe"
+)
+
+// RegisteredMessage is the result message of a successful registration
+const RegisteredMessage = "Application has been registered"
+
+// Decision is the answer of the user to a request of an application,
+// the always decisions are stored as the permission of the method
+type Decision int
+
+const (
+	Reject Decision = iota
+	Accept
+	AlwaysAccept
+	AlwaysReject
+)
+
+// Request is a call of an application waiting for the approval of the user
+type Request struct {
+	Application xswd.ApplicationData
+	Method      string
+	Params      json.RawMessage
+}
+
+type Options struct {
+	// Daemon receives the node. calls, they are public data and never need a permission
+	Daemon Backend
+	// Wallet receives the wallet. calls once the user allowed them
+	Wallet Backend
+	// Store keeps the permissions between connections, in memory by default
+	Store Store
+	// ApproveApplication is called for each registration and returns the permissions granted by the user.
+	// The permissions requested by the application are only shown to the user, a method
+	// not granted here is asked on each call. The context is done when the application disconnects.
+	ApproveApplication func(ctx context.Context, app xswd.ApplicationData) (granted map[string]xswd.Permission, approved bool, err error)
+	// ApproveRequest is called for the wallet calls and subscriptions without an always permission,
+	// calls of an application are handled one at a time so it never has two pending requests
+	ApproveRequest func(ctx context.Context, request Request) (Decision, error)
+	// VerifySignature checks the signature of the application data when there is one,
+	// ApplicationData.VerifySignature by default
+	VerifySignature func(app xswd.ApplicationData) error
+	// RequireSignature rejects the applications without signature
+	RequireSignature bool
+	// RegistrationTimeout is the time given to send the application data, 30s by default
+	RegistrationTimeout time.Duration
+	// CheckOrigin is passed to the websocket upgrader. If nil, native applications without origin
+	// and web pages served over http or https are allowed, their url is checked against the origin on registration.
+	CheckOrigin func(r *http.Request) bool
+	// OnError receives the store and event errors
+	OnError func(error)
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpc.RPCError   `json:"error,omitempty"`
+}
+
+type registeredResult struct {
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+}
+
+type application struct {
+	data   xswd.ApplicationData
+	origin string
+	conn   *websocket.Conn
+
+	writeMutex sync.Mutex
+
+	mutex         sync.Mutex
+	registered    bool
+	permissions   map[string]xswd.Permission
+	subscriptions map[string]json.RawMessage
+}
+
+func (a *application) write(value interface{}) error {
+	a.writeMutex.Lock()
+	defer a.writeMutex.Unlock()
+
+	a.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return a.conn.WriteJSON(value)
+}
+
+// Server hosts the XSWD protocol: applications register with their ApplicationData,
+// then their node. and wallet. calls are routed to the backends once allowed by the user
+type Server struct {
+	options  Options
+	upgrader websocket.Upgrader
+
+	mutex        sync.Mutex
+	applications map[string]*application
+	subscribed   map[string]bool
+}
+
+func NewServer(options Options) (*Server, error) {
+	if options.ApproveApplication == nil || options.ApproveRequest == nil {
+		return nil, ErrNoApprover
+	}
+
+	if options.Store == nil {
+		options.Store = NewMemoryStore()
+	}
+
+	if options.VerifySignature == nil {
+		options.VerifySignature = xswd.ApplicationData.VerifySignature
+	}
+
+	if options.RegistrationTimeout <= 0 {
+		options.RegistrationTimeout = 30 * time.Second
+	}
+
+	checkOrigin := options.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = allowOrigin
+	}
+
+	return &Server{
+		options:      options,
+		upgrader:     websocket.Upgrader{CheckOrigin: checkOrigin},
+		applications: make(map[string]*application),
+		subscribed:   make(map[string]bool),
+	}, nil
+}
+
+// allowOrigin accepts the requests without origin and the ones of http and https pages
+func allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// checkOrigin rejects the web pages registering with the url of another site,
+// native applications send no origin
+func checkOrigin(data xswd.ApplicationData, origin string) error {
+	if origin == "" {
+		return nil
+	}
+
+	u, err := url.Parse(data.Url)
+	if err != nil || u.Scheme+"://"+u.Host != origin {
+		return ErrInvalidOrigin
+	}
+
+	return nil
+}
+
+func (s *Server) reportErr(err error) {
+	if s.options.OnError != nil {
+		s.options.OnError(err)
+	}
+}
+
+// ServeHTTP accepts applications on any path, the wallet serves them at /xswd
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.serve(conn, r.Header.Get("Origin"))
+}
+
+func (s *Server) serve(conn *websocket.Conn, origin string) {
+	defer conn.Close()
+
+	// done when the application disconnects so pending approvals are abandoned
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages := make(chan []byte)
+	go func() {
+		defer cancel()
+		defer close(messages)
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	app, err := s.register(ctx, conn, origin, messages)
+	if err != nil {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		conn.WriteJSON(newResponse(nil, nil, err))
+		return
+	}
+
+	defer s.unregister(app)
+
+	for msg := range messages {
+		err = app.write(s.handle(ctx, app, msg))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) register(ctx context.Context, conn *websocket.Conn, origin string, messages <-chan []byte) (app *application, err error) {
+	timer := time.NewTimer(s.options.RegistrationTimeout)
+	defer timer.Stop()
+
+	var msg []byte
+	select {
+	case msg = <-messages:
+	case <-timer.C:
+		err = ErrRegistrationTimeout
+		return
+	}
+
+	var data xswd.ApplicationData
+	err = json.Unmarshal(msg, &data)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+		return
+	}
+
+	err = data.Validate()
+	if err != nil {
+		return
+	}
+
+	err = checkOrigin(data, origin)
+	if err != nil {
+		return
+	}
+
+	err = s.verifySignature(data)
+	if err != nil {
+		return
+	}
+
+	candidate := &application{
+		data:          data,
+		origin:        origin,
+		conn:          conn,
+		subscriptions: make(map[string]json.RawMessage),
+	}
+
+	// the id is reserved while the user is asked
+	s.mutex.Lock()
+	_, used := s.applications[data.ID]
+	if !used {
+		s.applications[data.ID] = candidate
+	}
+	s.mutex.Unlock()
+
+	if used {
+		err = ErrApplicationIDUsed
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			s.unregister(candidate)
+		}
+	}()
+
+	granted, approved, err := s.options.ApproveApplication(ctx, data)
+	if err != nil {
+		return
+	}
+
+	if !approved {
+		err = ErrApplicationRejected
+		return
+	}
+
+	stored, ok, err := s.options.Store.Get(data.ID)
+	if err != nil {
+		return
+	}
+
+	// the previous choices of the user are kept unless changed by this approval
+	permissions := make(map[string]xswd.Permission)
+	if ok && stored.Matches(data, origin) {
+		permissions = copyPermissions(stored.Permissions)
+	}
+
+	for method, permission := range granted {
+		if xswd.ValidMethod(method) && permission.Valid() {
+			permissions[method] = permission
+		}
+	}
+
+	err = s.options.Store.Put(StoredApplication{ID: data.ID, Name: data.Name, Url: data.Url, Origin: origin, Permissions: permissions})
+	if err != nil {
+		s.reportErr(err)
+	}
+
+	candidate.mutex.Lock()
+	candidate.permissions = permissions
+	candidate.registered = true
+	candidate.mutex.Unlock()
+
+	err = candidate.write(newResponse(nil, registeredResult{Message: RegisteredMessage, Success: true}, nil))
+	if err != nil {
+		return
+	}
+
+	app = candidate
+	return
+}
+
+func (s *Server) verifySignature(data xswd.ApplicationData) (err error) {
+	if data.Signature == "" {
+		if s.options.RequireSignature {
+			err = ErrSignatureRequired
+		}
+
+		return
+	}
+
+	err = s.options.VerifySignature(data)
+	if err != nil && !errors.Is(err, ErrInvalidSignature) {
+		err = fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+	}
+
+	return
+}
+
+func (s *Server) unregister(app *application) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.applications[app.data.ID] == app {
+		delete(s.applications, app.data.ID)
+	}
+}
+
+func (s *Server) handle(ctx context.Context, app *application, msg []byte) response {
+	var req request
+	err := json.Unmarshal(msg, &req)
+	if err != nil {
+		return newResponse(nil, nil, fmt.Errorf("%w: %s", ErrParse, err))
+	}
+
+	if req.Method == "" {
+		return newResponse(req.ID, nil, ErrInvalidRequest)
+	}
+
+	result, err := s.call(ctx, app, req)
+	return newResponse(req.ID, result, err)
+}
+
+func (s *Server) call(ctx context.Context, app *application, req request) (result interface{}, err error) {
+	switch req.Method {
+	case SubscribeMethod:
+		return s.subscribe(ctx, app, req, "")
+	case UnsubscribeMethod:
+		return s.unsubscribe(app, req, "")
+	}
+
+	prefix, method := splitMethod(req.Method)
+	backend := s.backend(prefix)
+	if backend == nil {
+		err = fmt.Errorf("%w: %s", ErrMethodNotFound, req.Method)
+		return
+	}
+
+	switch method {
+	case SubscribeMethod:
+		return s.subscribe(ctx, app, req, prefix)
+	case UnsubscribeMethod:
+		return s.unsubscribe(app, req, prefix)
+	}
+
+	if prefix == xswd.WalletPrefix {
+		err = s.authorize(ctx, app, req.Method, req.Params)
+		if err != nil {
+			return
+		}
+	}
+
+	return backend.Call(ctx, method, req.Params)
+}
+
+// splitMethod returns the node. or wallet. prefix and the method without it
+func splitMethod(method string) (prefix string, name string) {
+	if !xswd.ValidMethod(method) {
+		return
+	}
+
+	i := strings.Index(method, ".")
+	return method[:i+1], method[i+1:]
+}
+
+func (s *Server) backend(prefix string) Backend {
+	switch prefix {
+	case xswd.NodePrefix:
+		return s.options.Daemon
+	case xswd.WalletPrefix:
+		return s.options.Wallet
+	}
+
+	return nil
+}
+
+// authorize asks the user unless the application has an always permission for the method
+func (s *Server) authorize(ctx context.Context, app *application, method string, params json.RawMessage) (err error) {
+	app.mutex.Lock()
+	permission := app.permissions[method]
+	app.mutex.Unlock()
+
+	switch permission {
+	case xswd.AcceptAlways:
+		return
+	case xswd.DenyAlways:
+		return ErrPermissionDenied
+	}
+
+	decision, err := s.options.ApproveRequest(ctx, Request{Application: app.data, Method: method, Params: params})
+	if err != nil {
+		return
+	}
+
+	switch decision {
+	case Accept:
+		return
+	case AlwaysAccept:
+		s.setPermission(app, method, xswd.AcceptAlways)
+		return
+	case AlwaysReject:
+		s.setPermission(app, method, xswd.DenyAlways)
+	}
+
+	return ErrPermissionDenied
+}
+
+func (s *Server) setPermission(app *application, method string, permission xswd.Permission) {
+	app.mutex.Lock()
+	app.permissions[method] = permission
+	stored := StoredApplication{ID: app.data.ID, Name: app.data.Name, Url: app.data.Url, Origin: app.origin, Permissions: copyPermissions(app.permissions)}
+	app.mutex.Unlock()
+
+	err := s.options.Store.Put(stored)
+	if err != nil {
+		s.reportErr(err)
+	}
+}
+
+// event returns the prefixed event name of the subscription params,
+// prefix is empty when the event is already prefixed
+func event(params json.RawMessage, prefix string) (name string, err error) {
+	var subscription struct {
+		Notify string `json:"notify"`
+	}
+
+	err = json.Unmarshal(params, &subscription)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidParams, err)
+		return
+	}
+
+	name = prefix + subscription.Notify
+	if !xswd.ValidMethod(name) {
+		err = fmt.Errorf("%w: invalid event %q", ErrInvalidParams, name)
+	}
+
+	return
+}
+
+// subscribe sends the events to the application with the id of the subscribe request
+func (s *Server) subscribe(ctx context.Context, app *application, req request, prefix string) (result interface{}, err error) {
+	if len(req.ID) == 0 || string(req.ID) == "null" {
+		err = fmt.Errorf("%w: subscriptions need a request id", ErrInvalidRequest)
+		return
+	}
+
+	name, err := event(req.Params, prefix)
+	if err != nil {
+		return
+	}
+
+	eventPrefix, eventName := splitMethod(name)
+	backend := s.backend(eventPrefix)
+	if backend == nil {
+		err = fmt.Errorf("%w: %s", ErrMethodNotFound, name)
+		return
+	}
+
+	if eventPrefix == xswd.WalletPrefix {
+		err = s.authorize(ctx, app, xswd.WalletPrefix+SubscribeMethod, req.Params)
+		if err != nil {
+			return
+		}
+	}
+
+	err = s.subscribeBackend(backend, name, eventName)
+	if err != nil {
+		return
+	}
+
+	app.mutex.Lock()
+	app.subscriptions[name] = req.ID
+	app.mutex.Unlock()
+
+	return true, nil
+}
+
+// subscribeBackend subscribes once to the events of the backends pushing them
+func (s *Server) subscribeBackend(backend Backend, name string, event string) (err error) {
+	subscriber, ok := backend.(Subscriber)
+	if !ok {
+		return
+	}
+
+	s.mutex.Lock()
+	if s.subscribed[name] {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.subscribed[name] = true
+	s.mutex.Unlock()
+
+	err = subscriber.Subscribe(event, func(data json.RawMessage) {
+		s.Notify(name, data)
+	})
+
+	if err != nil {
+		s.mutex.Lock()
+		delete(s.subscribed, name)
+		s.mutex.Unlock()
+	}
+
+	return
+}
+
+func (s *Server) unsubscribe(app *application, req request, prefix string) (result interface{}, err error) {
+	name, err := event(req.Params, prefix)
+	if err != nil {
+		return
+	}
+
+	app.mutex.Lock()
+	_, ok := app.subscriptions[name]
+	delete(app.subscriptions, name)
+	app.mutex.Unlock()
+
+	if !ok {
+		err = fmt.Errorf("%w: not subscribed to %s", ErrInvalidParams, name)
+		return
+	}
+
+	return true, nil
+}
+
+// Notify sends the event to the applications subscribed to it,
+// the event is prefixed like node.new_block
+func (s *Server) Notify(event string, data interface{}) {
+	result, err := json.Marshal(data)
+	if err != nil {
+		s.reportErr(err)
+		return
+	}
+
+	for _, app := range s.registered() {
+		app.mutex.Lock()
+		id, ok := app.subscriptions[event]
+		app.mutex.Unlock()
+
+		if ok {
+			app.write(response{JSONRPC: "2.0", ID: id, Result: json.RawMessage(result)})
+		}
+	}
+}
+
+func (s *Server) registered() []*application {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	apps := make([]*application, 0, len(s.applications))
+	for _, app := range s.applications {
+		app.mutex.Lock()
+		if app.registered {
+			apps = append(apps, app)
+		}
+		app.mutex.Unlock()
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].data.ID < apps[j].data.ID
+	})
+
+	return apps
+}
+
+// Applications returns the connected applications sorted by id
+func (s *Server) Applications() []xswd.ApplicationData {
+	apps := s.registered()
+	data := make([]xswd.ApplicationData, len(apps))
+	for i, app := range apps {
+		data[i] = app.data
+	}
+
+	return data
+}
+
+// Disconnect closes the connection of the application, false if it is not connected
+func (s *Server) Disconnect(id string) bool {
+	s.mutex.Lock()
+	app, ok := s.applications[id]
+	s.mutex.Unlock()
+
+	if ok {
+		app.conn.Close()
+	}
+
+	return ok
+}
+
+// Revoke deletes the stored permissions of the application and disconnects it
+func (s *Server) Revoke(id string) (err error) {
+	err = s.options.Store.Delete(id)
+	if err != nil {
+		return
+	}
+
+	s.Disconnect(id)
+	return
+}
+
+// Close disconnects all the applications
+func (s *Server) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, app := range s.applications {
+		app.conn.Close()
+	}
+}
+
+func newResponse(id json.RawMessage, result interface{}, err error) (res response) {
+	res.JSONRPC = "2.0"
+	res.ID = id
+	if len(id) == 0 {
+		res.ID = json.RawMessage("null")
+	}
+
+	if err != nil {
+		res.Error = rpcError(err)
+		return
+	}
+
+	res.Result = result
+	if res.Result == nil {
+		res.Result = json.RawMessage("null")
+	}
+
+	return
+}
+
+func rpcError(err error) *rpc.RPCError {
+	var rpcErr *rpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	code := CodeInternalError
+	switch {
+	case errors.Is(err, ErrParse):
+		code = CodeParseError
+	case errors.Is(err, ErrInvalidRequest):
+		code = CodeInvalidRequest
+	case errors.Is(err, ErrMethodNotFound):
+		code = CodeMethodNotFound
+	case errors.Is(err, ErrInvalidParams):
+		code = CodeInvalidParams
+	case errors.Is(err, ErrPermissionDenied):
+		code = CodePermissionDenied
+	case errors.Is(err, xswd.ErrInvalidApplicationID),
+		errors.Is(err, xswd.ErrInvalidApplicationName),
+		errors.Is(err, xswd.ErrApplicationDescriptionTooLong),
+		errors.Is(err, xswd.ErrInvalidApplicationUrl),
+		errors.Is(err, xswd.ErrTooManyPermissions),
+		errors.Is(err, xswd.ErrInvalidPermission),
+		errors.Is(err, ErrApplicationIDUsed),
+		errors.Is(err, ErrApplicationRejected),
+		errors.Is(err, ErrRegistrationTimeout),
+		errors.Is(err, ErrSignatureRequired),
+		errors.Is(err, ErrInvalidSignature):
+		code = CodeInvalidApplication
+	}
+
+	return &rpc.RPCError{Code: code, Message: err.Error()}
+}
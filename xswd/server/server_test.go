@@ -0,0 +1,696 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xelis-project/xelis-go-sdk/daemon"
+	daemonEvents "github.com/xelis-project/xelis-go-sdk/daemon/events"
+	"github.com/xelis-project/xelis-go-sdk/rpc"
+	"github.com/xelis-project/xelis-go-sdk/signature"
+	"github.com/xelis-project/xelis-go-sdk/wallet"
+	walletEvents "github.com/xelis-project/xelis-go-sdk/wallet/events"
+	"github.com/xelis-project/xelis-go-sdk/xswd"
+)
+
+const testAppID = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+type approver struct {
+	mutex     sync.Mutex
+	reject    bool
+	granted   map[string]xswd.Permission
+	decisions []Decision
+	requests  []Request
+}
+
+func (a *approver) application(ctx context.Context, app xswd.ApplicationData) (map[string]xswd.Permission, bool, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.granted, !a.reject, nil
+}
+
+func (a *approver) grant(granted map[string]xswd.Permission) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.granted = granted
+}
+
+func (a *approver) request(ctx context.Context, request Request) (decision Decision, err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.requests = append(a.requests, request)
+	if len(a.decisions) > 0 {
+		decision = a.decisions[0]
+		a.decisions = a.decisions[1:]
+	}
+
+	return
+}
+
+func (a *approver) prompts() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return len(a.requests)
+}
+
+func (a *approver) decide(decisions ...Decision) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.decisions = append(a.decisions, decisions...)
+}
+
+// fakeDaemon answers get_info over http and method not found for the rest
+func fakeDaemon(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpc.RPCRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if req.Method == "get_info" {
+			res["result"] = daemon.GetInfoResult{Height: 42, Topoheight: 50}
+		} else {
+			res["error"] = rpc.RPCError{Code: CodeMethodNotFound, Message: "Method not found"}
+		}
+
+		json.NewEncoder(w).Encode(res)
+	}))
+}
+
+// eventWallet is a wallet backend pushing the events given to push
+type eventWallet struct {
+	Handlers
+	mutex  sync.Mutex
+	events map[string]func(json.RawMessage)
+}
+
+func (w *eventWallet) Subscribe(event string, onData func(json.RawMessage)) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.events[event] = onData
+	return nil
+}
+
+func (w *eventWallet) push(event string, data string) bool {
+	w.mutex.Lock()
+	onData, ok := w.events[event]
+	w.mutex.Unlock()
+
+	if ok {
+		onData(json.RawMessage(data))
+	}
+
+	return ok
+}
+
+type testServer struct {
+	*Server
+	approver *approver
+	wallet   *eventWallet
+	endpoint string
+}
+
+func setupServer(t *testing.T, options Options) (s testServer) {
+	node := fakeDaemon(t)
+	t.Cleanup(node.Close)
+
+	rpcDaemon, err := daemon.NewRPC(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.approver = &approver{}
+	s.wallet = &eventWallet{
+		Handlers: Handlers{
+			"get_version": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+				return "1.0.0", nil
+			},
+			"get_address": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+				return "xet:address", nil
+			},
+		},
+		events: make(map[string]func(json.RawMessage)),
+	}
+
+	options.Daemon = NewHttpBackend(rpcDaemon)
+	options.Wallet = s.wallet
+	options.ApproveApplication = s.approver.application
+	options.ApproveRequest = s.approver.request
+
+	s.Server, err = NewServer(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpServer := httptest.NewServer(s.Server)
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(s.Server.Close)
+
+	s.endpoint = "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/xswd"
+	return
+}
+
+func testApp() xswd.ApplicationData {
+	return xswd.ApplicationData{
+		ID:          testAppID,
+		Name:        "Test App",
+		Description: "This is a test app.",
+		Url:         "https://example.com",
+		Permissions: map[string]xswd.Permission{"wallet.get_address": xswd.AcceptAlways},
+	}
+}
+
+func connect(t *testing.T, s testServer, app xswd.ApplicationData) (x *xswd.XSWD, err error) {
+	x, err = xswd.NewXSWD(s.endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connectionErr := x.WS.ConnectionErr
+	go func() {
+		<-connectionErr
+	}()
+
+	_, err = x.Authorize(app)
+	if err != nil {
+		x.Close()
+		x = nil
+	}
+
+	return
+}
+
+// waitDisconnected waits for the server to see the application disconnect
+func waitDisconnected(t *testing.T, s testServer) {
+	deadline := time.Now().Add(2 * time.Second)
+	for len(s.Applications()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("application still connected")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	s := setupServer(t, Options{})
+
+	app := testApp()
+	app.ID = "ertherth"
+	_, err := connect(t, s, app)
+	if err == nil || err.Error() != "Invalid application ID" {
+		t.Fatalf("invalid id: %v", err)
+	}
+
+	s.approver.reject = true
+	_, err = connect(t, s, testApp())
+	if err == nil || err.Error() != ErrApplicationRejected.Error() {
+		t.Fatalf("rejected: %v", err)
+	}
+
+	s.approver.reject = false
+	x, err := connect(t, s, testApp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	_, err = connect(t, s, testApp())
+	if err == nil || err.Error() != ErrApplicationIDUsed.Error() {
+		t.Fatalf("id used: %v", err)
+	}
+
+	apps := s.Applications()
+	if len(apps) != 1 || apps[0].Name != "Test App" {
+		t.Fatalf("applications: %+v", apps)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	s := setupServer(t, Options{
+		RequireSignature: true,
+		VerifySignature: func(app xswd.ApplicationData) error {
+			if app.Signature != "valid" {
+				return errors.New("bad signature")
+			}
+
+			return nil
+		},
+	})
+
+	_, err := connect(t, s, testApp())
+	if err == nil || err.Error() != ErrSignatureRequired.Error() {
+		t.Fatalf("no signature: %v", err)
+	}
+
+	app := testApp()
+	app.Signature = "invalid"
+	_, err = connect(t, s, app)
+	if err == nil || !strings.HasPrefix(err.Error(), ErrInvalidSignature.Error()) {
+		t.Fatalf("invalid signature: %v", err)
+	}
+
+	app.Signature = "valid"
+	x, err := connect(t, s, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x.Close()
+}
+
+func TestSignedApplication(t *testing.T) {
+	s := setupServer(t, Options{RequireSignature: true})
+
+	key, err := signature.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := testApp()
+	err = app.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := app
+	tampered.Permissions = map[string]xswd.Permission{"wallet.build_transaction": xswd.AcceptAlways}
+	_, err = connect(t, s, tampered)
+	if err == nil || err.Error() != ErrInvalidSignature.Error() {
+		t.Fatalf("tampered application: %v", err)
+	}
+
+	x, err := connect(t, s, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x.Close()
+}
+
+func TestRouting(t *testing.T) {
+	s := setupServer(t, Options{})
+
+	x, err := connect(t, s, testApp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	info, err := x.Daemon.GetInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Height != 42 || info.Topoheight != 50 {
+		t.Fatalf("info: %+v", info)
+	}
+
+	_, err = x.Daemon.GetVersion()
+	if err == nil || err.Error() != "Method not found" {
+		t.Fatalf("unknown node method: %v", err)
+	}
+
+	// requested by the application but not granted by the user
+	s.approver.decide(Accept)
+	addr, err := x.Wallet.GetAddress(wallet.GetAddressParams{})
+	if err != nil || addr != "xet:address" {
+		t.Fatalf("address: %s %v", addr, err)
+	}
+
+	if s.approver.prompts() != 1 {
+		t.Fatal("requested permission was not prompted")
+	}
+
+	x.Close()
+	waitDisconnected(t, s)
+
+	// granted with the application
+	s.approver.grant(map[string]xswd.Permission{"wallet.get_address": xswd.AcceptAlways})
+	x, err = connect(t, s, testApp())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err = x.Wallet.GetAddress(wallet.GetAddressParams{})
+	if err != nil || addr != "xet:address" {
+		t.Fatalf("address: %s %v", addr, err)
+	}
+
+	if s.approver.prompts() != 1 {
+		t.Fatal("granted permission was prompted")
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	s := setupServer(t, Options{})
+
+	register := func(origin string) (err error) {
+		conn, _, err := websocket.DefaultDialer.Dial(s.endpoint, http.Header{"Origin": {origin}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		err = conn.WriteJSON(testApp())
+		if err != nil {
+			return
+		}
+
+		var res rpc.RPCResponse
+		err = conn.ReadJSON(&res)
+		if err == nil && res.Error != nil {
+			err = res.Error
+		}
+
+		return
+	}
+
+	err := register("null")
+	if !errors.Is(err, websocket.ErrBadHandshake) {
+		t.Fatalf("null origin: %v", err)
+	}
+
+	err = register("https://attacker.example")
+	if err == nil || err.Error() != ErrInvalidOrigin.Error() {
+		t.Fatalf("other origin: %v", err)
+	}
+
+	err = register("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "permissions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := setupServer(t, Options{Store: store})
+
+	// stored permissions are only reused for signed applications when there is no origin
+	key, err := signature.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := testApp()
+	err = signed.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := connect(t, s, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.approver.decide(Accept, Reject, AlwaysAccept)
+	expected := []error{nil, ErrPermissionDenied, nil, nil}
+	for i, expectedErr := range expected {
+		version, err := x.Wallet.GetVersion()
+		if expectedErr == nil && (err != nil || version != "1.0.0") {
+			t.Fatalf("call %d: %s %v", i, version, err)
+		}
+
+		if expectedErr != nil && (err == nil || err.Error() != expectedErr.Error()) {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+
+	if s.approver.prompts() != 3 {
+		t.Fatalf("%d prompts", s.approver.prompts())
+	}
+
+	request := s.approver.requests[0]
+	if request.Method != "wallet.get_version" || request.Application.ID != signed.ID {
+		t.Fatalf("request: %+v", request)
+	}
+
+	stored, ok, err := store.Get(signed.ID)
+	if err != nil || !ok {
+		t.Fatal("permissions not stored", err)
+	}
+
+	// requested by the application but never granted
+	if _, ok := stored.Permissions["wallet.get_address"]; ok || stored.Permissions["wallet.get_version"] != xswd.AcceptAlways {
+		t.Fatalf("stored: %+v", stored)
+	}
+
+	x.Close()
+	waitDisconnected(t, s)
+
+	// the stored permission is used on the next connection
+	x, err = connect(t, s, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = x.Wallet.GetVersion()
+	if err != nil || s.approver.prompts() != 3 {
+		t.Fatalf("stored permission: %v %d prompts", err, s.approver.prompts())
+	}
+
+	x.Close()
+	waitDisconnected(t, s)
+
+	// but not if the application changed its name
+	app := testApp()
+	app.Name = "Other App"
+	err = app.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, err = connect(t, s, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	s.approver.decide(AlwaysReject)
+	for i := 0; i < 2; i++ {
+		_, err = x.Wallet.GetVersion()
+		if err == nil || err.Error() != ErrPermissionDenied.Error() {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+
+	if s.approver.prompts() != 4 {
+		t.Fatalf("%d prompts", s.approver.prompts())
+	}
+
+	err = s.Revoke(signed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok, _ = store.Get(signed.ID)
+	if ok {
+		t.Fatal("revoked permissions still stored")
+	}
+
+	waitDisconnected(t, s)
+}
+
+func TestEvents(t *testing.T) {
+	s := setupServer(t, Options{})
+
+	x, err := connect(t, s, testApp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	nodeTopoheight := make(chan uint64, 1)
+	err = x.Daemon.NewTopoheightFunc(func(topoheight uint64, err error) {
+		nodeTopoheight <- topoheight
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Notify(xswd.NodePrefix+daemonEvents.NewTopoheight, 7)
+	s.Notify(xswd.NodePrefix+daemonEvents.NewBlock, daemon.Block{})
+	if topoheight := <-nodeTopoheight; topoheight != 7 {
+		t.Fatalf("node topoheight %d", topoheight)
+	}
+
+	// wallet events need a permission
+	s.approver.decide(Reject)
+	err = x.Wallet.NewTopoheightFunc(func(topoheight uint64, err error) {})
+	if err == nil || err.Error() != ErrPermissionDenied.Error() {
+		t.Fatalf("rejected subscription: %v", err)
+	}
+
+	if s.wallet.push(walletEvents.NewTopoheight, "1") {
+		t.Fatal("rejected subscription reached the backend")
+	}
+
+	x.Close()
+	waitDisconnected(t, s)
+
+	x, err = connect(t, s, testApp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	walletTopoheight := make(chan uint64, 1)
+	s.approver.decide(Accept)
+	err = x.Wallet.NewTopoheightFunc(func(topoheight uint64, err error) {
+		walletTopoheight <- topoheight
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.wallet.push(walletEvents.NewTopoheight, `{"topoheight":9}`) {
+		t.Fatal("backend not subscribed")
+	}
+
+	if topoheight := <-walletTopoheight; topoheight != 9 {
+		t.Fatalf("wallet topoheight %d", topoheight)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	s := setupServer(t, Options{})
+
+	conn, _, err := websocket.DefaultDialer.Dial(s.endpoint, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteJSON(testApp())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var registered struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *int64           `json:"id"`
+		Result  registeredResult `json:"result"`
+	}
+
+	err = conn.ReadJSON(&registered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if registered.JSONRPC != "2.0" || registered.ID != nil || !registered.Result.Success || registered.Result.Message != RegisteredMessage {
+		t.Fatalf("registration: %+v", registered)
+	}
+
+	tests := []struct {
+		message string
+		code    int
+	}{
+		{`not json`, CodeParseError},
+		{`{"jsonrpc":"2.0","id":1}`, CodeInvalidRequest},
+		{`{"jsonrpc":"2.0","id":2,"method":"get_info"}`, CodeMethodNotFound},
+		{`{"jsonrpc":"2.0","id":3,"method":"wallet.unknown"}`, CodePermissionDenied},
+		{`{"jsonrpc":"2.0","id":4,"method":"node.subscribe","params":{}}`, CodeInvalidParams},
+		{`{"jsonrpc":"2.0","id":5,"method":"node.unsubscribe","params":{"notify":"new_block"}}`, CodeInvalidParams},
+		{`{"jsonrpc":"2.0","method":"subscribe","params":{"notify":"node.new_block"}}`, CodeInvalidRequest},
+	}
+
+	for _, test := range tests {
+		err = conn.WriteMessage(websocket.TextMessage, []byte(test.message))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res rpc.RPCResponse
+		err = conn.ReadJSON(&res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Error == nil || res.Error.Code != test.code {
+			t.Fatalf("%s: %+v", test.message, res.Error)
+		}
+	}
+
+	s.approver.decide(Accept)
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":"a","method":"wallet.unknown"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res struct {
+		ID    string        `json:"id"`
+		Error *rpc.RPCError `json:"error"`
+	}
+
+	err = conn.ReadJSON(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ID != "a" || res.Error == nil || res.Error.Code != CodeMethodNotFound {
+		t.Fatalf("allowed unknown method: %+v", res)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "permissions.json")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := StoredApplication{ID: testAppID, Name: "Test App", Permissions: map[string]xswd.Permission{"wallet.get_balance": xswd.DenyAlways}}
+	err = store.Put(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Put(StoredApplication{ID: strings.Repeat("0", 64), Name: "Other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Delete(strings.Repeat("0", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps := store.Applications()
+	if len(apps) != 1 || apps[0].Name != "Test App" || apps[0].Permissions["wallet.get_balance"] != xswd.DenyAlways {
+		t.Fatalf("reopened store: %+v", apps)
+	}
+
+	signed := xswd.ApplicationData{ID: testAppID, Name: "Test App", Signature: "signature"}
+	if !apps[0].Matches(signed, "") || apps[0].Matches(signed, "https://example.com") ||
+		apps[0].Matches(xswd.ApplicationData{ID: testAppID, Name: "Test App"}, "") ||
+		apps[0].Matches(xswd.ApplicationData{ID: testAppID, Name: "Test App", Url: "https://example.com", Signature: "signature"}, "") {
+		t.Fatal("matches")
+	}
+}
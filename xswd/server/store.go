@@ -0,0 +1,182 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/xelis-project/xelis-go-sdk/xswd"
+)
+
+// StoredApplication is an application approved by the user with the permissions it was granted
+type StoredApplication struct {
+	ID          string                     `json:"id"`
+	Name        string                     `json:"name"`
+	Url         string                     `json:"url,omitempty"`
+	Origin      string                     `json:"origin,omitempty"`
+	Permissions map[string]xswd.Permission `json:"permissions"`
+}
+
+// Matches reports if the stored permissions belong to the application connecting from origin,
+// they are not reused if the application changed its name, url or origin.
+// Without origin, only a signed application proves it owns the id.
+func (s StoredApplication) Matches(app xswd.ApplicationData, origin string) bool {
+	if s.ID != app.ID || s.Name != app.Name || s.Url != app.Url || s.Origin != origin {
+		return false
+	}
+
+	return origin != "" || app.Signature != ""
+}
+
+// Store keeps the permissions granted by the user between connections
+type Store interface {
+	Get(id string) (app StoredApplication, ok bool, err error)
+	Put(app StoredApplication) error
+	Delete(id string) error
+}
+
+type MemoryStore struct {
+	mutex sync.RWMutex
+	apps  map[string]StoredApplication
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{apps: make(map[string]StoredApplication)}
+}
+
+func (m *MemoryStore) Get(id string) (app StoredApplication, ok bool, err error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	app, ok = m.apps[id]
+	app.Permissions = copyPermissions(app.Permissions)
+	return
+}
+
+func (m *MemoryStore) Put(app StoredApplication) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	app.Permissions = copyPermissions(app.Permissions)
+	m.apps[app.ID] = app
+	return nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.apps, id)
+	return nil
+}
+
+// Applications returns the stored applications sorted by id
+func (m *MemoryStore) Applications() []StoredApplication {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	apps := make([]StoredApplication, 0, len(m.apps))
+	for _, app := range m.apps {
+		app.Permissions = copyPermissions(app.Permissions)
+		apps = append(apps, app)
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].ID < apps[j].ID
+	})
+
+	return apps
+}
+
+// FileStore is a MemoryStore saved as json after each change
+type FileStore struct {
+	*MemoryStore
+	path string
+	// serializes the writes so the file is never older than the memory
+	writeMutex sync.Mutex
+}
+
+// OpenFileStore loads the stored applications, the file is created on the first change
+func OpenFileStore(path string) (store *FileStore, err error) {
+	store = &FileStore{MemoryStore: NewMemoryStore(), path: path}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		store = nil
+		return
+	}
+
+	var apps []StoredApplication
+	err = json.Unmarshal(b, &apps)
+	if err != nil {
+		store = nil
+		return
+	}
+
+	for _, app := range apps {
+		store.MemoryStore.Put(app)
+	}
+
+	return
+}
+
+func (f *FileStore) Put(app StoredApplication) error {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	f.MemoryStore.Put(app)
+	return f.save()
+}
+
+func (f *FileStore) Delete(id string) error {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	f.MemoryStore.Delete(id)
+	return f.save()
+}
+
+// the file is replaced by a rename so a crash never leaves a partial store
+func (f *FileStore) save() (err error) {
+	data, err := json.MarshalIndent(f.Applications(), "", "  ")
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func copyPermissions(permissions map[string]xswd.Permission) map[string]xswd.Permission {
+	copied := make(map[string]xswd.Permission, len(permissions))
+	for method, permission := range permissions {
+		copied[method] = permission
+	}
+
+	return copied
+}
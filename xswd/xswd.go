@@ -41,12 +41,12 @@ func NewXSWD(endpoint string) (*XSWD, error) {
 	ws.CallTimeout = 0 // Not timeout, because we have to wait for user input.
 
 	daemon := &daemon.WebSocket{
-		Prefix: "node.",
+		Prefix: NodePrefix,
 		WS:     ws,
 	}
 
 	wallet := &wallet.WebSocket{
-		Prefix: "wallet.",
+		Prefix: WalletPrefix,
 		WS:     ws,
 	}
 